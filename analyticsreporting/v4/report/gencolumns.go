@@ -0,0 +1,138 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build ignore
+
+// gencolumns fetches the public Analytics columns from the metadata API
+// and writes them out as Dimension and Metric constants.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	analytics "google.golang.org/api/analytics/v3"
+)
+
+var (
+	output     = flag.String("o", "columns.go", "Output file.")
+	deprecated = flag.Bool("deprecated", false, "Include columns marked as DEPRECATED.")
+	reportType = flag.String("report_type", "ga", "Report type to list columns for.")
+)
+
+// templateTag marks templated column IDs, such as "ga:dimensionXX".
+const templateTag = "XX"
+
+func main() {
+	flag.Parse()
+
+	// The metadata API does not require authorization.
+	svc, err := analytics.New(http.DefaultClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cols, err := svc.Metadata.Columns.List(*reportType).Do()
+	if err != nil {
+		log.Fatalf("listing columns: %v", err)
+	}
+
+	var dims, mets []*analytics.Column
+	for _, c := range cols.Items {
+		if c.Attributes["status"] == "DEPRECATED" && !*deprecated {
+			continue
+		}
+		// Templated columns such as ga:dimensionXX are exposed as
+		// CustomDimension and CustomMetric instead.
+		if strings.Contains(c.Id, templateTag) {
+			continue
+		}
+		switch c.Attributes["type"] {
+		case "DIMENSION":
+			dims = append(dims, c)
+		case "METRIC":
+			mets = append(mets, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gencolumns.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package report\n")
+	writeConsts(&buf, "Dimension", dims)
+	writeConsts(&buf, "Metric", mets)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting output: %v\n%s", err, buf.Bytes())
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeConsts(buf *bytes.Buffer, typ string, cols []*analytics.Column) {
+	sort.Sort(byID(cols))
+	fmt.Fprintf(buf, "\n// %ss.\nconst (\n", typ)
+	for i, c := range cols {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		name := identifier(c.Id)
+		des := fmt.Sprintf("%s is the %q %s.", name, c.Attributes["uiName"], strings.ToLower(typ))
+		if d := c.Attributes["description"]; d != "" {
+			des += " " + d
+		}
+		for _, l := range wrap(des, 70) {
+			fmt.Fprintf(buf, "\t// %s\n", l)
+		}
+		if c.Attributes["status"] == "DEPRECATED" {
+			fmt.Fprintf(buf, "\t//\n\t// Deprecated: see the Analytics metadata API for a replacement.\n")
+		}
+		fmt.Fprintf(buf, "\t%s %s = %q\n", name, typ, c.Id)
+	}
+	buf.WriteString(")\n")
+}
+
+// identifier maps a column ID such as "ga:pageviewsPerSession" to a Go
+// identifier such as "PageviewsPerSession".
+func identifier(id string) string {
+	if i := strings.Index(id, ":"); i >= 0 {
+		id = id[i+1:]
+	}
+	r := []rune(id)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func wrap(s string, n int) []string {
+	var lines []string
+	var line string
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(w) > n {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type byID []*analytics.Column
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].Id < s[j].Id }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
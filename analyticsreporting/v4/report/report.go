@@ -0,0 +1,171 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report provides typed dimension and metric names for the
+// Analytics Reporting API, together with a builder for report requests.
+//
+// Usage example:
+//
+//   req := report.New("12345").
+//     DateRange("7daysAgo", "yesterday").
+//     Metrics(report.Sessions, report.Pageviews).
+//     Dimensions(report.Country).
+//     OrderBy(report.Sessions, report.Descending).
+//     PageSize(100).
+//     Request()
+//   resp, err := svc.Reports.BatchGet(report.Batch(req)).Do()
+//
+// The Dimension and Metric constants are generated from the Analytics
+// metadata API by gencolumns.go; run "go generate" to refresh them.
+// Templated columns, such as "ga:goalXXCompletions", have no constants;
+// they may be used by converting their name, as in
+// report.Metric("ga:goal1Completions").
+package report // import "google.golang.org/api/analyticsreporting/v4/report"
+
+//go:generate go run gencolumns.go -o columns.go
+
+import (
+	"strconv"
+	"strings"
+
+	analyticsreporting "google.golang.org/api/analyticsreporting/v4"
+)
+
+// A Dimension is the name of an Analytics dimension, such as "ga:country".
+type Dimension string
+
+// A Metric is the name of an Analytics metric, such as "ga:sessions".
+type Metric string
+
+// Column is implemented by Dimension and Metric.
+type Column interface {
+	ColumnName() string
+}
+
+// ColumnName returns the API name of the dimension.
+func (d Dimension) ColumnName() string { return string(d) }
+
+// ColumnName returns the API name of the metric.
+func (m Metric) ColumnName() string { return string(m) }
+
+// CustomDimension returns the Dimension for the custom dimension with the given index.
+func CustomDimension(index int) Dimension {
+	return Dimension("ga:dimension" + strconv.Itoa(index))
+}
+
+// CustomMetric returns the Metric for the custom metric with the given index.
+func CustomMetric(index int) Metric {
+	return Metric("ga:metric" + strconv.Itoa(index))
+}
+
+// Expression returns a Metric for a calculated expression combining
+// other metrics, such as "ga:sessions/ga:users". Operands are passed
+// as Metrics so that their names are checked at compile time.
+func Expression(op string, metrics ...Metric) Metric {
+	names := make([]string, len(metrics))
+	for i, m := range metrics {
+		names[i] = string(m)
+	}
+	return Metric(strings.Join(names, op))
+}
+
+// SortOrder is the direction in which OrderBy sorts a column.
+type SortOrder string
+
+const (
+	Ascending  SortOrder = "ASCENDING"
+	Descending SortOrder = "DESCENDING"
+)
+
+// Builder builds an analyticsreporting.ReportRequest.
+// Its methods return the Builder so that calls can be chained.
+type Builder struct {
+	r *analyticsreporting.ReportRequest
+}
+
+// New returns a Builder for a report on the view with the given ID.
+func New(viewID string) *Builder {
+	return &Builder{r: &analyticsreporting.ReportRequest{ViewId: viewID}}
+}
+
+// DateRange adds a date range to the request. Dates may be in
+// YYYY-MM-DD format or relative, such as "today" or "7daysAgo".
+func (b *Builder) DateRange(start, end string) *Builder {
+	b.r.DateRanges = append(b.r.DateRanges, &analyticsreporting.DateRange{
+		StartDate: start,
+		EndDate:   end,
+	})
+	return b
+}
+
+// Metrics adds metrics to the request.
+func (b *Builder) Metrics(metrics ...Metric) *Builder {
+	for _, m := range metrics {
+		b.r.Metrics = append(b.r.Metrics, &analyticsreporting.Metric{Expression: string(m)})
+	}
+	return b
+}
+
+// MetricAs adds a metric with an alias to the request. The alias is used
+// as the column name in the response.
+func (b *Builder) MetricAs(m Metric, alias string) *Builder {
+	b.r.Metrics = append(b.r.Metrics, &analyticsreporting.Metric{
+		Expression: string(m),
+		Alias:      alias,
+	})
+	return b
+}
+
+// Dimensions adds dimensions to the request.
+func (b *Builder) Dimensions(dims ...Dimension) *Builder {
+	for _, d := range dims {
+		b.r.Dimensions = append(b.r.Dimensions, &analyticsreporting.Dimension{Name: string(d)})
+	}
+	return b
+}
+
+// OrderBy adds a sort on the given dimension or metric.
+func (b *Builder) OrderBy(c Column, order SortOrder) *Builder {
+	b.r.OrderBys = append(b.r.OrderBys, &analyticsreporting.OrderBy{
+		FieldName: c.ColumnName(),
+		SortOrder: string(order),
+	})
+	return b
+}
+
+// Filters sets the filter expression of the request, such as
+// "ga:browser==Firefox".
+func (b *Builder) Filters(expr string) *Builder {
+	b.r.FiltersExpression = expr
+	return b
+}
+
+// PageSize sets the maximum number of rows to return.
+func (b *Builder) PageSize(n int64) *Builder {
+	b.r.PageSize = n
+	return b
+}
+
+// PageToken sets the continuation token returned by a previous report.
+func (b *Builder) PageToken(token string) *Builder {
+	b.r.PageToken = token
+	return b
+}
+
+// IncludeEmptyRows sets whether rows with all-zero metrics are returned.
+func (b *Builder) IncludeEmptyRows(v bool) *Builder {
+	b.r.IncludeEmptyRows = v
+	return b
+}
+
+// Request returns the built ReportRequest.
+func (b *Builder) Request() *analyticsreporting.ReportRequest {
+	return b.r
+}
+
+// Batch wraps report requests in a GetReportsRequest suitable for
+// passing to ReportsService.BatchGet.
+func Batch(reqs ...*analyticsreporting.ReportRequest) *analyticsreporting.GetReportsRequest {
+	return &analyticsreporting.GetReportsRequest{ReportRequests: reqs}
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	req := New("12345").
+		DateRange("7daysAgo", "yesterday").
+		Metrics(Sessions, Expression("/", Pageviews, Sessions)).
+		MetricAs(CustomMetric(3), "widgets").
+		Dimensions(Country, CustomDimension(1)).
+		OrderBy(Sessions, Descending).
+		Filters("ga:browser==Firefox").
+		PageSize(100).
+		Request()
+
+	got, err := json.Marshal(Batch(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"reportRequests":[{"dateRanges":[{"endDate":"yesterday","startDate":"7daysAgo"}],` +
+		`"dimensions":[{"name":"ga:country"},{"name":"ga:dimension1"}],` +
+		`"filtersExpression":"ga:browser==Firefox",` +
+		`"metrics":[{"expression":"ga:sessions"},{"expression":"ga:pageviews/ga:sessions"},{"alias":"widgets","expression":"ga:metric3"}],` +
+		`"orderBys":[{"fieldName":"ga:sessions","sortOrder":"DESCENDING"}],` +
+		`"pageSize":100,"viewId":"12345"}]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// awsCredentials are the security credentials of an AWS role or user.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+// awsSubjectToken returns the subject token of a workload on AWS: a
+// GetCallerIdentity request signed with its credentials, which Google's
+// Security Token Service sends to AWS to learn the workload's identity.
+func (cs *credentialSource) awsSubjectToken(ctx context.Context, hc *http.Client, audience string) (string, error) {
+	region, err := cs.awsRegion(ctx, hc)
+	if err != nil {
+		return "", err
+	}
+	creds, err := cs.awsCredentials(ctx, hc)
+	if err != nil {
+		return "", err
+	}
+	u := strings.Replace(cs.RegionalCredVerificationURL, "{region}", region, -1)
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-goog-cloud-target-resource", audience)
+	signAWS(req, creds, region, "sts", timeNow())
+
+	type header struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	token := struct {
+		URL     string   `json:"url"`
+		Method  string   `json:"method"`
+		Headers []header `json:"headers"`
+	}{URL: u, Method: req.Method}
+	for _, k := range sortedHeaderKeys(req) {
+		token.Headers = append(token.Headers, header{k, req.Header.Get(k)})
+	}
+	token.Headers = append(token.Headers, header{"host", req.URL.Host})
+	b, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryEscape(string(b)), nil
+}
+
+// awsRegion returns the region of the workload, from the environment or
+// from the instance metadata, where it is the zone less its last letter.
+func (cs *credentialSource) awsRegion(ctx context.Context, hc *http.Client) (string, error) {
+	for _, v := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if r := os.Getenv(v); r != "" {
+			return r, nil
+		}
+	}
+	if cs.RegionURL == "" {
+		return "", errors.New("no AWS region in the environment and no region_url")
+	}
+	b, err := getURL(ctx, hc, cs.RegionURL, nil)
+	if err != nil {
+		return "", err
+	}
+	zone := strings.TrimSpace(string(b))
+	if zone == "" {
+		return "", errors.New("empty AWS availability zone")
+	}
+	return zone[:len(zone)-1], nil
+}
+
+// awsCredentials returns the workload's credentials, from the
+// environment or from the instance metadata for its role.
+func (cs *credentialSource) awsCredentials(ctx context.Context, hc *http.Client) (*awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return &awsCredentials{id, secret, os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if cs.URL == "" {
+		return nil, errors.New("no AWS credentials in the environment and no url")
+	}
+	role, err := getURL(ctx, hc, cs.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := getURL(ctx, hc, strings.TrimSuffix(cs.URL, "/")+"/"+strings.TrimSpace(string(role)), nil)
+	if err != nil {
+		return nil, err
+	}
+	creds := new(awsCredentials)
+	if err := json.Unmarshal(b, creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// signAWS signs req, which has no body, with AWS Signature Version 4.
+func signAWS(req *http.Request, creds *awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.Token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.Token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for _, k := range sortedHeaderKeys(req) {
+		headers[k] = strings.TrimSpace(req.Header.Get(k))
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders bytes.Buffer
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Replace(req.URL.Query().Encode(), "+", "%20", -1),
+		canonHeaders.String(),
+		signedHeaders,
+		hexSHA256(nil),
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonRequest))
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
+
+// sortedHeaderKeys returns the names of the headers of req, in lower
+// case and sorted.
+func sortedHeaderKeys(req *http.Request) []string {
+	var keys []string
+	for k := range req.Header {
+		keys = append(keys, strings.ToLower(k))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(s))
+	return m.Sum(nil)
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
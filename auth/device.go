@@ -0,0 +1,206 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package auth supports authorizing API clients where the usual OAuth 2.0
+// flows do not fit, such as on headless machines without a browser, or
+// for workloads outside Google Cloud which have no service account key.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/oauth2"
+)
+
+// DeviceCodeURL is Google's endpoint for starting the device flow.
+const DeviceCodeURL = "https://accounts.google.com/o/oauth2/device/code"
+
+// deviceGrantType is the grant type with which a device code is
+// exchanged for a token.
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// pollUnit is the unit of the polling interval given by the server.
+// Tests shorten it.
+var pollUnit = time.Second
+
+// A DeviceCode is returned by DeviceFlow.Start. The user authorizes the
+// client by visiting VerificationURL on another device, such as a phone,
+// and entering UserCode.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	// ExpiresIn is the number of seconds for which the codes are valid.
+	ExpiresIn int64 `json:"expires_in"`
+	// Interval is the number of seconds to wait between polls for the
+	// token.
+	Interval int64 `json:"interval"`
+}
+
+// DeviceFlow runs the OAuth 2.0 flow for devices with limited input, or
+// without a browser, such as servers reached over SSH. The client is
+// authorized on another device, while the flow polls for the token.
+type DeviceFlow struct {
+	// Config holds the client's ID and secret, the scopes requested
+	// and, in Endpoint.TokenURL, where tokens are polled for, usually
+	// google.Endpoint.TokenURL. Its RedirectURL is not used.
+	Config *oauth2.Config
+
+	// CodeURL is the endpoint at which the flow starts. If empty,
+	// DeviceCodeURL is used.
+	CodeURL string
+
+	// Client sends the flow's requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// A DeviceFlowError is returned by DeviceFlow.Poll when the user denies
+// access, the codes expire, or the server rejects the flow otherwise.
+type DeviceFlowError struct {
+	// Code is the OAuth 2.0 error code, such as "access_denied" or
+	// "expired_token".
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *DeviceFlowError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("auth: device flow: %s: %s", e.Code, e.Description)
+	}
+	return "auth: device flow: " + e.Code
+}
+
+// Start starts the flow, returning the codes to show the user.
+func (f *DeviceFlow) Start(ctx context.Context) (*DeviceCode, error) {
+	codeURL := f.CodeURL
+	if codeURL == "" {
+		codeURL = DeviceCodeURL
+	}
+	v := url.Values{
+		"client_id": {f.Config.ClientID},
+		"scope":     {strings.Join(f.Config.Scopes, " ")},
+	}
+	var dc struct {
+		DeviceCode
+		// VerificationURI is the name RFC 8628 gives VerificationURL.
+		VerificationURI string `json:"verification_uri"`
+	}
+	if err := f.post(ctx, codeURL, v, &dc); err != nil {
+		return nil, err
+	}
+	if dc.DeviceCode.DeviceCode == "" || dc.UserCode == "" {
+		return nil, errors.New("auth: device flow: response is missing its codes")
+	}
+	if dc.VerificationURL == "" {
+		dc.VerificationURL = dc.VerificationURI
+	}
+	return &dc.DeviceCode, nil
+}
+
+// Poll waits for the user to authorize the client with dc, polling for
+// the token at the interval the server asks for, until the token is
+// granted, the codes expire or are refused, or ctx is done.
+func (f *DeviceFlow) Poll(ctx context.Context, dc *DeviceCode) (*oauth2.Token, error) {
+	interval := time.Duration(dc.Interval) * pollUnit
+	if interval <= 0 {
+		interval = 5 * pollUnit
+	}
+	// The device code is sent under the name RFC 8628 gives it, and the
+	// one Google's endpoint used before.
+	v := url.Values{
+		"client_id":     {f.Config.ClientID},
+		"client_secret": {f.Config.ClientSecret},
+		"code":          {dc.DeviceCode},
+		"device_code":   {dc.DeviceCode},
+		"grant_type":    {deviceGrantType},
+	}
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var tr tokenResponse
+		err := f.post(ctx, f.Config.Endpoint.TokenURL, v, &tr)
+		if e, ok := err.(*DeviceFlowError); ok {
+			switch e.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * pollUnit
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return tr.token()
+	}
+}
+
+// post posts v to u, decoding the JSON response into dst, or an error
+// response into a *DeviceFlowError.
+func (f *DeviceFlow) post(ctx context.Context, u string, v url.Values, dst interface{}) error {
+	res, err := ctxhttp.PostForm(ctx, f.Client, u, v)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		e := new(DeviceFlowError)
+		if json.Unmarshal(body, e) != nil || e.Code == "" {
+			return fmt.Errorf("auth: device flow: %s: %s", res.Status, body)
+		}
+		return e
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return fmt.Errorf("auth: device flow: cannot decode response: %v", err)
+	}
+	return nil
+}
+
+// tokenResponse is the JSON response of an OAuth 2.0 token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+}
+
+func (tr *tokenResponse) token() (*oauth2.Token, error) {
+	if tr.AccessToken == "" {
+		return nil, errors.New("auth: server response missing access_token")
+	}
+	t := &oauth2.Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = timeNow().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	if tr.IDToken != "" {
+		t = t.WithExtra(map[string]interface{}{"id_token": tr.IDToken})
+	}
+	return t, nil
+}
+
+// timeNow is time.Now, replaced by tests.
+var timeNow = time.Now
@@ -0,0 +1,125 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// newDeviceServer returns a server granting a token after answering
+// pending polls for the device code, and the flow using it.
+func newDeviceServer(t *testing.T, pending int) (*httptest.Server, *DeviceFlow) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/code", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.FormValue("scope"), "a b"; got != want {
+			t.Errorf("scope: got %q, want %q", got, want)
+		}
+		fmt.Fprint(w, `{"device_code": "dev", "user_code": "USER", "verification_uri": "https://example.com/device", "expires_in": 1800, "interval": 1}`)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != deviceGrantType || r.FormValue("device_code") != "dev" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		if pending > 0 {
+			pending--
+			w.WriteHeader(http.StatusPreconditionRequired)
+			fmt.Fprint(w, `{"error": "authorization_pending"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "at", "token_type": "Bearer", "refresh_token": "rt", "expires_in": 3600}`)
+	})
+	srv := httptest.NewServer(mux)
+	return srv, &DeviceFlow{
+		Config: &oauth2.Config{
+			ClientID:     "id",
+			ClientSecret: "secret",
+			Scopes:       []string{"a", "b"},
+			Endpoint:     oauth2.Endpoint{TokenURL: srv.URL + "/token"},
+		},
+		CodeURL: srv.URL + "/code",
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	defer func(d time.Duration) { pollUnit = d }(pollUnit)
+	pollUnit = time.Millisecond
+
+	srv, f := newDeviceServer(t, 2)
+	defer srv.Close()
+	ctx := context.Background()
+	dc, err := f.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc.UserCode != "USER" || dc.VerificationURL != "https://example.com/device" {
+		t.Errorf("got codes %+v", dc)
+	}
+	tok, err := f.Poll(ctx, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "at" || tok.RefreshToken != "rt" || tok.Expiry.IsZero() {
+		t.Errorf("got token %+v", tok)
+	}
+
+	if _, err := f.Poll(ctx, &DeviceCode{DeviceCode: "other", Interval: 1}); err == nil {
+		t.Error("Poll with a bad device code succeeded")
+	} else if e, ok := err.(*DeviceFlowError); !ok || e.Code != "invalid_grant" {
+		t.Errorf("Poll with a bad device code: got %v, want invalid_grant", err)
+	}
+}
+
+func TestDeviceFlowTokenSource(t *testing.T) {
+	defer func(d time.Duration) { pollUnit = d }(pollUnit)
+	pollUnit = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "token.json")
+
+	srv, f := newDeviceServer(t, 0)
+	defer srv.Close()
+	prompts := 0
+	prompt := func(*DeviceCode) { prompts++ }
+	for i := 0; i < 2; i++ {
+		ts, err := f.TokenSource(context.Background(), file, prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != "at" {
+			t.Errorf("run %d: got access token %q, want at", i, tok.AccessToken)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("user prompted %d times, want once", prompts)
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file mode %v, want 0600", perm)
+	}
+}
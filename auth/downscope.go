@@ -0,0 +1,126 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// stsURL is the endpoint of Google's Security Token Service. Tests
+// replace it.
+var stsURL = "https://sts.googleapis.com/v1/token"
+
+// MaxAccessBoundaryRules is the number of rules a Credential Access
+// Boundary may hold.
+const MaxAccessBoundaryRules = 10
+
+// An AccessBoundaryRule is one rule of a Credential Access Boundary,
+// granting a down-scoped token some of the permissions of the token it
+// is made from on one resource, such as a Cloud Storage bucket.
+type AccessBoundaryRule struct {
+	// AvailableResource is the full resource name of the resource, such
+	// as "//storage.googleapis.com/projects/_/buckets/bucket-name".
+	AvailableResource string `json:"availableResource"`
+
+	// AvailablePermissions are the IAM roles whose permissions the token
+	// keeps on the resource, such as "inRole:roles/storage.objectViewer".
+	AvailablePermissions []string `json:"availablePermissions"`
+
+	// Condition, if non-nil, further limits the objects within the
+	// resource to which the permissions apply.
+	Condition *AvailabilityCondition `json:"availabilityCondition,omitempty"`
+}
+
+// An AvailabilityCondition is a CEL expression limiting the objects to
+// which an AccessBoundaryRule applies.
+type AvailabilityCondition struct {
+	Expression  string `json:"expression"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// DownscopedTokenSource returns a TokenSource whose tokens are those of
+// root with the Credential Access Boundary of rules applied, so that
+// they carry only the permissions the rules allow. This lets a broker
+// holding broad credentials hand out tokens for, say, one bucket.
+//
+// The TokenSource may be used with any generated package, for instance
+// through oauth2.NewClient or option.WithTokenSource. Its requests are
+// sent with the client in ctx under oauth2.HTTPClient, if any.
+func DownscopedTokenSource(ctx context.Context, root oauth2.TokenSource, rules ...AccessBoundaryRule) (oauth2.TokenSource, error) {
+	if root == nil {
+		return nil, errors.New("auth: downscoping needs a root TokenSource")
+	}
+	if len(rules) == 0 || len(rules) > MaxAccessBoundaryRules {
+		return nil, fmt.Errorf("auth: a Credential Access Boundary needs between 1 and %d rules, not %d", MaxAccessBoundaryRules, len(rules))
+	}
+	for i, r := range rules {
+		if r.AvailableResource == "" || len(r.AvailablePermissions) == 0 {
+			return nil, fmt.Errorf("auth: access boundary rule %d needs a resource and permissions", i)
+		}
+		for _, p := range r.AvailablePermissions {
+			if !strings.HasPrefix(p, "inRole:") {
+				return nil, fmt.Errorf("auth: access boundary rule %d: permission %q does not start with \"inRole:\"", i, p)
+			}
+		}
+	}
+	var boundary struct {
+		AccessBoundary struct {
+			Rules []AccessBoundaryRule `json:"accessBoundaryRules"`
+		} `json:"accessBoundary"`
+	}
+	boundary.AccessBoundary.Rules = rules
+	b, err := json.Marshal(boundary)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(nil, &downscopingTokenSource{ctx: ctx, root: root, options: string(b)}), nil
+}
+
+type downscopingTokenSource struct {
+	ctx     context.Context
+	root    oauth2.TokenSource
+	options string // the access boundary, in JSON
+}
+
+func (ts *downscopingTokenSource) Token() (*oauth2.Token, error) {
+	rootTok, err := ts.root.Token()
+	if err != nil {
+		return nil, err
+	}
+	v := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"subject_token_type":   {accessTokenType},
+		"requested_token_type": {accessTokenType},
+		"subject_token":        {rootTok.AccessToken},
+		"options":              {ts.options},
+	}
+	req, err := http.NewRequest("POST", stsURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var tr tokenResponse
+	if err := doJSON(ts.ctx, oauth2.NewClient(ts.ctx, nil), req, &tr); err != nil {
+		return nil, fmt.Errorf("auth: downscoping: %v", err)
+	}
+	tok, err := tr.token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Expiry.IsZero() {
+		// A down-scoped token lasts as long as the token it is made from.
+		tok.Expiry = rootTok.Expiry
+	}
+	return tok, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+func TestDownscopedTokenSource(t *testing.T) {
+	rule := AccessBoundaryRule{
+		AvailableResource:    "//storage.googleapis.com/projects/_/buckets/b",
+		AvailablePermissions: []string{"inRole:roles/storage.objectViewer"},
+		Condition:            &AvailabilityCondition{Expression: "resource.name.startsWith('projects/_/buckets/b/objects/pub')"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.FormValue("subject_token"); got != "root" {
+			t.Errorf("got subject token %q, want root", got)
+		}
+		var options struct {
+			AccessBoundary struct {
+				Rules []AccessBoundaryRule `json:"accessBoundaryRules"`
+			} `json:"accessBoundary"`
+		}
+		if err := json.Unmarshal([]byte(r.FormValue("options")), &options); err != nil {
+			t.Errorf("options %q: %v", r.FormValue("options"), err)
+		}
+		if got := options.AccessBoundary.Rules; !reflect.DeepEqual(got, []AccessBoundaryRule{rule}) {
+			t.Errorf("got rules %+v, want %+v", got, rule)
+		}
+		fmt.Fprint(w, `{"access_token": "down", "token_type": "Bearer", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token"}`)
+	}))
+	defer srv.Close()
+	defer func(u string) { stsURL = u }(stsURL)
+	stsURL = srv.URL
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	root := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "root", Expiry: expiry})
+	ts, err := DownscopedTokenSource(context.Background(), root, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "down" || !tok.Expiry.Equal(expiry) {
+		t.Errorf("got token %q expiring %v, want down expiring %v", tok.AccessToken, tok.Expiry, expiry)
+	}
+
+	for _, rules := range [][]AccessBoundaryRule{
+		nil,
+		{{AvailableResource: "r"}},
+		{{AvailableResource: "r", AvailablePermissions: []string{"roles/storage.objectViewer"}}},
+	} {
+		if _, err := DownscopedTokenSource(context.Background(), root, rules...); err == nil {
+			t.Errorf("rules %+v accepted", rules)
+		}
+	}
+}
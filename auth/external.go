@@ -0,0 +1,281 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ExternalAccountType is the type of the credentials of workloads outside
+// Google Cloud, such as on AWS or with an OIDC identity provider, which
+// are exchanged for Google access tokens through workload identity
+// federation rather than signed with a service account key.
+const ExternalAccountType = "external_account"
+
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+	cloudPlatformScope     = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// externalConfig is the JSON form of external account credentials.
+type externalConfig struct {
+	Type                           string           `json:"type"`
+	Audience                       string           `json:"audience"`
+	SubjectTokenType               string           `json:"subject_token_type"`
+	TokenURL                       string           `json:"token_url"`
+	ServiceAccountImpersonationURL string           `json:"service_account_impersonation_url"`
+	ClientID                       string           `json:"client_id"`
+	ClientSecret                   string           `json:"client_secret"`
+	CredentialSource               credentialSource `json:"credential_source"`
+}
+
+// credentialSource says where the token of the workload's own identity,
+// the subject token, is found.
+type credentialSource struct {
+	// File names a file holding the token, as an OIDC provider may keep
+	// up to date.
+	File string `json:"file"`
+
+	// URL is where the token is fetched, with Headers. For AWS, it is
+	// where the role's security credentials are fetched.
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+
+	// Format says how the token is held in the file or response: as
+	// text, or as a field of a JSON object.
+	Format struct {
+		Type                  string `json:"type"`
+		SubjectTokenFieldName string `json:"subject_token_field_name"`
+	} `json:"format"`
+
+	// EnvironmentID is "aws1" for credentials from AWS, for which the
+	// subject token is a signed GetCallerIdentity request.
+	EnvironmentID               string `json:"environment_id"`
+	RegionURL                   string `json:"region_url"`
+	RegionalCredVerificationURL string `json:"regional_cred_verification_url"`
+}
+
+// ExternalAccountTokenSource returns a TokenSource for the external
+// account credentials in jsonKey, with the given scopes. Each token is
+// obtained by exchanging the workload's own credentials, found as the
+// credentials describe, at Google's Security Token Service, and then, if
+// the credentials name a service account to impersonate, for a token of
+// that service account.
+//
+// Its requests are sent with the client in ctx under oauth2.HTTPClient,
+// if any.
+func ExternalAccountTokenSource(ctx context.Context, jsonKey []byte, scopes ...string) (oauth2.TokenSource, error) {
+	var c externalConfig
+	if err := json.Unmarshal(jsonKey, &c); err != nil {
+		return nil, err
+	}
+	if c.Type != ExternalAccountType {
+		return nil, fmt.Errorf("auth: credentials have type %q, want %q", c.Type, ExternalAccountType)
+	}
+	if c.Audience == "" || c.SubjectTokenType == "" || c.TokenURL == "" {
+		return nil, errors.New("auth: external account credentials need audience, subject_token_type and token_url")
+	}
+	cs := &c.CredentialSource
+	switch {
+	case cs.EnvironmentID != "":
+		if cs.EnvironmentID != "aws1" {
+			return nil, fmt.Errorf("auth: unsupported credential source environment %q", cs.EnvironmentID)
+		}
+		if cs.RegionalCredVerificationURL == "" {
+			return nil, errors.New("auth: AWS credential source needs regional_cred_verification_url")
+		}
+	case cs.File == "" && cs.URL == "":
+		return nil, errors.New("auth: credential source needs a file or url")
+	}
+	switch cs.Format.Type {
+	case "", "text":
+	case "json":
+		if cs.Format.SubjectTokenFieldName == "" {
+			return nil, errors.New("auth: json credential source format needs subject_token_field_name")
+		}
+	default:
+		return nil, fmt.Errorf("auth: unsupported credential source format %q", cs.Format.Type)
+	}
+	return oauth2.ReuseTokenSource(nil, &externalTokenSource{ctx: ctx, c: &c, scopes: scopes}), nil
+}
+
+// DefaultTokenSource is like google.DefaultTokenSource, but also accepts
+// external account credentials in the file named by the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable.
+func DefaultTokenSource(ctx context.Context, scopes ...string) (oauth2.TokenSource, error) {
+	if file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(b, &f) == nil && f.Type == ExternalAccountType {
+			return ExternalAccountTokenSource(ctx, b, scopes...)
+		}
+	}
+	return google.DefaultTokenSource(ctx, scopes...)
+}
+
+type externalTokenSource struct {
+	ctx    context.Context
+	c      *externalConfig
+	scopes []string
+}
+
+func (ts *externalTokenSource) Token() (*oauth2.Token, error) {
+	hc := oauth2.NewClient(ts.ctx, nil)
+	subject, err := ts.c.CredentialSource.subjectToken(ts.ctx, hc, ts.c.Audience)
+	if err != nil {
+		return nil, fmt.Errorf("auth: cannot get subject token: %v", err)
+	}
+	scopes := ts.scopes
+	if ts.c.ServiceAccountImpersonationURL != "" {
+		// The federated token only needs to be allowed to impersonate.
+		scopes = []string{cloudPlatformScope}
+	}
+	v := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"audience":             {ts.c.Audience},
+		"scope":                {strings.Join(scopes, " ")},
+		"requested_token_type": {accessTokenType},
+		"subject_token":        {subject},
+		"subject_token_type":   {ts.c.SubjectTokenType},
+	}
+	req, err := http.NewRequest("POST", ts.c.TokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ts.c.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(ts.c.ClientID), url.QueryEscape(ts.c.ClientSecret))
+	}
+	var tr tokenResponse
+	if err := doJSON(ts.ctx, hc, req, &tr); err != nil {
+		return nil, fmt.Errorf("auth: token exchange: %v", err)
+	}
+	tok, err := tr.token()
+	if err != nil || ts.c.ServiceAccountImpersonationURL == "" {
+		return tok, err
+	}
+	return ts.impersonate(hc, tok)
+}
+
+// impersonate exchanges tok for a token of the service account named by
+// the credentials.
+func (ts *externalTokenSource) impersonate(hc *http.Client, tok *oauth2.Token) (*oauth2.Token, error) {
+	body, err := json.Marshal(struct {
+		Scope    []string `json:"scope"`
+		Lifetime string   `json:"lifetime"`
+	}{ts.scopes, "3600s"})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", ts.c.ServiceAccountImpersonationURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tok.SetAuthHeader(req)
+	var res struct {
+		AccessToken string `json:"accessToken"`
+		ExpireTime  string `json:"expireTime"`
+	}
+	if err := doJSON(ts.ctx, hc, req, &res); err != nil {
+		return nil, fmt.Errorf("auth: service account impersonation: %v", err)
+	}
+	expiry, err := time.Parse(time.RFC3339, res.ExpireTime)
+	if err != nil {
+		return nil, fmt.Errorf("auth: service account impersonation: bad expireTime: %v", err)
+	}
+	return &oauth2.Token{AccessToken: res.AccessToken, TokenType: "Bearer", Expiry: expiry}, nil
+}
+
+// subjectToken returns the token of the workload's own identity.
+func (cs *credentialSource) subjectToken(ctx context.Context, hc *http.Client, audience string) (string, error) {
+	if cs.EnvironmentID != "" {
+		return cs.awsSubjectToken(ctx, hc, audience)
+	}
+	var b []byte
+	var err error
+	if cs.File != "" {
+		b, err = ioutil.ReadFile(cs.File)
+	} else {
+		b, err = getURL(ctx, hc, cs.URL, cs.Headers)
+	}
+	if err != nil {
+		return "", err
+	}
+	if cs.Format.Type != "json" {
+		return strings.TrimSpace(string(b)), nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return "", err
+	}
+	token, ok := fields[cs.Format.SubjectTokenFieldName].(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("no %q field in credential source", cs.Format.SubjectTokenFieldName)
+	}
+	return token, nil
+}
+
+// getURL returns the body of a GET request for u with the given headers.
+func getURL(ctx context.Context, hc *http.Client, u string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := ctxhttp.Do(ctx, hc, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: %s: %s", u, res.Status, b)
+	}
+	return b, nil
+}
+
+// doJSON sends req, decoding the JSON response into dst.
+func doJSON(ctx context.Context, hc *http.Client, req *http.Request, dst interface{}) error {
+	res, err := ctxhttp.Do(ctx, hc, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", res.Status, b)
+	}
+	return json.Unmarshal(b, dst)
+}
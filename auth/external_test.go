@@ -0,0 +1,167 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSignAWS(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := &awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("got Authorization\n%s\nwant\n%s", got, want)
+	}
+}
+
+// newSTSServer returns a server exchanging subject tokens accepted by
+// check, and impersonating service accounts at /impersonate.
+func newSTSServer(t *testing.T, check func(subject string)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != tokenExchangeGrantType || r.FormValue("audience") != "aud" {
+			t.Errorf("token exchange with form %v", r.Form)
+		}
+		check(r.FormValue("subject_token"))
+		fmt.Fprintf(w, `{"access_token": "federated", "token_type": "Bearer", "expires_in": 3600, "scope": %q}`, r.FormValue("scope"))
+	})
+	mux.HandleFunc("/impersonate", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer federated" {
+			t.Errorf("impersonation with Authorization %q", got)
+		}
+		var body struct{ Scope []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Scope) != 1 || body.Scope[0] != "s" {
+			t.Errorf("impersonation with scopes %q, want [s]", body.Scope)
+		}
+		fmt.Fprint(w, `{"accessToken": "impersonated", "expireTime": "2030-01-01T00:00:00Z"}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestExternalAccountFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "oidc.json")
+	if err := ioutil.WriteFile(file, []byte(`{"id_token": "oidc"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv := newSTSServer(t, func(subject string) {
+		if subject != "oidc" {
+			t.Errorf("got subject token %q, want oidc", subject)
+		}
+	})
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		impersonate string
+		want        string
+	}{
+		{"", "federated"},
+		{srv.URL + "/impersonate", "impersonated"},
+	} {
+		key, _ := json.Marshal(map[string]interface{}{
+			"type":                              ExternalAccountType,
+			"audience":                          "aud",
+			"subject_token_type":                "urn:ietf:params:oauth:token-type:jwt",
+			"token_url":                         srv.URL + "/token",
+			"service_account_impersonation_url": tt.impersonate,
+			"credential_source": map[string]interface{}{
+				"file":   file,
+				"format": map[string]string{"type": "json", "subject_token_field_name": "id_token"},
+			},
+		})
+		ts, err := ExternalAccountTokenSource(context.Background(), key, "s")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != tt.want {
+			t.Errorf("impersonate %q: got token %q, want %q", tt.impersonate, tok.AccessToken, tt.want)
+		}
+	}
+}
+
+func TestExternalAccountAWS(t *testing.T) {
+	for k, v := range map[string]string{
+		"AWS_REGION":            "us-east-2",
+		"AWS_ACCESS_KEY_ID":     "AKID",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "session",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+	srv := newSTSServer(t, func(subject string) {
+		s, err := url.QueryUnescape(subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var token struct {
+			URL, Method string
+			Headers     []struct{ Key, Value string }
+		}
+		if err := json.Unmarshal([]byte(s), &token); err != nil {
+			t.Fatalf("subject token %q: %v", s, err)
+		}
+		if want := "https://sts.us-east-2.amazonaws.com?Action=GetCallerIdentity&Version=2011-06-15"; token.URL != want || token.Method != "POST" {
+			t.Errorf("got request %s %s, want POST %s", token.Method, token.URL, want)
+		}
+		headers := make(map[string]string)
+		for _, h := range token.Headers {
+			headers[h.Key] = h.Value
+		}
+		if !strings.HasPrefix(headers["authorization"], "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("got Authorization %q", headers["authorization"])
+		}
+		if headers["x-goog-cloud-target-resource"] != "aud" || headers["x-amz-security-token"] != "session" {
+			t.Errorf("got headers %v", headers)
+		}
+	})
+	defer srv.Close()
+
+	key := []byte(`{
+		"type": "external_account",
+		"audience": "aud",
+		"subject_token_type": "urn:ietf:params:aws:token-type:aws4_request",
+		"token_url": "` + srv.URL + `/token",
+		"credential_source": {
+			"environment_id": "aws1",
+			"regional_cred_verification_url": "https://sts.{region}.amazonaws.com?Action=GetCallerIdentity&Version=2011-06-15"
+		}
+	}`)
+	ts, err := ExternalAccountTokenSource(context.Background(), key, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// ReadTokenFile reads a token written by WriteTokenFile.
+func ReadTokenFile(file string) (*oauth2.Token, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	t := new(oauth2.Token)
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WriteTokenFile writes t to file, readable only by its owner. The file
+// is replaced atomically, so that a crash does not leave it truncated.
+func WriteTokenFile(file string, t *oauth2.Token) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// TokenSource returns a TokenSource for f.Config, for programs which run
+// unattended once authorized. It starts from the token saved in file by
+// an earlier run, if there is one. Otherwise it runs the flow, calling
+// prompt with the codes to show the user, and saves the token granted
+// in file. The tokens it refreshes are saved too, so that file always
+// holds the latest.
+func (f *DeviceFlow) TokenSource(ctx context.Context, file string, prompt func(*DeviceCode)) (oauth2.TokenSource, error) {
+	t, err := ReadTokenFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		dc, err := f.Start(ctx)
+		if err != nil {
+			return nil, err
+		}
+		prompt(dc)
+		if t, err = f.Poll(ctx, dc); err != nil {
+			return nil, err
+		}
+		if err := WriteTokenFile(file, t); err != nil {
+			return nil, err
+		}
+	}
+	if f.Client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, f.Client)
+	}
+	return &savingTokenSource{
+		src:  f.Config.TokenSource(ctx, t),
+		file: file,
+		last: t.AccessToken,
+	}, nil
+}
+
+// savingTokenSource writes the tokens of src to file as they change.
+type savingTokenSource struct {
+	src  oauth2.TokenSource
+	file string
+
+	mu   sync.Mutex
+	last string // the access token last saved
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	t, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.AccessToken != s.last {
+		if err := WriteTokenFile(s.file, t); err != nil {
+			return nil, err
+		}
+		s.last = t.AccessToken
+	}
+	return t, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io"
+	"net/http"
+	"os"
+
+	"google.golang.org/api/googleapi"
+)
+
+// replayableBody is a request body which SendRequest can reopen from its
+// source in order to resend the request.
+type replayableBody struct {
+	io.ReadCloser
+	src  googleapi.BodySource
+	size int64 // -1 if unknown
+}
+
+// NewBody returns a request body reading from src. Requests with such
+// bodies, or with none, may be retried by SendRequest.
+func NewBody(src googleapi.BodySource) (io.ReadCloser, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &replayableBody{ReadCloser: rc, src: src, size: bodySize(src, rc)}, nil
+}
+
+// bodySize returns the length of the body read by rc, opened from src,
+// or -1 if it is not known in advance.
+func bodySize(src googleapi.BodySource, rc io.ReadCloser) int64 {
+	if s, ok := src.(interface {
+		Size() int64
+	}); ok {
+		return s.Size()
+	}
+	if f, ok := rc.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+	}
+	return -1
+}
+
+// JSONBody returns a request body holding the JSON encoding of v, which
+// SendRequest can replay. v is encoded with the Codec in settings, which
+// may be nil.
+func JSONBody(style googleapi.MarshalStyle, v interface{}, settings *ServiceSettings) (io.ReadCloser, error) {
+	b, err := settings.codec().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// Match the output of googleapi.MarshalStyle.JSONReader, which ends
+	// the value with a newline.
+	b = append(b, '\n')
+	if style == googleapi.WithDataWrapper {
+		b = append(append([]byte(`{"data": `), b...), '}')
+	}
+	return NewBody(googleapi.BytesBody(b))
+}
+
+// setContentLength sets the ContentLength of req from its body, if that
+// was created by NewBody, since http.NewRequest only recognizes a few
+// types of in-memory readers.
+func setContentLength(req *http.Request) {
+	if rb, ok := req.Body.(*replayableBody); ok && req.ContentLength == 0 && rb.size > 0 {
+		req.ContentLength = rb.size
+	}
+}
+
+// PrepareRequest readies req, built by a generated call, to be sent by
+// means other than SendRequest: it sets the ContentLength of req, as
+// SendRequest would, and if the body was created by NewBody, a GetBody
+// function which reopens it, so that an http.Client can follow
+// redirects which resend it.
+func PrepareRequest(req *http.Request) {
+	setContentLength(req)
+	rb, ok := req.Body.(*replayableBody)
+	if !ok || req.GetBody != nil {
+		return
+	}
+	src, size := rb.src, rb.size
+	req.GetBody = func() (io.ReadCloser, error) {
+		rc, err := src.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &replayableBody{ReadCloser: rc, src: src, size: size}, nil
+	}
+}
+
+// canReplay reports whether req can be sent more than once: it has no
+// body, a body created by NewBody, or a GetBody function.
+func canReplay(req *http.Request) bool {
+	if req.Body == nil || req.GetBody != nil {
+		return true
+	}
+	_, ok := req.Body.(*replayableBody)
+	return ok
+}
+
+// rewindBody replaces the body of req, which must satisfy canReplay,
+// with a new reader of the whole body.
+func rewindBody(req *http.Request) error {
+	switch {
+	case req.Body == nil:
+		return nil
+	case req.GetBody != nil:
+		rc, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Body = rc
+		return nil
+	}
+	rb := req.Body.(*replayableBody)
+	rc, err := rb.src.Open()
+	if err != nil {
+		return err
+	}
+	req.Body = &replayableBody{ReadCloser: rc, src: rb.src, size: rb.size}
+	return nil
+}
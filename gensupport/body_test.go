@@ -0,0 +1,139 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// noPause is a BackoffStrategy which retries immediately, up to max times.
+type noPause struct{ n, max int }
+
+func (b *noPause) Pause() (time.Duration, bool) {
+	b.n++
+	return 0, b.n <= b.max
+}
+
+func (b *noPause) Reset() { b.n = 0 }
+
+func TestSendRequestRetryReplaysBody(t *testing.T) {
+	defer func(f func() BackoffStrategy) { retryBackoff = f }(retryBackoff)
+	retryBackoff = func() BackoffStrategy { return &noPause{max: 3} }
+
+	f, err := ioutil.TempFile("", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("file contents")
+	f.Close()
+
+	for _, tt := range []struct {
+		desc string
+		src  googleapi.BodySource
+		want string
+	}{
+		{"bytes", googleapi.BytesBody([]byte(`{"name":"x"}`)), `{"name":"x"}`},
+		{"file", googleapi.FileBody(f.Name()), "file contents"},
+	} {
+		var bodies []string
+		var lengths []int64
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+			lengths = append(lengths, r.ContentLength)
+			if len(bodies) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+
+		body, err := NewBody(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest("PUT", ts.URL, body)
+		res, err := SendRequest(nil, http.DefaultClient, req, &ServiceSettings{Retry: true}, "test.update")
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: got status %d, want 200", tt.desc, res.StatusCode)
+		}
+		if len(bodies) != 3 {
+			t.Fatalf("%s: got %d attempts, want 3", tt.desc, len(bodies))
+		}
+		for i, b := range bodies {
+			if b != tt.want || lengths[i] != int64(len(tt.want)) {
+				t.Errorf("%s: attempt %d: got body %q of length %d, want %q", tt.desc, i, b, lengths[i], tt.want)
+			}
+		}
+	}
+}
+
+func TestSendRequestNoRetryForStreamedBody(t *testing.T) {
+	defer func(f func() BackoffStrategy) { retryBackoff = f }(retryBackoff)
+	retryBackoff = func() BackoffStrategy { return &noPause{max: 3} }
+
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("streamed"))
+		pw.Close()
+	}()
+	req, _ := http.NewRequest("PUT", ts.URL, pr)
+	res, err := SendRequest(nil, http.DefaultClient, req, &ServiceSettings{Retry: true}, "test.update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}
+
+func TestPrepareRequest(t *testing.T) {
+	body, err := NewBody(googleapi.BytesBody([]byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "http://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PrepareRequest(req)
+	if req.ContentLength != 5 {
+		t.Errorf("ContentLength: got %d, want 5", req.ContentLength)
+	}
+	if req.GetBody == nil {
+		t.Fatal("GetBody not set")
+	}
+	for i := 0; i < 2; i++ {
+		rc, err := req.GetBody()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(b) != "hello" {
+			t.Errorf("GetBody #%d: got %q, %v; want %q", i, b, err, "hello")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+// retryReserve is the number of retries a Breaker's budget starts with,
+// and the most it may accumulate.
+const retryReserve = 10
+
+// Breaker implements the circuit breaker and retry budget described by
+// googleapi.BreakerPolicy. It is safe for concurrent use.
+// A nil *Breaker allows every call and retry.
+type Breaker struct {
+	policy googleapi.BreakerPolicy
+
+	mu       sync.Mutex
+	failures int       // consecutive failed calls
+	openedAt time.Time // when the breaker last opened; zero if closed
+	probing  bool      // whether a probe call is in flight
+	tokens   float64   // retries remaining in the budget
+}
+
+// NewBreaker returns a Breaker following p, or nil if p is nil.
+func NewBreaker(p *googleapi.BreakerPolicy) *Breaker {
+	if p == nil {
+		return nil
+	}
+	return &Breaker{policy: *p, tokens: retryReserve}
+}
+
+// allow reports whether a call may be sent, returning a
+// *googleapi.CircuitOpenError if not. A call allowed while the breaker
+// is open is a probe, whose outcome must be passed to record.
+func (b *Breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	until := b.openedAt.Add(b.policy.Cooldown)
+	if b.probing || now().Before(until) {
+		return &googleapi.CircuitOpenError{Until: until}
+	}
+	b.probing = true
+	return nil
+}
+
+// record notes the outcome of a call allowed by allow.
+func (b *Breaker) record(res *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	var status int
+	if res != nil {
+		status = res.StatusCode
+	}
+	// Cancelled calls say nothing about the health of the API.
+	failed := status >= 500 || (err != nil && err != context.Canceled && err != context.DeadlineExceeded)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	if !b.openedAt.IsZero() || (b.policy.Failures > 0 && b.failures >= b.policy.Failures) {
+		b.openedAt = now()
+	}
+}
+
+// deposit credits the retry budget for a new call.
+func (b *Breaker) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.tokens += b.policy.RetryRatio
+	if b.tokens > retryReserve {
+		b.tokens = retryReserve
+	}
+	b.mu.Unlock()
+}
+
+// withdraw reports whether the retry budget permits another retry,
+// and if so charges it for one.
+func (b *Breaker) withdraw() bool {
+	if b == nil || b.policy.RetryRatio == 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// budgetBackoff is a BackoffStrategy which stops retrying when the
+// retry budget of its Breaker is exhausted.
+type budgetBackoff struct {
+	BackoffStrategy
+	b *Breaker
+}
+
+func (bb budgetBackoff) Pause() (time.Duration, bool) {
+	pause, retry := bb.BackoffStrategy.Pause()
+	return pause, retry && bb.b.withdraw()
+}
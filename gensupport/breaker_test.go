@@ -0,0 +1,109 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	clock := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+
+	status, hits := http.StatusInternalServerError, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	settings := &ServiceSettings{Breaker: NewBreaker(&googleapi.BreakerPolicy{Failures: 2, Cooldown: time.Minute})}
+	call := func() error {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		res, err := SendRequest(nil, http.DefaultClient, req, settings, "test.get")
+		if res != nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	wantOpen := func(desc string) {
+		before := hits
+		err := call()
+		if _, ok := err.(*googleapi.CircuitOpenError); !ok {
+			t.Errorf("%s: got error %v, want *googleapi.CircuitOpenError", desc, err)
+		}
+		if hits != before {
+			t.Errorf("%s: request sent while breaker open", desc)
+		}
+	}
+
+	call()
+	call()
+	wantOpen("after 2 failures")
+
+	// A failed probe keeps the breaker open for another cooldown.
+	clock = clock.Add(time.Minute)
+	if err := call(); err != nil {
+		t.Errorf("probe: got error %v, want nil", err)
+	}
+	wantOpen("after failed probe")
+
+	// A successful probe closes it.
+	clock = clock.Add(time.Minute)
+	status = http.StatusOK
+	call()
+	before := hits
+	if err := call(); err != nil || hits != before+1 {
+		t.Errorf("after successful probe: got error %v and %d requests, want nil and 1", err, hits-before)
+	}
+}
+
+func TestBreakerRetryBudget(t *testing.T) {
+	defer func(f func() BackoffStrategy) { retryBackoff = f }(retryBackoff)
+	retryBackoff = func() BackoffStrategy { return &noPause{max: 100} }
+
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	settings := &ServiceSettings{
+		Retry:   true,
+		Breaker: NewBreaker(&googleapi.BreakerPolicy{RetryRatio: 0.5}),
+	}
+	// The first call spends the reserve; later calls earn half a retry each.
+	for i, want := range []int{1 + retryReserve, 1, 2, 1} {
+		hits = 0
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		res, err := SendRequest(nil, http.DefaultClient, req, settings, "test.get")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if hits != want {
+			t.Errorf("call %d: got %d attempts, want %d", i, hits, want)
+		}
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	if err := b.allow(); err != nil {
+		t.Errorf("allow: got %v, want nil", err)
+	}
+	b.record(nil, nil)
+	b.deposit()
+	if !b.withdraw() {
+		t.Error("withdraw: got false, want true")
+	}
+}
@@ -6,7 +6,10 @@ package gensupport
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 
 	"google.golang.org/api/googleapi"
 )
@@ -26,6 +29,19 @@ func NewMediaBuffer(media io.Reader, chunkSize int) *MediaBuffer {
 	return &MediaBuffer{media: media, chunk: make([]byte, 0, chunkSize)}
 }
 
+// skip discards the first n bytes of the media, which must not have been
+// read yet, so that the first chunk starts at offset n.
+func (mb *MediaBuffer) skip(n int64) error {
+	if mb.off != 0 || len(mb.chunk) != 0 || mb.err != nil {
+		return errors.New("gensupport: media already read")
+	}
+	if m, err := io.CopyN(ioutil.Discard, mb.media, n); err != nil {
+		return fmt.Errorf("gensupport: skipping %d bytes of media already uploaded: got %d: %v", n, m, err)
+	}
+	mb.off = n
+	return nil
+}
+
 // Chunk returns the current buffered chunk, the offset in the underlying media
 // from which the chunk is drawn, and the size of the chunk.
 // Successive calls to Chunk return the same chunk between calls to Next.
@@ -294,3 +294,36 @@ func TestAdapter(t *testing.T) {
 		checkConversion(to, tc.wantTyper)
 	}
 }
+
+func TestMediaBufferSkip(t *testing.T) {
+	mb := NewMediaBuffer(bytes.NewReader([]byte("abcdefghij")), 4)
+	if err := mb.skip(6); err != nil {
+		t.Fatal(err)
+	}
+	var offs []int64
+	var got []byte
+	for {
+		chunk, off, _, err := mb.Chunk()
+		b, _ := ioutil.ReadAll(chunk)
+		offs = append(offs, off)
+		got = append(got, b...)
+		if err != nil {
+			break
+		}
+		mb.Next()
+	}
+	if want := []int64{6, 10}; !reflect.DeepEqual(offs, want) {
+		t.Errorf("chunk offsets: got %v, want %v", offs, want)
+	}
+	if string(got) != "ghij" {
+		t.Errorf("got %q, want %q", got, "ghij")
+	}
+	if err := mb.skip(1); err == nil {
+		t.Error("skipping media already read succeeded")
+	}
+
+	mb = NewMediaBuffer(bytes.NewReader([]byte("abc")), 4)
+	if err := mb.skip(5); err == nil {
+		t.Error("skipping past the end of the media succeeded")
+	}
+}
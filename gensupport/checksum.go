@@ -0,0 +1,79 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// VerifyChecksum arranges for the body of res to be checked against the
+// checksums in its X-Goog-Hash header as it is read. Once the whole body
+// has been read, a mismatch is reported by Read returning a
+// *googleapi.ChecksumError instead of io.EOF.
+//
+// Responses without a checksum, partial responses and responses which
+// were decompressed by the client are left unchanged, since their bodies
+// do not match the checksum of the stored media.
+func VerifyChecksum(res *http.Response) {
+	if res.StatusCode != http.StatusOK || res.Uncompressed || res.Header.Get("Content-Encoding") != "" {
+		return
+	}
+	sums := parseHashHeader(res.Header)
+	// Prefer crc32c, which is cheaper to compute and always present for
+	// objects composed from others, for which there is no md5.
+	var h hash.Hash
+	alg := "crc32c"
+	if sums[alg] != "" {
+		h = crc32.New(crc32cTable)
+	} else if alg = "md5"; sums[alg] != "" {
+		h = md5.New()
+	} else {
+		return
+	}
+	res.Body = &checksumReader{ReadCloser: res.Body, h: h, alg: alg, want: sums[alg]}
+}
+
+// parseHashHeader returns the checksums in an X-Goog-Hash header, such as
+// "crc32c=n03x6A==,md5=Ojk9c3dhfxgoKVVHYwFbHQ==", keyed by algorithm.
+func parseHashHeader(h http.Header) map[string]string {
+	sums := make(map[string]string)
+	for _, v := range h["X-Goog-Hash"] {
+		for _, kv := range strings.Split(v, ",") {
+			i := strings.Index(kv, "=")
+			if i < 0 {
+				continue
+			}
+			sums[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+		}
+	}
+	return sums
+}
+
+type checksumReader struct {
+	io.ReadCloser
+	h         hash.Hash
+	alg, want string
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if got := base64.StdEncoding.EncodeToString(r.h.Sum(nil)); got != r.want {
+			return n, &googleapi.ChecksumError{Algorithm: r.alg, Want: r.want, Got: got}
+		}
+	}
+	return n, err
+}
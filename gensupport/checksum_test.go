@@ -0,0 +1,51 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	// Checksums of "hello world", as sent by Google Cloud Storage.
+	const (
+		crc32c = "crc32c=yZRlqg=="
+		md5    = "md5=XrY7u+Ae7tCTyyK7j1rNww=="
+	)
+	for _, tt := range []struct {
+		desc    string
+		status  int
+		header  []string
+		body    string
+		wantErr bool
+	}{
+		{"both match", 200, []string{crc32c + "," + md5}, "hello world", false},
+		{"separate headers", 200, []string{crc32c, md5}, "hello world", false},
+		{"crc32c mismatch", 200, []string{crc32c}, "hello w0rld", true},
+		{"md5 mismatch", 200, []string{md5}, "hello w0rld", true},
+		{"no checksum", 200, nil, "hello w0rld", false},
+		{"partial content", 206, []string{crc32c}, "hello", false},
+	} {
+		res := &http.Response{
+			StatusCode: tt.status,
+			Header:     http.Header{"X-Goog-Hash": tt.header},
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}
+		VerifyChecksum(res)
+		got, err := ioutil.ReadAll(res.Body)
+		if string(got) != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.desc, got, tt.body)
+		}
+		_, isChecksumErr := err.(*googleapi.ChecksumError)
+		if tt.wantErr != isChecksumErr || (!tt.wantErr && err != nil) {
+			t.Errorf("%s: got error %v, want checksum error: %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
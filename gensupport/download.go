@@ -0,0 +1,166 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+// FetchLink fetches link, the URL held by the field name of a resource,
+// such as its downloadUrl, using client, which should be authorized as
+// the service's is. A response other than a success is closed and
+// returned as a *googleapi.Error. Otherwise, the caller must close the
+// body of the response.
+func FetchLink(ctx context.Context, client *http.Client, name, link string) (*http.Response, error) {
+	if link == "" {
+		return nil, fmt.Errorf("gensupport: %s is empty", name)
+	}
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := SendRequest(ctx, client, req, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := googleapi.CheckResponse(res); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// RangeHeader returns the value of a Range header requesting length bytes
+// starting at offset. A length of zero or less requests the rest of the
+// media.
+func RangeHeader(offset, length int64) string {
+	if length <= 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// downloadBackoff returns the strategy for pausing between attempts to
+// resume a download. It is a variable so that tests need not wait.
+var downloadBackoff = DefaultBackoffStrategy
+
+// ResumeDownload replaces the body of res, a response containing media,
+// with one which resumes the download if reading fails before the whole
+// body has been read. fetch is called with the number of bytes received
+// so far, and must request the media which follows them; it is called
+// at most maxResumes times in all. If ctx is non-nil, no download is
+// resumed once ctx is done.
+//
+// A download is only resumed from a 206 Partial Content response
+// carrying the same ETag, if any, as res, so that the parts are known to
+// come from the same media, and whose Content-Range starts just after
+// the bytes received.
+func ResumeDownload(ctx context.Context, res *http.Response, maxResumes int, fetch func(received int64) (*http.Response, error)) {
+	// A response to a request for a range starts at the range's offset.
+	start, _, _ := parseContentRange(res.Header.Get("Content-Range"))
+	res.Body = &resumingBody{
+		ctx:     ctx,
+		body:    res.Body,
+		etag:    res.Header.Get("Etag"),
+		start:   start,
+		fetch:   fetch,
+		left:    maxResumes,
+		backoff: downloadBackoff(),
+	}
+}
+
+type resumingBody struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	etag     string
+	start    int64 // offset in the media of the first byte of the body
+	fetch    func(received int64) (*http.Response, error)
+	received int64
+	left     int // resumptions left
+	backoff  BackoffStrategy
+	err      error // sticky error, once resumption has failed
+}
+
+var errMediaChanged = errors.New("gensupport: media changed while being downloaded")
+
+// parseContentRange returns the first and last byte positions in v, the
+// value of a Content-Range header such as "bytes 10-19/100".
+func parseContentRange(v string) (first, last int64, ok bool) {
+	if _, err := fmt.Sscanf(v, "bytes %d-%d/", &first, &last); err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+func (r *resumingBody) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.body.Read(p)
+	r.received += int64(n)
+	if err == nil || err == io.EOF || err == context.Canceled || err == context.DeadlineExceeded {
+		return n, err
+	}
+	r.body.Close()
+	var done <-chan struct{}
+	if r.ctx != nil {
+		done = r.ctx.Done()
+	}
+	for r.left > 0 {
+		r.left--
+		if pause, ok := r.backoff.Pause(); ok {
+			t := time.NewTimer(pause)
+			select {
+			case <-done:
+				t.Stop()
+				r.err = r.ctx.Err()
+				return n, r.err
+			case <-t.C:
+			}
+		}
+		res, ferr := r.fetch(r.received)
+		if ferr != nil {
+			err = ferr
+			continue
+		}
+		if res.StatusCode != http.StatusPartialContent {
+			res.Body.Close()
+			err = fmt.Errorf("gensupport: resuming download: got status %d, want %d", res.StatusCode, http.StatusPartialContent)
+			continue
+		}
+		if etag := res.Header.Get("Etag"); r.etag != "" && etag != r.etag {
+			res.Body.Close()
+			err = errMediaChanged
+			break
+		}
+		cr := res.Header.Get("Content-Range")
+		if first, _, ok := parseContentRange(cr); !ok || first != r.start+r.received {
+			res.Body.Close()
+			err = fmt.Errorf("gensupport: resuming download at byte %d: got Content-Range %q", r.start+r.received, cr)
+			break
+		}
+		r.body = res.Body
+		if n > 0 {
+			return n, nil
+		}
+		return r.Read(p)
+	}
+	r.err = err
+	return n, err
+}
+
+func (r *resumingBody) Close() error {
+	if r.err != nil {
+		return nil // the body has already been closed
+	}
+	return r.body.Close()
+}
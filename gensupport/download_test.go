@@ -0,0 +1,180 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+func TestRangeHeader(t *testing.T) {
+	for _, tt := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, 0, "bytes=0-"},
+		{10, 0, "bytes=10-"},
+		{10, 5, "bytes=10-14"},
+	} {
+		if got := RangeHeader(tt.offset, tt.length); got != tt.want {
+			t.Errorf("RangeHeader(%d, %d) = %q, want %q", tt.offset, tt.length, got, tt.want)
+		}
+	}
+}
+
+var errConnReset = errors.New("connection reset")
+
+// brokenBody returns a body which yields s and then fails.
+func brokenBody(s string) io.ReadCloser {
+	return ioutil.NopCloser(&errReader{buf: []byte(s), err: errConnReset})
+}
+
+func TestResumeDownload(t *testing.T) {
+	defer func(old func() BackoffStrategy) { downloadBackoff = old }(downloadBackoff)
+	downloadBackoff = func() BackoffStrategy { return NoPauseStrategy }
+
+	const media = "hello world"
+	for _, tt := range []struct {
+		desc       string
+		maxResumes int
+		parts      []*http.Response // responses to successive fetches
+		wantBody   string
+		wantErr    error
+	}{
+		{
+			desc:       "resumed twice",
+			maxResumes: 2,
+			parts: []*http.Response{
+				{StatusCode: 206, Body: brokenBody(media[3:7])},
+				{StatusCode: 206, Body: ioutil.NopCloser(strings.NewReader(media[7:]))},
+			},
+			wantBody: media,
+		},
+		{
+			desc:       "out of resumes",
+			maxResumes: 1,
+			parts: []*http.Response{
+				{StatusCode: 206, Body: brokenBody(media[3:7])},
+			},
+			wantBody: media[:7],
+			wantErr:  errConnReset,
+		},
+		{
+			desc:       "range ignored",
+			maxResumes: 1,
+			parts: []*http.Response{
+				{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(media))},
+			},
+			wantBody: media[:3],
+		},
+		{
+			desc:       "wrong range",
+			maxResumes: 2,
+			parts: []*http.Response{
+				{StatusCode: 206, Header: http.Header{"Etag": {`"1"`}, "Content-Range": {"bytes 0-10/11"}}, Body: ioutil.NopCloser(strings.NewReader(media))},
+			},
+			wantBody: media[:3],
+		},
+		{
+			desc:       "media changed",
+			maxResumes: 2,
+			parts: []*http.Response{
+				{StatusCode: 206, Header: http.Header{"Etag": {`"2"`}}, Body: ioutil.NopCloser(strings.NewReader(media[3:]))},
+			},
+			wantBody: media[:3],
+			wantErr:  errMediaChanged,
+		},
+	} {
+		var offsets []int64
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Etag": {`"1"`}},
+			Body:       brokenBody(media[:3]),
+		}
+		ResumeDownload(nil, res, tt.maxResumes, func(received int64) (*http.Response, error) {
+			offsets = append(offsets, received)
+			part := tt.parts[0]
+			tt.parts = tt.parts[1:]
+			if part.Header == nil {
+				part.Header = http.Header{
+					"Etag":          {`"1"`},
+					"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", received, len(media)-1, len(media))},
+				}
+			}
+			return part, nil
+		})
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(got) != tt.wantBody {
+			t.Errorf("%s: got body %q, want %q", tt.desc, got, tt.wantBody)
+		}
+		if tt.wantErr != nil && err != tt.wantErr {
+			t.Errorf("%s: got error %v, want %v", tt.desc, err, tt.wantErr)
+		}
+		if complete := tt.wantBody == media; complete != (err == nil) {
+			t.Errorf("%s: got error %v, want error: %v", tt.desc, err, !complete)
+		}
+		if len(offsets) > 0 && offsets[0] != 3 {
+			t.Errorf("%s: first resumed at %d, want 3", tt.desc, offsets[0])
+		}
+	}
+}
+
+func TestResumeDownloadCanceled(t *testing.T) {
+	defer func(old func() BackoffStrategy) { downloadBackoff = old }(downloadBackoff)
+	downloadBackoff = func() BackoffStrategy { return &ExponentialBackoff{Base: time.Hour, Max: time.Hour} }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := &http.Response{StatusCode: 200, Body: brokenBody("abc")}
+	ResumeDownload(ctx, res, 1, func(int64) (*http.Response, error) {
+		t.Fatal("download resumed after its context was canceled")
+		return nil, nil
+	})
+	time.AfterFunc(10*time.Millisecond, cancel)
+	got, err := ioutil.ReadAll(res.Body)
+	if string(got) != "abc" || err != context.Canceled {
+		t.Errorf("got %q, %v, want %q, %v", got, err, "abc", context.Canceled)
+	}
+}
+
+func TestFetchLink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "contents")
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	res, err := FetchLink(ctx, http.DefaultClient, "downloadUrl", srv.URL+"/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || string(b) != "contents" {
+		t.Errorf("got body %q, %v; want %q", b, err, "contents")
+	}
+
+	_, err = FetchLink(ctx, http.DefaultClient, "downloadUrl", srv.URL+"/missing")
+	if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusNotFound {
+		t.Errorf("fetching a missing link: got error %v, want a 404 *googleapi.Error", err)
+	}
+	if _, err := FetchLink(ctx, http.DefaultClient, "downloadUrl", ""); err == nil {
+		t.Error("fetching an empty link: got nil error, want one")
+	}
+}
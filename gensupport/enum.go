@@ -0,0 +1,29 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import "google.golang.org/api/googleapi"
+
+// CheckEnum returns a *googleapi.EnumError for the first of values which
+// is not in allowed. Empty values are taken to be unset, and are always
+// accepted. name names the parameter or field holding the values.
+func CheckEnum(name string, allowed []string, values ...string) error {
+	for _, v := range values {
+		if v == "" || contains(allowed, v) {
+			continue
+		}
+		return &googleapi.EnumError{Name: name, Value: v, Allowed: allowed}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestCheckEnum(t *testing.T) {
+	allowed := []string{"asc", "desc"}
+	for _, values := range [][]string{nil, {"asc"}, {""}, {"desc", "asc"}} {
+		if err := CheckEnum("order", allowed, values...); err != nil {
+			t.Errorf("CheckEnum(%q): %v", values, err)
+		}
+	}
+
+	err := CheckEnum("order", allowed, "asc", "up", "down")
+	want := &googleapi.EnumError{Name: "order", Value: "up", Allowed: allowed}
+	if !reflect.DeepEqual(err, want) {
+		t.Fatalf("got error %#v, want %#v", err, want)
+	}
+	if got, want := err.Error(), `googleapi: invalid value "up" for order; allowed values are "asc", "desc"`; got != want {
+		t.Errorf("got message %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// GzipBody returns a reader of the gzip-compressed contents of body, for
+// use as a request body sent with "Content-Encoding: gzip".
+// body is read completely, and closed if it is an io.Closer.
+func GzipBody(body io.Reader) (io.Reader, error) {
+	if c, ok := body.(io.Closer); ok {
+		defer c.Close()
+	}
+	buf := new(bytes.Buffer)
+	zw := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(zw)
+	zw.Reset(buf)
+	if _, err := io.Copy(zw, body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// GzipStream is like GzipBody, but compresses body as the returned
+// reader is read rather than in advance, so that a large body, such as
+// the media of an upload, is not held in memory. The returned reader
+// must be closed if it is not read to the end. body is closed, if it is
+// an io.Closer, once it has been read or the returned reader is closed.
+func GzipStream(body io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzipWriterPool.Get().(*gzip.Writer)
+		zw.Reset(pw)
+		_, err := io.Copy(zw, body)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		gzipWriterPool.Put(zw)
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
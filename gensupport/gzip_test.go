@@ -0,0 +1,105 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGzipBody(t *testing.T) {
+	const want = `{"name":"object","size":"1024"}`
+	body, err := GzipBody(strings.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "https://www.googleapis.com/storage/v1/b", body)
+	if req.ContentLength <= 0 {
+		t.Errorf("got ContentLength %d, want it set", req.ContentLength)
+	}
+	zr, err := gzip.NewReader(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGzipBodyReusesWriters(t *testing.T) {
+	// Compressors are pooled; each body must be complete and independent.
+	inputs := []string{strings.Repeat("a", 1000), "b", ""}
+	var bodies []io.Reader
+	for _, in := range inputs {
+		body, err := GzipBody(strings.NewReader(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, body)
+	}
+	for i, body := range bodies {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(zr)
+		if err != nil || string(got) != inputs[i] {
+			t.Errorf("body %d: got %q, %v; want %q", i, got, err, inputs[i])
+		}
+	}
+}
+
+func TestGzipStream(t *testing.T) {
+	want := strings.Repeat("media", 100000)
+	zr, err := gzip.NewReader(GzipStream(strings.NewReader(want)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+// endless is an endless body which records when it is closed.
+type endless struct{ closed chan bool }
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(i)
+	}
+	return len(p), nil
+}
+
+func (e endless) Close() error {
+	close(e.closed)
+	return nil
+}
+
+func TestGzipStreamClose(t *testing.T) {
+	body := endless{make(chan bool)}
+	rc := GzipStream(body)
+	if _, err := rc.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	select {
+	case <-body.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the stream did not close its body")
+	}
+}
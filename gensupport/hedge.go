@@ -0,0 +1,176 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// latencyWindow is the number of recent latencies a Hedger keeps.
+	latencyWindow = 100
+	// minLatencySamples is the number of latencies needed before a
+	// Hedger estimates the hedging delay from them.
+	minLatencySamples = 20
+)
+
+// Hedger implements the hedged requests described by
+// googleapi.HedgePolicy. It is safe for concurrent use.
+// A nil *Hedger sends every request once.
+type Hedger struct {
+	policy googleapi.HedgePolicy
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of recent latencies
+	next      int             // index in latencies of the next to replace
+}
+
+// NewHedger returns a Hedger following p, or nil if p is nil.
+func NewHedger(p *googleapi.HedgePolicy) *Hedger {
+	if p == nil {
+		return nil
+	}
+	return &Hedger{policy: *p}
+}
+
+// observe records the latency of a completed call.
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < latencyWindow {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % latencyWindow
+}
+
+// delay returns how long to wait before sending a hedged request.
+func (h *Hedger) delay() time.Duration {
+	h.mu.Lock()
+	if len(h.latencies) < minLatencySamples {
+		h.mu.Unlock()
+		return h.policy.Delay
+	}
+	sorted := append(durations(nil), h.latencies...)
+	h.mu.Unlock()
+	sort.Sort(sorted)
+	d := sorted[len(sorted)*95/100]
+	if d < h.policy.Delay {
+		d = h.policy.Delay
+	}
+	return d
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// canHedge reports whether req may be sent more than once concurrently.
+// Only GET requests without a body qualify.
+func canHedge(req *http.Request) bool {
+	return req.Method == "GET" && req.Body == nil
+}
+
+type attempt struct {
+	res *http.Response
+	err error
+	n   int // index of the request's cancel func
+}
+
+// sendHedged sends req, and a second copy of it if h allows and no
+// response arrives within h's delay. It returns the first successful
+// response, cancelling the other request.
+func sendHedged(ctx context.Context, client *http.Client, req *http.Request, h *Hedger) (*http.Response, error) {
+	if h == nil || !canHedge(req) {
+		return send(ctx, client, req)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := now()
+	results := make(chan attempt, 2)
+	var cancels []context.CancelFunc
+	launch := func() {
+		actx, cancel := context.WithCancel(ctx)
+		n := len(cancels)
+		cancels = append(cancels, cancel)
+		r := new(http.Request)
+		*r = *req
+		r.Header = make(http.Header, len(req.Header))
+		for k, v := range req.Header {
+			r.Header[k] = v
+		}
+		go func() {
+			res, err := send(actx, client, r)
+			results <- attempt{res, err, n}
+		}()
+	}
+	launch()
+	pending := 1
+	timer := time.NewTimer(h.delay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			launch()
+			pending++
+			continue
+		case a := <-results:
+			pending--
+			if a.err != nil {
+				cancels[a.n]()
+				if pending > 0 {
+					// Wait for the other request, which may yet succeed.
+					continue
+				}
+				// Either no hedged request was sent, or both failed.
+				return nil, a.err
+			}
+			h.observe(now().Sub(start))
+			for i, cancel := range cancels {
+				if i != a.n {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				go discard(results)
+			}
+			a.res.Body = cancelOnClose{a.res.Body, cancels[a.n]}
+			return a.res, nil
+		}
+	}
+}
+
+// discard closes the response, if any, to the losing request of a
+// hedged pair, which has already been cancelled.
+func discard(results <-chan attempt) {
+	if a := <-results; a.res != nil {
+		a.res.Body.Close()
+	}
+}
+
+// cancelOnClose is a response body which cancels the context of its
+// request when closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestHedgeUsesFirstResponse(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		hits      int
+		cancelled = make(chan bool, 1)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		n := hits
+		mu.Unlock()
+		if n > 1 {
+			w.Write([]byte("hedged"))
+			return
+		}
+		select {
+		case <-w.(http.CloseNotifier).CloseNotify():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+			cancelled <- false
+			w.Write([]byte("slow"))
+		}
+	}))
+	defer ts.Close()
+
+	settings := &ServiceSettings{Hedger: NewHedger(&googleapi.HedgePolicy{Delay: 10 * time.Millisecond})}
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	res, err := SendRequest(nil, http.DefaultClient, req, settings, "test.get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(b) != "hedged" {
+		t.Errorf("got body %q, want %q", b, "hedged")
+	}
+	if !<-cancelled {
+		t.Error("slow request was not cancelled")
+	}
+}
+
+func TestHedgeOnlyGET(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	settings := &ServiceSettings{Hedger: NewHedger(&googleapi.HedgePolicy{Delay: time.Millisecond})}
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader("{}"))
+	res, err := SendRequest(nil, http.DefaultClient, req, settings, "test.insert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if hits != 1 {
+		t.Errorf("got %d requests, want 1", hits)
+	}
+}
+
+func TestHedgerDelay(t *testing.T) {
+	h := NewHedger(&googleapi.HedgePolicy{Delay: 10 * time.Millisecond})
+	for i := 1; i < minLatencySamples; i++ {
+		h.observe(time.Second)
+	}
+	if got, want := h.delay(), 10*time.Millisecond; got != want {
+		t.Errorf("too few samples: got delay %v, want %v", got, want)
+	}
+	for i := 1; i <= 2*latencyWindow; i++ {
+		h.observe(time.Duration(i%latencyWindow+1) * time.Millisecond)
+	}
+	if got, want := h.delay(), 96*time.Millisecond; got != want {
+		t.Errorf("got delay %v, want %v", got, want)
+	}
+	for i := 0; i < latencyWindow; i++ {
+		h.observe(time.Millisecond)
+	}
+	if got, want := h.delay(), 10*time.Millisecond; got != want {
+		t.Errorf("fast calls: got delay %v, want %v", got, want)
+	}
+}
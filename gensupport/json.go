@@ -35,13 +35,25 @@ func MarshalJSON(schema interface{}, forceSendFields []string) ([]byte, error) {
 }
 
 func schemaToMap(schema interface{}, mustInclude map[string]struct{}) (map[string]interface{}, error) {
+	return structToMap(reflect.ValueOf(schema), mustInclude)
+}
+
+// structToMap does the work of schemaToMap for the struct value s.
+// The fields of untagged embedded structs are included as if they were
+// fields of s, unless s has a field with the same JSON key; this matches
+// the treatment of embedded structs by encoding/json.
+func structToMap(s reflect.Value, mustInclude map[string]struct{}) (map[string]interface{}, error) {
 	m := make(map[string]interface{})
-	s := reflect.ValueOf(schema)
 	st := s.Type()
+	seen := make(map[string]bool)
+	var embedded []reflect.Value
 
 	for i := 0; i < s.NumField(); i++ {
 		jsonTag := st.Field(i).Tag.Get("json")
 		if jsonTag == "" {
+			if st.Field(i).Anonymous && st.Field(i).Type.Kind() == reflect.Struct {
+				embedded = append(embedded, s.Field(i))
+			}
 			continue
 		}
 		tag, err := parseJSONTag(jsonTag)
@@ -51,6 +63,7 @@ func schemaToMap(schema interface{}, mustInclude map[string]struct{}) (map[strin
 		if tag.ignore {
 			continue
 		}
+		seen[tag.apiName] = true
 
 		v := s.Field(i)
 		f := st.Field(i)
@@ -76,6 +89,17 @@ func schemaToMap(schema interface{}, mustInclude map[string]struct{}) (map[strin
 			m[tag.apiName] = v.Interface()
 		}
 	}
+	for _, e := range embedded {
+		em, err := structToMap(e, mustInclude)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range em {
+			if !seen[k] {
+				m[k] = v
+			}
+		}
+	}
 	return m, nil
 }
 
@@ -170,3 +194,22 @@ func isEmptyValue(v reflect.Value) bool {
 	}
 	return false
 }
+
+// VariantType returns the type name of the given variant.
+// If the map doesn't contain the named key or the value is not a string, "" is returned.
+// This is used to support "variant" APIs that can return one of a number of different types.
+func VariantType(t map[string]interface{}) string {
+	s, _ := t["type"].(string)
+	return s
+}
+
+// ConvertVariant uses the JSON encoder/decoder to fill in the struct 'dst' with the fields found in variant 'v'.
+// This is used to support "variant" APIs that can return one of a number of different types.
+// It reports whether the conversion was successful.
+func ConvertVariant(v map[string]interface{}, dst interface{}) bool {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, dst) == nil
+}
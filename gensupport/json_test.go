@@ -352,6 +352,35 @@ func TestSubschema(t *testing.T) {
 	}
 }
 
+func TestEmbeddedStructFields(t *testing.T) {
+	type inner struct {
+		A string `json:"a,omitempty"`
+		B int64  `json:"b,omitempty"`
+	}
+	// The outer B shadows inner.B, as it would for encoding/json.
+	type wire struct {
+		inner
+		B string `json:"b,omitempty"`
+	}
+	for _, tc := range []struct {
+		s               wire
+		forceSendFields []string
+		want            string
+	}{
+		{wire{inner{"x", 1}, ""}, nil, `{"a":"x"}`},
+		{wire{inner{"x", 1}, ""}, []string{"B"}, `{"a":"x","b":""}`},
+		{wire{inner{"", 1}, "1s"}, []string{"A"}, `{"a":"","b":"1s"}`},
+	} {
+		encoded, err := MarshalJSON(tc.s, tc.forceSendFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(encoded); got != tc.want {
+			t.Errorf("MarshalJSON(%+v, %v): got %s, want %s", tc.s, tc.forceSendFields, got, tc.want)
+		}
+	}
+}
+
 // checkMarshalJSON verifies that calling schemaToMap on tc.s yields a result which is equivalent to tc.want.
 func checkMarshalJSON(t *testing.T, tc testCase) {
 	doCheckMarshalJSON(t, tc.s, tc.s.ForceSendFields, tc.want)
@@ -6,12 +6,17 @@ package gensupport
 
 import (
 	"net/url"
+	"strings"
 
 	"google.golang.org/api/googleapi"
 )
 
 // URLParams is a simplified replacement for url.Values
 // that safely builds up URL parameters for encoding.
+//
+// Generated calls hold their parameters in a URLParams and copy it
+// when building each request, so that executing a call does not modify
+// it and a fully configured call may be executed concurrently.
 type URLParams map[string][]string
 
 // Get returns the first value for the given key, or "".
@@ -37,14 +42,70 @@ func (u URLParams) SetMulti(key string, values []string) {
 	u[key] = values
 }
 
+// SetDefault sets the key to value if value is non-empty and the key
+// has no value yet, so that a default set on a Service gives way to one
+// set on a call.
+func (u URLParams) SetDefault(key, value string) {
+	if value != "" && u.Get(key) == "" {
+		u.Set(key, value)
+	}
+}
+
+// Copy returns a copy of u which may be modified without affecting u.
+func (u URLParams) Copy() URLParams {
+	c := make(URLParams, len(u))
+	for k, vs := range u {
+		c[k] = vs // values are replaced, never modified, so they may be shared
+	}
+	return c
+}
+
 // Encode encodes the values into ``URL encoded'' form
-// ("bar=baz&foo=quux") sorted by key.
+// ("bar=baz&foo=quux") sorted by key. The values of a key with several
+// values are kept in the order they were set, as it may be meaningful,
+// as for the ranges of a batch request. The result does not depend on
+// the order in which keys were set, so that request URLs are stable
+// enough for signing, replay and use as cache keys.
 func (u URLParams) Encode() string {
 	return url.Values(u).Encode()
 }
 
+// SetOptions sets the URL parameters of opts in u. Options which set no
+// parameter, such as googleapi.LimitedBy, are skipped.
 func SetOptions(u URLParams, opts ...googleapi.CallOption) {
 	for _, o := range opts {
-		u.Set(o.Get())
+		if key, value := o.Get(); key != "" {
+			u.Set(key, value)
+		}
+	}
+}
+
+// AddParam returns uri with the URL parameter key set to value, unless
+// value is empty or uri already has the parameter, as a session URI
+// returned by the server may.
+func AddParam(uri, key, value string) string {
+	if value == "" {
+		return uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	q := u.Query()
+	if q.Get(key) != "" {
+		return uri
+	}
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// CombineFields combines fields into a single value for the "fields"
+// URL parameter.
+func CombineFields(s []googleapi.Field) string {
+	r := make([]string, len(s))
+	for i, v := range s {
+		r[i] = string(v)
 	}
+	return strings.Join(r, ",")
 }
@@ -0,0 +1,78 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestURLParamsCopy(t *testing.T) {
+	u := URLParams{"fields": {"items"}}
+	c := u.Copy()
+	SetOptions(c, googleapi.QuotaUser("q"), googleapi.LimitedBy("tenant"))
+	c.Set("alt", "json")
+	c.Set("fields", "kind")
+	if got, want := u.Encode(), "fields=items"; got != want {
+		t.Errorf("original: got %q, want %q", got, want)
+	}
+	if got, want := c.Encode(), "alt=json&fields=kind&quotaUser=q"; got != want {
+		t.Errorf("copy: got %q, want %q", got, want)
+	}
+}
+
+func TestURLParamsEncodeSorted(t *testing.T) {
+	u := make(URLParams)
+	u.SetMulti("id", []string{"c", "a", "b"})
+	u.Set("alt", "json")
+	u.Set("fields", "items")
+	// Values keep their order, which may be meaningful.
+	if got, want := u.Encode(), "alt=json&fields=items&id=c&id=a&id=b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := u["id"], []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode modified values: got %q, want %q", got, want)
+	}
+}
+
+func TestURLParamsSetDefault(t *testing.T) {
+	u := URLParams{"userProject": {"call"}}
+	u.SetDefault("userProject", "service")
+	u.SetDefault("quotaUser", "")
+	u.SetDefault("fields", "items")
+	if got, want := u.Encode(), "fields=items&userProject=call"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAddParam(t *testing.T) {
+	for _, tt := range []struct {
+		uri, value, want string
+	}{
+		{"https://h/upload?upload_id=x", "p", "https://h/upload?upload_id=x&userProject=p"},
+		{"https://h/upload?upload_id=x&userProject=q", "p", "https://h/upload?upload_id=x&userProject=q"},
+		{"https://h/upload?upload_id=x", "", "https://h/upload?upload_id=x"},
+	} {
+		if got := AddParam(tt.uri, "userProject", tt.value); got != tt.want {
+			t.Errorf("AddParam(%q, %q): got %q, want %q", tt.uri, tt.value, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkURLParamsEncode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := make(URLParams)
+		u.Set("alt", "json")
+		u.Set("maxResults", "100")
+		u.Set("pageToken", "CiAKGjBpNDd2Nmp2Zml2cXRwYjBpOXA")
+		u.SetMulti("projection", []string{"full", "noAcl"})
+		u.Set("fields", "items(name,size)")
+		SetOptions(u, googleapi.QuotaUser("user"))
+		u.Encode()
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+// Ping returns the result of a Service's Ping method, which calls the
+// method with ID methodID with do, usually a call's doRequest method.
+func Ping(methodID string, do func() (*http.Response, error)) *googleapi.PingResult {
+	start := time.Now()
+	res, err := do()
+	return pingResult(methodID, start, res, err)
+}
+
+// pingResult returns the result of a request started at start, closing
+// the body of res.
+func pingResult(methodID string, start time.Time, res *http.Response, err error) *googleapi.PingResult {
+	r := &googleapi.PingResult{Method: methodID, Latency: time.Since(start), Err: err}
+	if res != nil {
+		defer res.Body.Close()
+		r.StatusCode = res.StatusCode
+		if res.Request != nil {
+			// The query is left out, as it may hold an API key.
+			u := *res.Request.URL
+			u.RawQuery = ""
+			r.URL = u.String()
+		}
+		r.Err = googleapi.CheckResponse(res)
+	}
+	return r
+}
+
+// PingOptions sends an OPTIONS request to basePath with client, for the
+// Ping method of a Service whose API has no method cheap enough to call.
+func PingOptions(ctx context.Context, client *http.Client, basePath, userAgent string) *googleapi.PingResult {
+	start := time.Now()
+	req, err := http.NewRequest("OPTIONS", basePath, nil)
+	if err != nil {
+		return &googleapi.PingResult{Method: "OPTIONS", URL: basePath, Err: err}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	res, err := SendRequest(ctx, client, req, nil, "")
+	r := pingResult("OPTIONS", start, res, err)
+	r.URL = basePath
+	return r
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestPing(t *testing.T) {
+	for _, tt := range []struct {
+		status        int
+		wantOK        bool
+		wantDiagnosis string
+	}{
+		{200, true, "ok: "},
+		{400, true, "ok: "},
+		{401, false, "credentials: "},
+		{403, false, "credentials: "},
+		{404, false, "base URL: "},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		r := Ping("test.items.list", func() (*http.Response, error) {
+			return http.Get(srv.URL + "/items?key=secret")
+		})
+		if r.StatusCode != tt.status || r.OK() != tt.wantOK || !strings.HasPrefix(r.Diagnosis(), tt.wantDiagnosis) {
+			t.Errorf("status %d: got %d, OK %v, %q; want OK %v, %q...", tt.status, r.StatusCode, r.OK(), r.Diagnosis(), tt.wantOK, tt.wantDiagnosis)
+		}
+		if r.URL != srv.URL+"/items" {
+			t.Errorf("status %d: got URL %q, want it without its query", tt.status, r.URL)
+		}
+
+		r = PingOptions(context.Background(), http.DefaultClient, srv.URL+"/", "ua")
+		if r.Method != "OPTIONS" || r.StatusCode != tt.status {
+			t.Errorf("status %d: PingOptions got %s %d", tt.status, r.Method, r.StatusCode)
+		}
+		srv.Close()
+	}
+
+	r := PingOptions(context.Background(), http.DefaultClient, "http://127.0.0.1:0/", "ua")
+	if r.Reachable() || r.OK() || !strings.HasPrefix(r.Diagnosis(), "network: ") {
+		t.Errorf("unreachable server: got %+v, %q", r, r.Diagnosis())
+	}
+}
@@ -8,11 +8,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"golang.org/x/net/context"
 	"golang.org/x/net/context/ctxhttp"
+	"google.golang.org/api/googleapi"
 )
 
 const (
@@ -25,6 +27,14 @@ const (
 	// should be retried.
 	// https://cloud.google.com/storage/docs/json_api/v1/status-codes#standardcodes
 	statusTooManyRequests = 429
+
+	// uploadIDHeader names the header in which the Google uploader
+	// identifies the upload session.
+	uploadIDHeader = "X-GUploader-UploadID"
+
+	// maxUploadRedirects is the number of times in a row the upload
+	// session may be moved before the upload fails.
+	maxUploadRedirects = 10
 )
 
 // ResumableUpload is used by the generated APIs to provide resumable uploads.
@@ -47,6 +57,24 @@ type ResumableUpload struct {
 
 	// If not specified, a default exponential backoff strategy will be used.
 	Backoff BackoffStrategy
+
+	// Tracer, if non-nil, is used to create a span named MethodID
+	// covering the whole upload.
+	Tracer   googleapi.Tracer
+	MethodID string
+
+	// Throttle, if non-nil, limits the rate at which chunks are sent.
+	Throttle *Throttle
+
+	// UploadID is the upload session ID most recently reported by the
+	// server in the X-GUploader-UploadID header. It is kept in URI when
+	// the server moves the session to another URL.
+	UploadID string
+
+	// SessionMoved, if non-nil, is called with the new URI whenever the
+	// server moves the upload session to another URL, such as another
+	// host, so that a saved session URI can be kept up to date.
+	SessionMoved func(sessionURI string)
 }
 
 // Progress returns the number of bytes uploaded at this point.
@@ -61,7 +89,7 @@ func (rx *ResumableUpload) Progress() int64 {
 // size is the number of bytes in data.
 // final specifies whether data is the final chunk to be uploaded.
 func (rx *ResumableUpload) doUploadRequest(ctx context.Context, data io.Reader, off, size int64, final bool) (*http.Response, error) {
-	req, err := http.NewRequest("POST", rx.URI, data)
+	req, err := http.NewRequest("POST", rx.URI, rx.Throttle.Reader(data))
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +108,60 @@ func (rx *ResumableUpload) doUploadRequest(ctx context.Context, data io.Reader,
 	req.Header.Set("Content-Range", contentRange)
 	req.Header.Set("Content-Type", rx.MediaType)
 	req.Header.Set("User-Agent", rx.UserAgent)
-	return ctxhttp.Do(ctx, rx.Client, req)
+	return ctxhttp.Do(ctx, rx.noRedirectClient(), req)
+}
 
+// noRedirectClient returns a copy of rx.Client which does not follow
+// redirects. The client would resend a chunk without its body, or not
+// at all, so Upload follows them itself.
+func (rx *ResumableUpload) noRedirectClient() *http.Client {
+	client := http.DefaultClient
+	if rx.Client != nil {
+		client = rx.Client
+	}
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &c
+}
+
+// isRedirect reports whether res asks for the chunk to be sent again to
+// another URL.
+func isRedirect(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		return res.Header.Get("Location") != ""
+	}
+	return false
+}
+
+// moveSession updates rx.URI to loc, which is resolved against the
+// current URI. If loc lacks the upload ID of the session, it is added.
+func (rx *ResumableUpload) moveSession(loc string) error {
+	base, err := url.Parse(rx.URI)
+	if err != nil {
+		return err
+	}
+	u, err := base.Parse(loc)
+	if err != nil {
+		return fmt.Errorf("invalid upload session location %q: %v", loc, err)
+	}
+	if rx.UploadID != "" {
+		q := u.Query()
+		if q.Get("upload_id") == "" {
+			q.Set("upload_id", rx.UploadID)
+			u.RawQuery = q.Encode()
+		}
+	}
+	if u.String() == rx.URI {
+		return nil
+	}
+	rx.URI = u.String()
+	if rx.SessionMoved != nil {
+		rx.SessionMoved(rx.URI)
+	}
+	return nil
 }
 
 // reportProgress calls a user-supplied callback to report upload progress.
@@ -112,6 +192,18 @@ func (rx *ResumableUpload) transferChunk(ctx context.Context) (*http.Response, e
 		return res, err
 	}
 
+	if id := res.Header.Get(uploadIDHeader); id != "" {
+		rx.UploadID = id
+	}
+	// The session may move with a redirect, which resends the chunk, or
+	// with the Location of a 308, which accepts it.
+	if loc := res.Header.Get("Location"); loc != "" && (isRedirect(res) || res.StatusCode == statusResumeIncomplete) {
+		if err := rx.moveSession(loc); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+	}
+
 	if res.StatusCode == statusResumeIncomplete || res.StatusCode == http.StatusOK {
 		rx.reportProgress(off, off+int64(size))
 	}
@@ -122,6 +214,55 @@ func (rx *ResumableUpload) transferChunk(ctx context.Context) (*http.Response, e
 	return res, nil
 }
 
+// Resume continues an upload begun earlier, possibly by another process,
+// whose session is at rx.URI. It asks the server how much of the media
+// it has received, skips that much of rx.Media, which must supply the
+// media from its start, and uploads the rest as Upload does. If the
+// server has already received the whole media, its final response is
+// returned.
+func (rx *ResumableUpload) Resume(ctx context.Context) (*http.Response, error) {
+	backoff := rx.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoffStrategy()
+	}
+	res, err := Retry(ctx, func() (*http.Response, error) {
+		return rx.queryStatus(ctx)
+	}, backoff)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != statusResumeIncomplete {
+		return res, nil
+	}
+	res.Body.Close()
+	var received int64
+	if r := res.Header.Get("Range"); r != "" {
+		var first, last int64
+		if _, err := fmt.Sscanf(r, "bytes=%d-%d", &first, &last); err != nil || first != 0 {
+			return nil, fmt.Errorf("gensupport: invalid Range %q in upload status", r)
+		}
+		received = last + 1
+	}
+	if err := rx.Media.skip(received); err != nil {
+		return nil, err
+	}
+	rx.reportProgress(0, received)
+	return rx.Upload(ctx)
+}
+
+// queryStatus asks the server how much of the media it has received for
+// the upload session at rx.URI, with an empty request whose
+// Content-Range gives neither the range nor the size of the media.
+func (rx *ResumableUpload) queryStatus(ctx context.Context) (*http.Response, error) {
+	req, err := http.NewRequest("POST", rx.URI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Range", "bytes */*")
+	req.Header.Set("User-Agent", rx.UserAgent)
+	return ctxhttp.Do(ctx, rx.noRedirectClient(), req)
+}
+
 func contextDone(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
@@ -143,6 +284,13 @@ func (rx *ResumableUpload) Upload(ctx context.Context) (resp *http.Response, err
 	if backoff == nil {
 		backoff = DefaultBackoffStrategy()
 	}
+	retries := 0
+	redirects := 0
+	if span := startSpan(ctx, rx.Tracer, rx.MethodID); span != nil {
+		defer func() {
+			finishSpan(span, resp, googleapi.SpanInfo{Err: err, Retries: retries, BytesSent: rx.Progress()})
+		}()
+	}
 
 	for {
 		// Ensure that we return in the case of cancelled context, even if pause is 0.
@@ -162,6 +310,18 @@ func (rx *ResumableUpload) Upload(ctx context.Context) (resp *http.Response, err
 			status = resp.StatusCode
 		}
 
+		// If the session has moved, send the chunk again to its new URI
+		// without any delay.
+		if resp != nil && isRedirect(resp) {
+			resp.Body.Close()
+			if redirects++; redirects > maxUploadRedirects {
+				return nil, fmt.Errorf("upload session moved more than %d times", maxUploadRedirects)
+			}
+			pause = 0
+			continue
+		}
+		redirects = 0
+
 		// Check if we should retry the request.
 		if shouldRetry(status, err) {
 			var retry bool
@@ -170,6 +330,7 @@ func (rx *ResumableUpload) Upload(ctx context.Context) (resp *http.Response, err
 				if resp != nil && resp.Body != nil {
 					resp.Body.Close()
 				}
+				retries++
 				continue
 			}
 		}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
@@ -186,6 +187,48 @@ func TestInterruptedTransferChunks(t *testing.T) {
 	}
 }
 
+func TestUploadUnknownLength(t *testing.T) {
+	// Media read from a pipe is uploaded in chunks without its length being
+	// known until the writer closes the pipe.
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 5; i++ {
+			pw.Write([]byte(strings.Repeat("a", 5)))
+		}
+		pw.Close()
+	}()
+	media, mb := PrepareUpload(pr, 10)
+	if media != nil || mb == nil {
+		t.Fatal("PrepareUpload did not choose a chunked upload")
+	}
+	tr := &interruptibleTransport{
+		buf: make([]byte, 0, 25),
+		events: []event{
+			{"bytes 0-9/*", 308},
+			{"bytes 10-19/*", 308},
+			{"bytes 20-24/25", 200},
+		},
+		bodies: bodyTracker{},
+	}
+	rx := &ResumableUpload{
+		Client:    &http.Client{Transport: tr},
+		Media:     mb,
+		MediaType: "text/plain",
+		Backoff:   NoPauseStrategy,
+	}
+	res, err := rx.Upload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := string(tr.buf), strings.Repeat("a", 25); got != want {
+		t.Errorf("transferred contents: got %q, want %q", got, want)
+	}
+	if len(tr.events) > 0 {
+		t.Errorf("did not observe all expected events.  leftover events: %v", tr.events)
+	}
+}
+
 func TestCancelUploadFast(t *testing.T) {
 	const (
 		chunkSize = 90
@@ -271,3 +314,185 @@ func TestCancelUpload(t *testing.T) {
 		t.Errorf("unclosed request bodies: %v", tr.bodies)
 	}
 }
+
+// movingTransport answers each upload request with the next of its
+// responses, recording the URL and body of the request.
+type movingTransport struct {
+	responses []*http.Response
+	urls      []string
+	buf       []byte
+}
+
+func (t *movingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	t.urls = append(t.urls, req.URL.String())
+	res := t.responses[0]
+	t.responses = t.responses[1:]
+	if res.StatusCode == 308 || res.StatusCode == http.StatusOK {
+		t.buf = append(t.buf, b...)
+	}
+	res.Body = ioutil.NopCloser(strings.NewReader(""))
+	return res, nil
+}
+
+func TestUploadSessionMoved(t *testing.T) {
+	response := func(status int, header ...string) *http.Response {
+		h := http.Header{}
+		for i := 0; i < len(header); i += 2 {
+			h.Set(header[i], header[i+1])
+		}
+		return &http.Response{StatusCode: status, Header: h}
+	}
+	tr := &movingTransport{
+		responses: []*http.Response{
+			response(308, "X-GUploader-UploadID", "abc"),
+			// The chunk is sent again to the new host, which is told the upload ID.
+			response(http.StatusTemporaryRedirect, "Location", "https://b.example.com/upload"),
+			response(308, "Location", "https://c.example.com/upload?upload_id=xyz"),
+			response(http.StatusOK),
+		},
+	}
+	var moves []string
+	rx := &ResumableUpload{
+		Client:       &http.Client{Transport: tr},
+		URI:          "https://a.example.com/upload?upload_id=abc",
+		Media:        NewMediaBuffer(strings.NewReader(strings.Repeat("a", 30)), 10),
+		MediaType:    "text/plain",
+		Backoff:      NoPauseStrategy,
+		SessionMoved: func(uri string) { moves = append(moves, uri) },
+	}
+	res, err := rx.Upload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	wantURLs := []string{
+		"https://a.example.com/upload?upload_id=abc",
+		"https://a.example.com/upload?upload_id=abc",
+		"https://b.example.com/upload?upload_id=abc",
+		"https://c.example.com/upload?upload_id=xyz",
+	}
+	if !reflect.DeepEqual(tr.urls, wantURLs) {
+		t.Errorf("request URLs:\ngot  %q\nwant %q", tr.urls, wantURLs)
+	}
+	if !reflect.DeepEqual(moves, wantURLs[2:]) {
+		t.Errorf("SessionMoved: got %q, want %q", moves, wantURLs[2:])
+	}
+	if got, want := string(tr.buf), strings.Repeat("a", 30); got != want {
+		t.Errorf("transferred contents: got %q, want %q", got, want)
+	}
+	if rx.UploadID != "abc" {
+		t.Errorf("UploadID: got %q, want %q", rx.UploadID, "abc")
+	}
+}
+
+func TestUploadSessionMovedTooOften(t *testing.T) {
+	tr := &movingTransport{}
+	for i := 0; i <= maxUploadRedirects; i++ {
+		tr.responses = append(tr.responses, &http.Response{
+			StatusCode: http.StatusFound,
+			Header:     http.Header{"Location": {fmt.Sprintf("/upload/%d", i)}},
+		})
+	}
+	rx := &ResumableUpload{
+		Client:    &http.Client{Transport: tr},
+		URI:       "https://example.com/upload",
+		Media:     NewMediaBuffer(strings.NewReader("a"), 10),
+		MediaType: "text/plain",
+		Backoff:   NoPauseStrategy,
+	}
+	if _, err := rx.Upload(context.Background()); err == nil {
+		t.Error("got nil error, want one")
+	}
+}
+
+// sessionServer is an upload session which has received some of the
+// media before being interrupted.
+type sessionServer struct {
+	received []byte
+	ranges   []string // Content-Range of each request
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cr := r.Header.Get("Content-Range")
+	s.ranges = append(s.ranges, cr)
+	body, _ := ioutil.ReadAll(r.Body)
+	first, last, size := int64(len(s.received)), int64(0), int64(0)
+	switch {
+	case cr == "bytes */*": // a status query
+	case strings.HasPrefix(cr, "bytes */"):
+		fmt.Sscanf(cr, "bytes */%d", &size)
+	case strings.HasSuffix(cr, "/*"):
+		fmt.Sscanf(cr, "bytes %d-", &first)
+	default:
+		fmt.Sscanf(cr, "bytes %d-%d/%d", &first, &last, &size)
+	}
+	if cr != "bytes */*" {
+		if first != int64(len(s.received)) {
+			http.Error(w, "wrong offset", http.StatusBadRequest)
+			return
+		}
+		s.received = append(s.received, body...)
+	}
+	if size > 0 && int64(len(s.received)) == size {
+		io.WriteString(w, `{"id":"done"}`)
+		return
+	}
+	if len(s.received) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.received)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+func TestResumeUpload(t *testing.T) {
+	const media = "abcdefghij"
+	for _, tt := range []struct {
+		received   string
+		wantRanges []string
+	}{
+		{"abcd", []string{"bytes */*", "bytes 4-6/*", "bytes 7-9/*", "bytes */10"}},
+		{"", []string{"bytes */*", "bytes 0-2/*", "bytes 3-5/*", "bytes 6-8/*", "bytes 9-9/10"}},
+		{media, []string{"bytes */*"}},
+	} {
+		s := &sessionServer{received: []byte(tt.received)}
+		srv := httptest.NewServer(s)
+		var progress []int64
+		rx := &ResumableUpload{
+			URI:       srv.URL,
+			Media:     NewMediaBuffer(strings.NewReader(media), 3),
+			MediaType: "text/plain",
+			Backoff:   NoPauseStrategy,
+			Callback:  func(n int64) { progress = append(progress, n) },
+		}
+		if tt.received == media {
+			// The session has completed.
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.ranges = append(s.ranges, r.Header.Get("Content-Range"))
+				io.WriteString(w, `{"id":"done"}`)
+			})
+		}
+		res, err := rx.Resume(context.Background())
+		srv.Close()
+		if err != nil {
+			t.Errorf("received %q: %v", tt.received, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || string(body) != `{"id":"done"}` {
+			t.Errorf("received %q: got response %d %q", tt.received, res.StatusCode, body)
+		}
+		if string(s.received) != media {
+			t.Errorf("received %q: server has %q, want %q", tt.received, s.received, media)
+		}
+		if !reflect.DeepEqual(s.ranges, tt.wantRanges) {
+			t.Errorf("received %q: got requests %q, want %q", tt.received, s.ranges, tt.wantRanges)
+		}
+		if tt.received != media && progress[len(progress)-1] != int64(len(media)) {
+			t.Errorf("received %q: progress %v does not end at %d", tt.received, progress, len(media))
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package gensupport
 
 import (
+	"bytes"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
 
 	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
 )
 
 // Retry invokes the given function, retrying it multiple times if the connection failed or
@@ -20,9 +23,17 @@ func Retry(ctx context.Context, f func() (*http.Response, error), backoff Backof
 			status = resp.StatusCode
 		}
 
-		// Return if we shouldn't retry.
+		// Return if we shouldn't retry. The backoff is only consulted
+		// when a retry is needed, since doing so may use up a retry budget.
+		retry, ok := limitRetryable(resp)
+		if !ok {
+			retry = shouldRetry(status, err)
+		}
+		if !retry {
+			return resp, err
+		}
 		pause, retry := backoff.Pause()
-		if !shouldRetry(status, err) || !retry {
+		if !retry {
 			return resp, err
 		}
 
@@ -52,6 +63,42 @@ func DefaultBackoffStrategy() BackoffStrategy {
 	}
 }
 
+// maxLimitPeek is the most of an error response read to find whether it
+// reports an exceeded limit.
+const maxLimitPeek = 64 << 10
+
+// limitRetryable reports, for a response with status 403 or 429 whose
+// body reports that a rate limit or quota was exceeded, whether the
+// request should be attempted again, as given by googleapi.ClassifyLimit.
+// ok is false for other responses. The body of resp remains readable.
+func limitRetryable(resp *http.Response) (retry, ok bool) {
+	if resp == nil || resp.Body == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != statusTooManyRequests) {
+		return false, false
+	}
+	peek, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxLimitPeek))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+	if err != nil {
+		return false, false
+	}
+	e := googleapi.CheckResponse(&http.Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       ioutil.NopCloser(bytes.NewReader(peek)),
+	})
+	r, ok := googleapi.ClassifyLimit(e).(interface {
+		Retryable() bool
+	})
+	if !ok {
+		return false, false
+	}
+	return r.Retryable(), true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // shouldRetry returns true if the HTTP response / error indicates that the
 // request should be attempted again.
 func shouldRetry(status int, err error) bool {
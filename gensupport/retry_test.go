@@ -3,8 +3,10 @@ package gensupport
 import (
 	"errors"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -72,6 +74,46 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestRetryLimitReasons(t *testing.T) {
+	body := func(reason string) string {
+		return `{"error":{"code":403,"errors":[{"reason":"` + reason + `"}]}}`
+	}
+	for _, tt := range []struct {
+		status    int
+		body      string
+		wantCalls int
+	}{
+		{403, body("rateLimitExceeded"), 2},
+		{403, body("userRateLimitExceeded"), 2},
+		{403, body("forbidden"), 1},
+		{429, body("quotaExceeded"), 1},
+		{403, body("dailyLimitExceeded"), 1},
+		{429, "Too many requests", 2},
+	} {
+		calls := 0
+		f := func() (*http.Response, error) {
+			calls++
+			if calls > 1 {
+				return &http.Response{StatusCode: 200}, nil
+			}
+			return &http.Response{StatusCode: tt.status, Body: ioutil.NopCloser(strings.NewReader(tt.body))}, nil
+		}
+		resp, err := Retry(nil, f, &LimitRetryStrategy{Max: 3, Strategy: NoPauseStrategy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%d %s: %d calls, want %d", tt.status, tt.body, calls, tt.wantCalls)
+		}
+		if resp.StatusCode != 200 {
+			// The body must still be readable to report the error.
+			if got, _ := ioutil.ReadAll(resp.Body); string(got) != tt.body {
+				t.Errorf("%d %s: body %q after retry decision", tt.status, tt.body, got)
+			}
+		}
+	}
+}
+
 type checkCloseReader struct {
 	closed bool
 }
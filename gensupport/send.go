@@ -0,0 +1,394 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+	"google.golang.org/api/googleapi"
+)
+
+// ServiceSettings holds options which apply to every call made through
+// a generated Service. The zero value sends each request unchanged.
+type ServiceSettings struct {
+	// DryRun, if true, causes SendRequest to return the built request
+	// in a *googleapi.DryRunError instead of sending it. Unless the
+	// request is then sent, the caller must close its Body, if non-nil,
+	// since that of a media upload is written by a goroutine.
+	DryRun bool
+
+	// DisallowUnknownFields, if true, causes DecodeResponse to fail when
+	// a response contains fields not present in the target type.
+	DisallowUnknownFields bool
+
+	// OmitAPIClientHeader, if true, stops SendRequest from adding the
+	// x-goog-api-client header to requests.
+	OmitAPIClientHeader bool
+
+	// Tracer, if non-nil, is used to create a span for each request.
+	Tracer googleapi.Tracer
+
+	// MethodOverride, if true, causes SendRequest to send requests using
+	// HTTP methods other than GET and POST as POST requests, naming the
+	// original method in the X-HTTP-Method-Override header. This allows
+	// calls to pass through proxies which only permit GET and POST.
+	MethodOverride bool
+
+	// Retry, if true, causes SendRequest to resend requests which fail
+	// with a 5xx or 429 status or a temporary network error, pausing
+	// between attempts with exponential backoff. Requests exceeding a
+	// rate limit are retried even with a 403 status, while those which
+	// exhausted a quota are not; see googleapi.ClassifyLimit. Only calls
+	// to idempotent methods, without a body or with one created by
+	// NewBody or JSONBody, are retried.
+	Retry bool
+
+	// Breaker, if non-nil, is a circuit breaker and retry budget shared
+	// by all calls made with these settings.
+	Breaker *Breaker
+
+	// Hedger, if non-nil, sends a second copy of slow GET requests and
+	// uses whichever response arrives first.
+	Hedger *Hedger
+
+	// OnRateLimit, if non-nil, is called with the method ID of each call
+	// whose response reports quota information, and that information.
+	OnRateLimit func(methodID string, rl *googleapi.RateLimit)
+
+	// OnDeprecation, if non-nil, is called with the method ID of each
+	// call whose response announces that the endpoint is deprecated or
+	// will be shut down, and the announcement. If nil, the first such
+	// announcement for each method is logged.
+	OnDeprecation func(methodID string, d *googleapi.Deprecation)
+
+	// Codec, if non-nil, replaces googleapi.JSONCodec for encoding
+	// request bodies and decoding responses.
+	Codec googleapi.Codec
+
+	// ValidateEnums, if true, causes calls to check the values of enum
+	// parameters and request fields before sending, failing with a
+	// *googleapi.EnumError if one is not permitted.
+	ValidateEnums bool
+
+	// OnDecode, if non-nil, is called with the method ID of each call
+	// whose response has been decoded, and the decoded value.
+	OnDecode func(methodID string, v interface{})
+
+	// OnSLOViolation, if non-nil, is called with each call which takes
+	// longer than the budget of the latency class declared for its
+	// method with googleapi.SetLatencyClass. If nil, the function set by
+	// googleapi.HandleSLOViolations, if any, is called instead.
+	OnSLOViolation func(v *googleapi.SLOViolation)
+}
+
+// AfterDecode calls the OnDecode function of s, if any, with the method
+// ID of a call and the value decoded from its response.
+func (s *ServiceSettings) AfterDecode(methodID string, v interface{}) {
+	if s.OnDecode != nil {
+		s.OnDecode(methodID, v)
+	}
+}
+
+// codec returns the Codec to use with s, which may be nil.
+func (s *ServiceSettings) codec() googleapi.Codec {
+	if s == nil || s.Codec == nil {
+		return googleapi.JSONCodec
+	}
+	return s.Codec
+}
+
+// retryBackoff returns the strategy for pausing between attempts to send
+// a request. It is overridden in tests.
+var retryBackoff = DefaultBackoffStrategy
+
+// apiClientHeader is the value of the x-goog-api-client header, which
+// identifies the Go version and the version of this library to the server.
+var apiClientHeader = "gl-go/" + goVersion() + " gdcl/" + googleapi.Version
+
+// goVersion returns the Go version in the form used by other Google
+// client libraries, e.g. "1.6.2".
+func goVersion() string {
+	v := runtime.Version()
+	if strings.HasPrefix(v, "go") {
+		return v[len("go"):]
+	}
+	// A development version, e.g. "devel +b0532a9".
+	return strings.Replace(v, " ", "_", -1)
+}
+
+// SendRequest sends a single HTTP request using the given client.
+// If ctx is non-nil, the request is aborted when ctx is done.
+// settings may be nil. methodID is the discovery ID of the method
+// being called, which names its span when tracing is enabled.
+func SendRequest(ctx context.Context, client *http.Client, req *http.Request, settings *ServiceSettings, methodID string) (*http.Response, error) {
+	if settings == nil {
+		settings = &ServiceSettings{}
+	}
+	if !settings.OmitAPIClientHeader && req.Header.Get("X-Goog-Api-Client") == "" {
+		req.Header.Set("X-Goog-Api-Client", apiClientHeader)
+	}
+	if settings.MethodOverride && req.Method != "GET" && req.Method != "POST" {
+		req.Header.Set("X-HTTP-Method-Override", req.Method)
+		req.Method = "POST"
+	}
+	setContentLength(req)
+	if settings.DryRun {
+		return nil, &googleapi.DryRunError{Request: req}
+	}
+	if class, ok := googleapi.LookupLatencyClass(methodID); ok {
+		start := time.Now()
+		res, err := sendRetried(ctx, client, req, settings, methodID)
+		if d := time.Since(start); d > class.Budget {
+			settings.reportSLOViolation(&googleapi.SLOViolation{MethodID: methodID, Class: class, Latency: d, Err: err}, res)
+		}
+		return res, err
+	}
+	return sendRetried(ctx, client, req, settings, methodID)
+}
+
+// reportSLOViolation passes v, the violation of a call which received
+// res, to the handler of s or the default one.
+func (s *ServiceSettings) reportSLOViolation(v *googleapi.SLOViolation, res *http.Response) {
+	if res != nil {
+		v.StatusCode = res.StatusCode
+	}
+	f := s.OnSLOViolation
+	if f == nil {
+		f = googleapi.SLOHandler()
+	}
+	if f != nil {
+		f(v)
+	}
+}
+
+// sendRetried sends req, retrying it if settings allow. If settings has
+// a Tracer, the attempts share one span, which records their number.
+func sendRetried(ctx context.Context, client *http.Client, req *http.Request, settings *ServiceSettings, methodID string) (*http.Response, error) {
+	settings.Breaker.deposit()
+	span := startSpan(ctx, settings.Tracer, methodID)
+	var attempts int
+	var sent *int64 // body bytes sent by the last attempt, if traced
+	if span != nil {
+		sent = new(int64)
+	}
+	send := func() (*http.Response, error) {
+		attempts++
+		return sendChecked(ctx, client, req, settings, methodID, sent)
+	}
+	var res *http.Response
+	var err error
+	if !settings.Retry || !canReplay(req) || !idempotent(methodID, req) {
+		res, err = send()
+	} else {
+		res, err = Retry(ctx, func() (*http.Response, error) {
+			if attempts > 0 {
+				if err := rewindBody(req); err != nil {
+					return nil, err
+				}
+			}
+			return send()
+		}, budgetBackoff{retryBackoff(), settings.Breaker})
+	}
+	if span != nil {
+		finishSpan(span, res, googleapi.SpanInfo{Err: err, Retries: attempts - 1, BytesSent: *sent})
+	}
+	return res, err
+}
+
+// WaitLimiter pauses until the googleapi.Limiter chosen by a
+// googleapi.LimitedBy option among opts, if any, allows a call, or ctx
+// is done.
+func WaitLimiter(ctx context.Context, opts ...googleapi.CallOption) error {
+	if l, ok := googleapi.LimiterFromOptions(opts...); ok {
+		return l.Wait(ctx)
+	}
+	return nil
+}
+
+// idempotent reports whether the method with the given ID may be called
+// more than once with the same effect. Methods of packages which do not
+// register their methods are judged from the HTTP method of req.
+func idempotent(methodID string, req *http.Request) bool {
+	if m, ok := googleapi.LookupMethod(methodID); ok {
+		return m.Idempotent
+	}
+	method := req.Method
+	if o := req.Header.Get("X-HTTP-Method-Override"); o != "" {
+		method = o
+	}
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// sendChecked sends req once if the circuit breaker in settings allows,
+// and records the outcome and any quota information in the response.
+// If sent is non-nil, the number of body bytes sent is stored in it.
+func sendChecked(ctx context.Context, client *http.Client, req *http.Request, settings *ServiceSettings, methodID string, sent *int64) (*http.Response, error) {
+	if err := settings.Breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := sendCounted(ctx, client, req, settings, sent)
+	settings.Breaker.record(res, err)
+	if res != nil && settings.OnRateLimit != nil {
+		if rl, ok := googleapi.ParseRateLimit(res.Header); ok {
+			settings.OnRateLimit(methodID, rl)
+		}
+	}
+	if res != nil {
+		if d, ok := googleapi.ParseDeprecation(res.Header); ok {
+			if settings.OnDeprecation != nil {
+				settings.OnDeprecation(methodID, d)
+			} else {
+				logDeprecationOnce(methodID, d)
+			}
+		}
+	}
+	return res, err
+}
+
+// deprecationLogf logs deprecation announcements. It is overridden in
+// tests.
+var deprecationLogf = log.Printf
+
+var (
+	deprecationMu     sync.Mutex
+	deprecationLogged = make(map[string]bool) // by method ID
+)
+
+// logDeprecationOnce logs d, unless a deprecation has already been
+// logged for the method with the given ID.
+func logDeprecationOnce(methodID string, d *googleapi.Deprecation) {
+	deprecationMu.Lock()
+	logged := deprecationLogged[methodID]
+	deprecationLogged[methodID] = true
+	deprecationMu.Unlock()
+	if !logged {
+		deprecationLogf("googleapi: method %s is %v", methodID, d)
+	}
+}
+
+// sendCounted sends req once, storing the number of body bytes sent in
+// sent if it is non-nil.
+func sendCounted(ctx context.Context, client *http.Client, req *http.Request, settings *ServiceSettings, sent *int64) (*http.Response, error) {
+	if sent == nil || req.Body == nil {
+		return sendHedged(ctx, client, req, settings.Hedger)
+	}
+	body := &countingReader{ReadCloser: req.Body}
+	req.Body = body
+	res, err := sendHedged(ctx, client, req, settings.Hedger)
+	*sent = body.n
+	req.Body = body.ReadCloser // so that rewindBody can find its source
+	return res, err
+}
+
+func send(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	if ctx != nil {
+		return ctxhttp.Do(ctx, client, req)
+	}
+	return client.Do(req)
+}
+
+// MarshalRequest serializes req, including its body, for googleapi.Execute.
+// The body of req is consumed and closed.
+func MarshalRequest(req *http.Request) ([]byte, error) {
+	sr := googleapi.SerializedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		sr.Body = body
+	}
+	return json.Marshal(sr)
+}
+
+// DecodeResponse decodes the JSON body of res into target.
+// settings may be nil.
+//
+// Some APIs reply with 204 No Content, or an empty body, to calls which
+// declare a response. Such a response leaves target unchanged rather
+// than causing an error. A byte order mark or XSSI prefix before the
+// JSON is ignored.
+//
+// Strict decoding always uses encoding/json, since a Codec cannot be
+// asked to reject unknown fields.
+func DecodeResponse(target interface{}, res *http.Response, settings *ServiceSettings) error {
+	if res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(res.Body); err != nil {
+		return err
+	}
+	data := trimJSONPrefix(buf.Bytes())
+	if len(data) == 0 {
+		return nil
+	}
+	if settings != nil && settings.DisallowUnknownFields {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(target)
+	}
+	return settings.codec().Unmarshal(data, target)
+}
+
+var (
+	utf8BOM    = []byte("\xef\xbb\xbf")
+	xssiPrefix = []byte(")]}'")
+)
+
+// trimJSONPrefix returns data without any leading whitespace, UTF-8 byte
+// order mark or XSSI protection prefix ")]}'" (optionally followed by a
+// comma), which some proxies add to JSON responses and which would
+// otherwise fail to decode.
+func trimJSONPrefix(data []byte) []byte {
+	for {
+		trimmed := bytes.TrimLeft(data, " \t\r\n")
+		trimmed = bytes.TrimPrefix(trimmed, utf8BOM)
+		if bytes.HasPrefix(trimmed, xssiPrefix) {
+			trimmed = bytes.TrimPrefix(trimmed[len(xssiPrefix):], []byte(","))
+		}
+		if len(trimmed) == len(data) {
+			return data
+		}
+		data = trimmed
+	}
+}
+
+// CloseBody closes res.Body, if any. It first reads a few bytes, so that
+// the Transport sees the end of the body and can reuse the connection.
+func CloseBody(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+	// Two bytes for up to "\r\n" after a JSON document, and then one to
+	// see EOF if it has not been seen yet.
+	buf := make([]byte, 1)
+	for i := 0; i < 3; i++ {
+		if _, err := res.Body.Read(buf); err != nil {
+			break
+		}
+	}
+	res.Body.Close()
+}
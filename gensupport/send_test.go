@@ -0,0 +1,525 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// failTransport fails the test if any request is sent through it.
+type failTransport struct{ t *testing.T }
+
+func (f failTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Fatalf("unexpected request sent: %s %s", req.Method, req.URL)
+	return nil, nil
+}
+
+func TestSendRequestDryRun(t *testing.T) {
+	client := &http.Client{Transport: failTransport{t}}
+	req, _ := http.NewRequest("DELETE", "https://www.googleapis.com/storage/v1/b/bucket", nil)
+	res, err := SendRequest(nil, client, req, &ServiceSettings{DryRun: true}, "storage.buckets.delete")
+	if res != nil {
+		t.Errorf("got response %v, want nil", res)
+	}
+	got, ok := googleapi.IsDryRun(err)
+	if !ok {
+		t.Fatalf("got error %v, want *googleapi.DryRunError", err)
+	}
+	if got != req {
+		t.Errorf("got request %v, want %v", got, req)
+	}
+}
+
+func TestMarshalRequestExecute(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL+"/storage/v1/b/{bucket}/o?alt=json", strings.NewReader(`{"name":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	googleapi.Expand(req.URL, map[string]string{"bucket": "foo/bar"})
+	serialized, err := MarshalRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := googleapi.Execute(serialized, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got.Method != "POST" {
+		t.Errorf("method: got %q, want POST", got.Method)
+	}
+	if want := "/storage/v1/b/foo%2Fbar/o?alt=json"; got.RequestURI != want {
+		t.Errorf("request URI: got %q, want %q", got.RequestURI, want)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type: got %q, want application/json", ct)
+	}
+	if want := `{"name":"x"}`; gotBody != want {
+		t.Errorf("body: got %q, want %q", gotBody, want)
+	}
+}
+
+func TestDecodeResponseStrict(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+	}
+	body := `{"name":"a","extra":1}`
+	for _, tt := range []struct {
+		strict  bool
+		wantErr bool
+	}{
+		{false, false},
+		{true, true},
+	} {
+		res := &http.Response{Body: ioutil.NopCloser(strings.NewReader(body))}
+		var got target
+		err := DecodeResponse(&got, res, &ServiceSettings{DisallowUnknownFields: tt.strict})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("strict=%v: got error %v, want error: %v", tt.strict, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSendRequestAPIClientHeader(t *testing.T) {
+	for _, tt := range []struct {
+		omit   bool
+		header string // value set by the caller, if any
+		want   string
+	}{
+		{false, "", apiClientHeader},
+		{true, "", ""},
+		{false, "gl-go/custom", "gl-go/custom"},
+	} {
+		req, _ := http.NewRequest("GET", "https://www.googleapis.com/storage/v1/b/bucket", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Goog-Api-Client", tt.header)
+		}
+		settings := &ServiceSettings{DryRun: true, OmitAPIClientHeader: tt.omit}
+		SendRequest(nil, &http.Client{Transport: failTransport{t}}, req, settings, "storage.buckets.get")
+		if got := req.Header.Get("X-Goog-Api-Client"); got != tt.want {
+			t.Errorf("omit=%v, header=%q: got %q, want %q", tt.omit, tt.header, got, tt.want)
+		}
+	}
+	if !strings.HasPrefix(apiClientHeader, "gl-go/") || !strings.HasSuffix(apiClientHeader, " gdcl/"+googleapi.Version) {
+		t.Errorf("malformed header value %q", apiClientHeader)
+	}
+}
+
+func TestSendRequestMethodOverride(t *testing.T) {
+	for _, tt := range []struct {
+		method     string
+		override   bool
+		wantMethod string
+		wantHeader string
+	}{
+		{"PATCH", false, "PATCH", ""},
+		{"PATCH", true, "POST", "PATCH"},
+		{"DELETE", true, "POST", "DELETE"},
+		{"GET", true, "GET", ""},
+		{"POST", true, "POST", ""},
+	} {
+		req, _ := http.NewRequest(tt.method, "https://www.googleapis.com/storage/v1/b/bucket", nil)
+		settings := &ServiceSettings{DryRun: true, MethodOverride: tt.override}
+		SendRequest(nil, &http.Client{Transport: failTransport{t}}, req, settings, "storage.buckets.patch")
+		if req.Method != tt.wantMethod {
+			t.Errorf("%s, override=%v: got method %s, want %s", tt.method, tt.override, req.Method, tt.wantMethod)
+		}
+		if got := req.Header.Get("X-HTTP-Method-Override"); got != tt.wantHeader {
+			t.Errorf("%s, override=%v: got header %q, want %q", tt.method, tt.override, got, tt.wantHeader)
+		}
+	}
+}
+
+func TestDecodeResponseEmpty(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+	}
+	for _, tt := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusNoContent, ""},
+		{http.StatusOK, ""},
+		{http.StatusOK, "\n"},
+	} {
+		res := &http.Response{StatusCode: tt.status, Body: ioutil.NopCloser(strings.NewReader(tt.body))}
+		got := target{Name: "unchanged"}
+		if err := DecodeResponse(&got, res, nil); err != nil {
+			t.Errorf("status %d, body %q: got error %v, want nil", tt.status, tt.body, err)
+		}
+		if got.Name != "unchanged" {
+			t.Errorf("status %d, body %q: target modified: %+v", tt.status, tt.body, got)
+		}
+	}
+	res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"name":`))}
+	if err := DecodeResponse(&target{}, res, nil); err == nil {
+		t.Error("truncated body: got nil error, want one")
+	}
+}
+
+func TestDecodeResponsePrefix(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+	}
+	for _, body := range []string{
+		`{"name":"x"}`,
+		"\xef\xbb\xbf{\"name\":\"x\"}",
+		")]}'\n{\"name\":\"x\"}",
+		"\xef\xbb\xbf)]}'\n {\"name\":\"x\"}",
+		"\n)]}',\n{\"name\":\"x\"}",
+	} {
+		for _, strict := range []bool{false, true} {
+			res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}
+			var got target
+			if err := DecodeResponse(&got, res, &ServiceSettings{DisallowUnknownFields: strict}); err != nil {
+				t.Errorf("body %q, strict=%v: %v", body, strict, err)
+				continue
+			}
+			if got.Name != "x" {
+				t.Errorf("body %q, strict=%v: got %+v", body, strict, got)
+			}
+		}
+	}
+}
+
+func TestSendRequestRetriesIdempotentOnly(t *testing.T) {
+	defer func(f func() BackoffStrategy) { retryBackoff = f }(retryBackoff)
+	retryBackoff = func() BackoffStrategy { return &noPause{max: 2} }
+
+	googleapi.RegisterAPI(googleapi.APIInfo{
+		ID: "sendtest:v1",
+		Methods: []googleapi.MethodInfo{
+			{ID: "sendtest.things.get", HTTPMethod: "GET", Idempotent: true},
+			{ID: "sendtest.things.lookup", HTTPMethod: "POST", Idempotent: true},
+			{ID: "sendtest.things.put", HTTPMethod: "PUT"},
+		},
+	})
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		method, methodID string
+		override         bool
+		want             int
+	}{
+		{"GET", "sendtest.things.get", false, 3},
+		{"POST", "sendtest.things.lookup", false, 3},
+		{"PUT", "sendtest.things.put", false, 1},
+		// Methods of unregistered packages are judged by HTTP method.
+		{"DELETE", "unregistered.things.delete", false, 3},
+		{"DELETE", "unregistered.things.delete", true, 3},
+		{"POST", "unregistered.things.insert", false, 1},
+		{"PATCH", "unregistered.things.patch", true, 1},
+	} {
+		attempts = 0
+		req, _ := http.NewRequest(tt.method, ts.URL, nil)
+		settings := &ServiceSettings{Retry: true, MethodOverride: tt.override}
+		res, err := SendRequest(nil, http.DefaultClient, req, settings, tt.methodID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if attempts != tt.want {
+			t.Errorf("%s %s: got %d attempts, want %d", tt.method, tt.methodID, attempts, tt.want)
+		}
+	}
+}
+
+func TestSendRequestOnRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.Header().Set("X-RateLimit-Remaining", "3")
+		}
+	}))
+	defer ts.Close()
+
+	var gotIDs []string
+	var got *googleapi.RateLimit
+	settings := &ServiceSettings{OnRateLimit: func(id string, rl *googleapi.RateLimit) {
+		gotIDs = append(gotIDs, id)
+		got = rl
+	}}
+	for _, path := range []string{"/limited", "/unlimited"} {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		res, err := SendRequest(nil, http.DefaultClient, req, settings, "test"+strings.Replace(path, "/", ".", -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if len(gotIDs) != 1 || gotIDs[0] != "test.limited" {
+		t.Fatalf("callback called for %q, want only test.limited", gotIDs)
+	}
+	if got.Remaining != 3 || got.Limit != -1 {
+		t.Errorf("got %+v, want Remaining 3 and Limit -1", got)
+	}
+}
+
+// TestSendRequestConcurrent sends calls concurrently with the same
+// settings, as the calls of a generated Service are. Run it with -race.
+func TestSendRequestConcurrent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "3")
+		fmt.Fprintf(w, `{"name":%q}`, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	limited := 0
+	settings := &ServiceSettings{
+		Retry:   true,
+		Breaker: NewBreaker(&googleapi.BreakerPolicy{Failures: 5, Cooldown: time.Minute}),
+		Hedger:  NewHedger(&googleapi.HedgePolicy{Delay: time.Minute}),
+		OnRateLimit: func(string, *googleapi.RateLimit) {
+			mu.Lock()
+			limited++
+			mu.Unlock()
+		},
+	}
+	const n = 20
+	errc := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			path := fmt.Sprintf("/%d", i)
+			req, _ := http.NewRequest("GET", ts.URL+path, nil)
+			res, err := SendRequest(nil, http.DefaultClient, req, settings, "test.things.get")
+			if err != nil {
+				errc <- err
+				return
+			}
+			defer res.Body.Close()
+			var got struct{ Name string }
+			if err := DecodeResponse(&got, res, settings); err != nil {
+				errc <- err
+				return
+			}
+			if got.Name != path {
+				errc <- fmt.Errorf("got response for %s, want %s", got.Name, path)
+				return
+			}
+			errc <- nil
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	}
+	if limited != n {
+		t.Errorf("OnRateLimit called %d times, want %d", limited, n)
+	}
+}
+
+// countingCodec is a googleapi.Codec which counts its uses.
+type countingCodec struct{ marshals, unmarshals int }
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return googleapi.JSONCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return googleapi.JSONCodec.Unmarshal(data, v)
+}
+
+func TestCodec(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+	}
+	codec := &countingCodec{}
+	settings := &ServiceSettings{Codec: codec}
+
+	for _, tt := range []struct {
+		style googleapi.MarshalStyle
+		want  string
+	}{
+		{googleapi.WithoutDataWrapper, `{"name":"a"}` + "\n"},
+		{googleapi.WithDataWrapper, `{"data": {"name":"a"}` + "\n}"},
+	} {
+		body, err := JSONBody(tt.style, &target{Name: "a"}, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(body)
+		if string(b) != tt.want {
+			t.Errorf("got body %q, want %q", b, tt.want)
+		}
+	}
+	if codec.marshals != 2 {
+		t.Errorf("got %d calls to Marshal, want 2", codec.marshals)
+	}
+
+	for _, body := range []string{`{"name":"b"}`, ""} {
+		res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}
+		var got target
+		if err := DecodeResponse(&got, res, settings); err != nil {
+			t.Errorf("body %q: %v", body, err)
+		}
+	}
+	if codec.unmarshals != 1 {
+		t.Errorf("got %d calls to Unmarshal, want 1", codec.unmarshals)
+	}
+
+	// Strict decoding needs encoding/json.
+	settings.DisallowUnknownFields = true
+	res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"extra":1}`))}
+	if err := DecodeResponse(&target{}, res, settings); err == nil {
+		t.Error("strict: got nil error, want one")
+	}
+	if codec.unmarshals != 1 {
+		t.Error("strict decoding used the Codec")
+	}
+}
+
+func BenchmarkDecodeResponse(b *testing.B) {
+	type object struct {
+		Name     string            `json:"name"`
+		Size     uint64            `json:"size,string"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	type list struct {
+		Items         []*object `json:"items"`
+		NextPageToken string    `json:"nextPageToken"`
+	}
+	var l list
+	for i := 0; i < 100; i++ {
+		l.Items = append(l.Items, &object{Name: fmt.Sprintf("object-%d", i), Size: 1024, Metadata: map[string]string{"k": "v"}})
+	}
+	body, _ := json.Marshal(&l)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		res := &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(body))}
+		var got list
+		if err := DecodeResponse(&got, res, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAfterDecode(t *testing.T) {
+	var s ServiceSettings
+	s.AfterDecode("test.things.get", 1) // no hook: nothing happens
+
+	var gotID string
+	var got interface{}
+	s.OnDecode = func(id string, v interface{}) { gotID, got = id, v }
+	s.AfterDecode("test.things.get", 2)
+	if gotID != "test.things.get" || got != 2 {
+		t.Errorf("hook called with %q, %v; want test.things.get, 2", gotID, got)
+	}
+}
+
+func TestSendRequestDeprecation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			w.Header().Set("Warning", `299 - "Use v2"`)
+		}
+	}))
+	defer ts.Close()
+	send := func(settings *ServiceSettings, path string) {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		res, err := SendRequest(nil, http.DefaultClient, req, settings, "deprecationtest"+strings.Replace(path, "/", ".", -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	var gotIDs []string
+	settings := &ServiceSettings{OnDeprecation: func(id string, d *googleapi.Deprecation) {
+		if len(d.Warnings) != 1 || d.Warnings[0] != "Use v2" {
+			t.Errorf("got %+v", d)
+		}
+		gotIDs = append(gotIDs, id)
+	}}
+	for _, path := range []string{"/old", "/new", "/old"} {
+		send(settings, path)
+	}
+	if want := []string{"deprecationtest.old", "deprecationtest.old"}; !reflect.DeepEqual(gotIDs, want) {
+		t.Errorf("callback called for %q, want %q", gotIDs, want)
+	}
+
+	defer func(f func(string, ...interface{})) { deprecationLogf = f }(deprecationLogf)
+	// Start from no logged deprecations, so that the test may be repeated.
+	defer func(m map[string]bool) { deprecationLogged = m }(deprecationLogged)
+	deprecationLogged = make(map[string]bool)
+	var logged []string
+	deprecationLogf = func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	for _, path := range []string{"/old", "/new", "/old"} {
+		send(nil, path)
+	}
+	if want := []string{`googleapi: method deprecationtest.old is deprecated (warning "Use v2")`}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("logged %q, want %q", logged, want)
+	}
+}
+
+func TestSendRequestSLOViolation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer ts.Close()
+	budget := googleapi.LatencyClass{Name: "test", Budget: 50 * time.Millisecond}
+	for _, id := range []string{"slo.slow", "slo.fast"} {
+		googleapi.SetLatencyClass(id, budget)
+		defer googleapi.SetLatencyClass(id, googleapi.LatencyClass{})
+	}
+
+	var local, central []*googleapi.SLOViolation
+	googleapi.HandleSLOViolations(func(v *googleapi.SLOViolation) { central = append(central, v) })
+	defer googleapi.HandleSLOViolations(nil)
+	for _, settings := range []*ServiceSettings{
+		{OnSLOViolation: func(v *googleapi.SLOViolation) { local = append(local, v) }},
+		nil,
+	} {
+		for _, path := range []string{"/slow", "/fast", "/undeclared"} {
+			req, _ := http.NewRequest("GET", ts.URL+path, nil)
+			res, err := SendRequest(nil, http.DefaultClient, req, settings, "slo"+strings.Replace(path, "/", ".", -1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+		}
+	}
+	for _, got := range [][]*googleapi.SLOViolation{local, central} {
+		if len(got) != 1 {
+			t.Fatalf("got %d violations, want 1", len(got))
+		}
+		if v := got[0]; v.MethodID != "slo.slow" || v.Class != budget || v.Latency < budget.Budget || v.StatusCode != http.StatusAccepted {
+			t.Errorf("got violation %+v", v)
+		}
+	}
+}
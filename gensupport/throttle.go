@@ -0,0 +1,97 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Throttle limits the rate at which data is read through the readers it
+// wraps. All readers wrapped by a Throttle share its limit, so that the
+// successive chunks of an upload are together held to the limit.
+// A nil *Throttle imposes no limit.
+type Throttle struct {
+	rate int64 // bytes per second
+
+	mu  sync.Mutex
+	due time.Time // when the bytes read so far are within the limit
+}
+
+// now and sleep are overridden in tests.
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// NewThrottle returns a Throttle which limits reads to bytesPerSec bytes
+// per second on average, or nil if bytesPerSec is not positive.
+func NewThrottle(bytesPerSec int64) *Throttle {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &Throttle{rate: bytesPerSec}
+}
+
+// Reader returns a reader which reads from r no faster than t allows.
+func (t *Throttle) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &throttledReader{r: r, t: t}
+}
+
+// ReadCloser is like Reader, but for an io.ReadCloser such as a response body.
+func (t *Throttle) ReadCloser(rc io.ReadCloser) io.ReadCloser {
+	if t == nil {
+		return rc
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{t.Reader(rc), rc}
+}
+
+// wait records that n more bytes have been read, and pauses until doing
+// so is within the limit. Time spent idle is not saved up, so that a
+// reader which resumes after a pause does not get a burst.
+func (t *Throttle) wait(n int) {
+	t.mu.Lock()
+	tnow := now()
+	if t.due.Before(tnow) {
+		t.due = tnow
+	}
+	t.due = t.due.Add(time.Duration(float64(n) / float64(t.rate) * float64(time.Second)))
+	d := t.due.Sub(tnow)
+	t.mu.Unlock()
+	if d > 0 {
+		sleep(d)
+	}
+}
+
+// maxThrottledRead bounds the size of each read, so that a large buffer
+// does not cause a burst of data followed by a long pause.
+const maxThrottledRead = 32 << 10
+
+type throttledReader struct {
+	r io.Reader
+	t *Throttle
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	max := int64(maxThrottledRead)
+	if tr.t.rate < max {
+		max = tr.t.rate
+	}
+	if int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		tr.t.wait(n)
+	}
+	return n, err
+}
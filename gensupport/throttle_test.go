@@ -0,0 +1,108 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	// Simulate the passing of time.
+	clock := time.Unix(0, 0)
+	defer func(oldNow func() time.Time, oldSleep func(time.Duration)) {
+		now, sleep = oldNow, oldSleep
+	}(now, sleep)
+	now = func() time.Time { return clock }
+	sleep = func(d time.Duration) { clock = clock.Add(d) }
+
+	th := NewThrottle(100)
+	// Two readers share the limit.
+	for _, s := range []string{strings.Repeat("a", 150), strings.Repeat("b", 150)} {
+		got, err := ioutil.ReadAll(th.Reader(strings.NewReader(s)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != s {
+			t.Errorf("got %q, want %q", got, s)
+		}
+	}
+	// 300 bytes at 100 bytes per second take 3 seconds.
+	if got, want := clock.Sub(time.Unix(0, 0)), 3*time.Second; got != want {
+		t.Errorf("took %v, want %v", got, want)
+	}
+}
+
+func TestThrottleIdle(t *testing.T) {
+	clock := time.Unix(0, 0)
+	defer func(oldNow func() time.Time, oldSleep func(time.Duration)) {
+		now, sleep = oldNow, oldSleep
+	}(now, sleep)
+	now = func() time.Time { return clock }
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d; clock = clock.Add(d) }
+
+	th := NewThrottle(100)
+	th.wait(100)
+	// An idle minute does not allow a burst afterwards.
+	clock = clock.Add(time.Minute)
+	slept = 0
+	th.wait(200)
+	if want := 2 * time.Second; slept != want {
+		t.Errorf("after idling, slept %v, want %v", slept, want)
+	}
+}
+
+func TestThrottleLargeCount(t *testing.T) {
+	clock := time.Unix(0, 0)
+	defer func(oldNow func() time.Time, oldSleep func(time.Duration)) {
+		now, sleep = oldNow, oldSleep
+	}(now, sleep)
+	now = func() time.Time { return clock }
+	sleep = func(d time.Duration) { clock = clock.Add(d) }
+
+	// Well past the 9.2 GB at which counting nanoseconds in an int64
+	// overflows.
+	th := NewThrottle(1 << 30)
+	for i := 0; i < 20; i++ {
+		th.wait(1 << 30)
+	}
+	if got, want := clock.Sub(time.Unix(0, 0)), 20*time.Second; got != want {
+		t.Errorf("took %v, want %v", got, want)
+	}
+}
+
+func TestNilThrottle(t *testing.T) {
+	var th *Throttle = NewThrottle(0)
+	if th != nil {
+		t.Fatalf("NewThrottle(0) = %v, want nil", th)
+	}
+	r := bytes.NewReader(nil)
+	if got := th.Reader(r); got != r {
+		t.Errorf("nil Throttle wrapped its reader")
+	}
+}
+
+func TestThrottleReadCloserClosesPipe(t *testing.T) {
+	// A throttled multipart upload body must still close its pipe when
+	// the transport closes the request body, or the goroutine writing the
+	// parts leaks.
+	combined, _ := CombineBodyMedia(strings.NewReader("{}"), "application/json", strings.NewReader(strings.Repeat("m", 1<<20)), "text/plain")
+	req, err := http.NewRequest("POST", "http://example.com/", NewThrottle(1<<30).ReadCloser(combined))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Body.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := combined.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Errorf("reading the combined body after Close: got %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+// startSpan starts a span for the call named name, or returns nil if
+// tracer is nil.
+func startSpan(ctx context.Context, tracer googleapi.Tracer, name string) googleapi.Span {
+	if tracer == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.StartSpan(ctx, name)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// spanBody is a response body which finishes its span when closed.
+type spanBody struct {
+	countingReader
+	span googleapi.Span
+	info googleapi.SpanInfo
+	once sync.Once
+}
+
+func (b *spanBody) Close() error {
+	err := b.countingReader.Close()
+	b.once.Do(func() {
+		b.info.BytesReceived = b.n
+		b.span.Finish(b.info)
+	})
+	return err
+}
+
+// finishSpan arranges for span to be finished with info once the caller
+// is done with res. If res is nil, span is finished immediately.
+func finishSpan(span googleapi.Span, res *http.Response, info googleapi.SpanInfo) {
+	if res == nil || res.Body == nil {
+		span.Finish(info)
+		return
+	}
+	info.StatusCode = res.StatusCode
+	res.Body = &spanBody{countingReader: countingReader{ReadCloser: res.Body}, span: span, info: info}
+}
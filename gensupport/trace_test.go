@@ -0,0 +1,131 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+type recordingTracer struct {
+	names []string
+	infos []googleapi.SpanInfo
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) googleapi.Span {
+	t.names = append(t.names, name)
+	return recordingSpan{t}
+}
+
+type recordingSpan struct{ t *recordingTracer }
+
+func (s recordingSpan) Finish(info googleapi.SpanInfo) {
+	s.t.infos = append(s.t.infos, info)
+}
+
+func TestSendRequestTrace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name":"obj"}`))
+	}))
+	defer ts.Close()
+
+	tracer := &recordingTracer{}
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(`{"name":"x"}`))
+	res, err := SendRequest(nil, http.DefaultClient, req, &ServiceSettings{Tracer: tracer}, "storage.objects.insert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracer.infos) != 0 {
+		t.Fatal("span finished before the response body was closed")
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body.Close()
+
+	if want := []string{"storage.objects.insert"}; len(tracer.names) != 1 || tracer.names[0] != want[0] {
+		t.Errorf("span names: got %q, want %q", tracer.names, want)
+	}
+	if len(tracer.infos) != 1 {
+		t.Fatalf("got %d finished spans, want 1", len(tracer.infos))
+	}
+	want := googleapi.SpanInfo{StatusCode: http.StatusCreated, BytesSent: 12, BytesReceived: 14}
+	if got := tracer.infos[0]; got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSendRequestTraceRetries(t *testing.T) {
+	defer func(f func() BackoffStrategy) { retryBackoff = f }(retryBackoff)
+	retryBackoff = func() BackoffStrategy { return NoPauseStrategy }
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if calls++; calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	tracer := &recordingTracer{}
+	req, _ := http.NewRequest("PUT", ts.URL, strings.NewReader(`{"name":"x"}`))
+	res, err := SendRequest(nil, http.DefaultClient, req, &ServiceSettings{Tracer: tracer, Retry: true}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if len(tracer.names) != 1 || len(tracer.infos) != 1 {
+		t.Fatalf("started %d spans and finished %d, want 1 of each", len(tracer.names), len(tracer.infos))
+	}
+	want := googleapi.SpanInfo{StatusCode: http.StatusOK, Retries: 2, BytesSent: 12, BytesReceived: 2}
+	if got := tracer.infos[0]; got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestResumableUploadTrace(t *testing.T) {
+	tr := &interruptibleTransport{
+		events: []event{
+			{"bytes 0-9/*", http.StatusServiceUnavailable},
+			{"bytes 0-9/*", 308},
+			{"bytes 10-19/*", http.StatusServiceUnavailable},
+			{"bytes 10-19/*", 308},
+			{"bytes */20", 200},
+		},
+		bodies: bodyTracker{},
+	}
+	tracer := &recordingTracer{}
+	rx := &ResumableUpload{
+		Client:    &http.Client{Transport: tr},
+		Media:     NewMediaBuffer(strings.NewReader(strings.Repeat("a", 20)), 10),
+		MediaType: "text/plain",
+		Backoff:   NoPauseStrategy,
+		Tracer:    tracer,
+		MethodID:  "storage.objects.insert",
+	}
+	res, err := rx.Upload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if len(tracer.infos) != 1 {
+		t.Fatalf("got %d finished spans, want 1", len(tracer.infos))
+	}
+	want := googleapi.SpanInfo{StatusCode: http.StatusOK, Retries: 2, BytesSent: 20}
+	if got := tracer.infos[0]; got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
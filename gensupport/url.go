@@ -0,0 +1,103 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/internal/uritemplates"
+)
+
+// ResolveRelative resolves relstr, the path template of a method, against
+// basestr, the base path of its Service, leaving the braces of any
+// template variables unescaped. If either fails to parse, they are
+// joined as they are, and the request built with the result fails.
+func ResolveRelative(basestr, relstr string) string {
+	u, err := url.Parse(basestr)
+	if err != nil {
+		return basestr + relstr
+	}
+	rel, err := url.Parse(relstr)
+	if err != nil {
+		return basestr + relstr
+	}
+	u = u.ResolveReference(rel)
+	us := u.String()
+	us = strings.Replace(us, "%7B", "{", -1)
+	us = strings.Replace(us, "%7D", "}", -1)
+	return us
+}
+
+// BasePath returns the base URL to which a call is sent: callPath if it
+// was set with the call's BaseURL method, or else the URL set in ctx by
+// googleapi.WithBaseURL, or else servicePath, the BasePath of its
+// Service. ctx may be nil. An overriding URL which is not an absolute
+// URL is an error.
+func BasePath(ctx context.Context, servicePath, callPath string) (string, error) {
+	if callPath != "" {
+		return callPath, checkBaseURL(callPath)
+	}
+	if ctx != nil {
+		if u, ok := googleapi.BaseURLFromContext(ctx); ok {
+			return u, checkBaseURL(u)
+		}
+	}
+	return servicePath, nil
+}
+
+func checkBaseURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("gensupport: invalid base URL: %v", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("gensupport: base URL %q is not absolute", s)
+	}
+	return nil
+}
+
+// has4860Fix is whether this Go environment contains the fix for
+// http://golang.org/issue/4860
+var has4860Fix bool
+
+// init initializes has4860Fix by checking the behavior of the net/http package.
+func init() {
+	r := http.Request{
+		URL: &url.URL{
+			Scheme: "http",
+			Opaque: "//opaque",
+		},
+	}
+	b := &bytes.Buffer{}
+	r.Write(b)
+	has4860Fix = bytes.HasPrefix(b.Bytes(), []byte("GET http"))
+}
+
+// SetOpaque sets u.Opaque from u.Path such that HTTP requests to it
+// don't alter any hex-escaped characters in u.Path.
+func SetOpaque(u *url.URL) {
+	u.Opaque = "//" + u.Host + u.Path
+	if !has4860Fix {
+		u.Opaque = u.Scheme + ":" + u.Opaque
+	}
+}
+
+// Expand substitutes any {encoded} strings in the URL passed in using
+// the map supplied.
+//
+// This calls SetOpaque to avoid encoding of the parameters in the URL path.
+func Expand(u *url.URL, expansions map[string]string) {
+	expanded, err := uritemplates.Expand(u.Path, expansions)
+	if err == nil {
+		u.Path = expanded
+		SetOpaque(u)
+	}
+}
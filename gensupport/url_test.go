@@ -0,0 +1,98 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import (
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+func TestResolveRelative(t *testing.T) {
+	for _, tt := range []struct {
+		base, rel, want string
+	}{
+		{"https://www.googleapis.com/storage/v1/", "b/{bucket}/o", "https://www.googleapis.com/storage/v1/b/{bucket}/o"},
+		{"https://www.googleapis.com/storage/v1/", "/upload/storage/v1/b/{bucket}/o", "https://www.googleapis.com/upload/storage/v1/b/{bucket}/o"},
+		{"https://www.googleapis.com/discovery/v1/", "apis?preferred=true", "https://www.googleapis.com/discovery/v1/apis?preferred=true"},
+	} {
+		if got := ResolveRelative(tt.base, tt.rel); got != tt.want {
+			t.Errorf("ResolveRelative(%q, %q) = %q, want %q", tt.base, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	const (
+		service = "https://www.googleapis.com/storage/v1/"
+		call    = "https://eu.example.com/storage/v1/"
+		inCtx   = "https://us.example.com/storage/v1/"
+	)
+	ctx := googleapi.WithBaseURL(context.Background(), inCtx)
+	for _, tt := range []struct {
+		ctx            context.Context
+		callPath, want string
+	}{
+		{nil, "", service},
+		{context.Background(), "", service},
+		{ctx, "", inCtx},
+		{ctx, call, call},
+		{nil, call, call},
+	} {
+		if got, err := BasePath(tt.ctx, service, tt.callPath); got != tt.want || err != nil {
+			t.Errorf("BasePath(%v, %q, %q) = %q, %v, want %q", tt.ctx, service, tt.callPath, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"://bad", "eu.example.com/storage/v1/", "%zz"} {
+		if _, err := BasePath(nil, service, bad); err == nil {
+			t.Errorf("BasePath with call path %q succeeded", bad)
+		}
+		if _, err := BasePath(googleapi.WithBaseURL(context.Background(), bad), service, ""); err == nil {
+			t.Errorf("BasePath with context URL %q succeeded", bad)
+		}
+		// Resolving must not panic either.
+		ResolveRelative(bad, "b/{bucket}")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		path       string
+		expansions map[string]string
+		want       string
+	}{
+		{"/b/{bucket}/o/{object}", map[string]string{"bucket": "b", "object": "a/b c"}, "/b/b/o/a%2Fb%20c"},
+		{"/v1/{+name}", map[string]string{"name": "projects/p/topics/t"}, "/v1/projects/p/topics/t"},
+		{"/b/{bucket}", map[string]string{}, "/b/"},
+	} {
+		u, _ := url.Parse("https://www.googleapis.com" + tt.path)
+		Expand(u, tt.expansions)
+		if u.Path != tt.want {
+			t.Errorf("%s: got path %q, want %q", tt.path, u.Path, tt.want)
+		}
+		if want := "//www.googleapis.com" + tt.want; u.Opaque != want {
+			t.Errorf("%s: got opaque %q, want %q", tt.path, u.Opaque, want)
+		}
+	}
+}
+
+func BenchmarkResolveRelative(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ResolveRelative("https://www.googleapis.com/storage/v1/", "b/{bucket}/o/{object}")
+	}
+}
+
+func BenchmarkExpand(b *testing.B) {
+	expansions := map[string]string{"bucket": "my-bucket", "object": "path/to/object.txt"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u, _ := url.Parse("https://www.googleapis.com/storage/v1/b/{bucket}/o/{object}?alt=json")
+		Expand(u, expansions)
+	}
+}
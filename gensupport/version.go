@@ -0,0 +1,23 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import "fmt"
+
+// Version is the version of the interface this package offers to
+// generated code. It is incremented whenever the generator starts to
+// emit code which needs something new from this package.
+const Version = 1
+
+// CheckVersion panics unless this package is at least version v, the
+// Version the package for API api was generated against. Generated
+// packages call it from an init function, so that a binary combining one
+// with an older copy of this package fails at start-up with a message
+// saying what to update, rather than misbehaving later.
+func CheckVersion(api string, v int) {
+	if v > Version {
+		panic(fmt.Sprintf("gensupport: the package for %s was generated for gensupport version %d, but this is version %d; update google.golang.org/api/gensupport", api, v, Version))
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gensupport
+
+import "testing"
+
+func TestCheckVersion(t *testing.T) {
+	for _, tt := range []struct {
+		v         int
+		wantPanic bool
+	}{
+		{Version - 1, false},
+		{Version, false},
+		{Version + 1, true},
+	} {
+		func() {
+			defer func() {
+				if got := recover() != nil; got != tt.wantPanic {
+					t.Errorf("CheckVersion(%d): got panic %v, want %v", tt.v, got, tt.wantPanic)
+				}
+			}()
+			CheckVersion("storage:v1", tt.v)
+		}()
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// apis holds the contents of the file named by -apilist, which lets a
+// fork pin the APIs it ships independently of the live directory. The
+// file names one API ID per line; blank lines and lines beginning with
+// "#" are ignored. For example:
+//
+//   # Shipped by this fork.
+//   storage:v1
+//   compute:beta
+//   !plus:v1
+//
+// If any APIs are listed, only they are generated, whether or not the
+// directory marks them as preferred, or lists them at all. An ID prefixed
+// with "!" is never generated; a file containing only such lines
+// generates every API in the directory except those.
+var apis *apiList
+
+type apiList struct {
+	allow []string // API IDs, in file order
+	deny  map[string]bool
+}
+
+func loadAPIList(file string) (*apiList, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	l := &apiList{deny: make(map[string]bool)}
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		deny := strings.HasPrefix(line, "!")
+		id := strings.TrimSpace(strings.TrimPrefix(line, "!"))
+		if parts := strings.Split(id, ":"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%s:%d: malformed API ID %q", file, n, id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%s:%d: API %s listed twice", file, n, id)
+		}
+		seen[id] = true
+		if deny {
+			l.deny[id] = true
+		} else {
+			l.allow = append(l.allow, id)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// allows reports whether l explicitly lists the API with the given ID.
+func (l *apiList) allows(id string) bool {
+	for _, a := range l.allow {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the APIs of the directory all which l permits, followed
+// by those which l lists but the directory does not.
+func (l *apiList) filter(all *AllAPIs) []*API {
+	allowed := make(map[string]bool)
+	for _, id := range l.allow {
+		allowed[id] = true
+	}
+	var out AllAPIs
+	found := make(map[string]bool)
+	for _, a := range all.Items {
+		if l.deny[a.ID] || len(allowed) > 0 && !allowed[a.ID] {
+			continue
+		}
+		found[a.ID] = true
+		out.Items = append(out.Items, a)
+	}
+	for _, id := range l.allow {
+		if !found[id] {
+			out.addAPI(id)
+		}
+	}
+	return out.Items
+}
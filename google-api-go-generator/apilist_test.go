@@ -0,0 +1,95 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func writeTempAPIList(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "apilist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(contents)
+	f.Close()
+	return f.Name()
+}
+
+func TestAPIListFilter(t *testing.T) {
+	dir := &AllAPIs{}
+	for _, id := range []string{"plus:v1", "storage:v1", "tasks:v1"} {
+		dir.addAPI(id)
+	}
+	for _, tt := range []struct {
+		list string
+		want []string
+	}{
+		{"# comment\n\nstorage:v1\ncompute:beta\n", []string{"storage:v1", "compute:beta"}},
+		{"!plus:v1\n", []string{"storage:v1", "tasks:v1"}},
+		{"tasks:v1\n!plus:v1\n", []string{"tasks:v1"}},
+	} {
+		file := writeTempAPIList(t, tt.list)
+		l, err := loadAPIList(file)
+		os.Remove(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, a := range l.filter(dir) {
+			got = append(got, a.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestLoadAPIListInvalid(t *testing.T) {
+	for _, list := range []string{
+		"storage\n",
+		"storage:\n",
+		"storage:v1\n!storage:v1\n",
+	} {
+		file := writeTempAPIList(t, list)
+		if _, err := loadAPIList(file); err == nil {
+			t.Errorf("%q: got nil error, want one", list)
+		}
+		os.Remove(file)
+	}
+}
+
+func TestFilterVersions(t *testing.T) {
+	items := []*API{
+		{ID: "compute:v1", Preferred: true},
+		{ID: "compute:beta"},
+		{ID: "storage:v1beta2"},
+	}
+	defer func(v, api string) {
+		*versions, *apiToGenerate, apis = v, api, nil
+	}(*versions, *apiToGenerate)
+	*apiToGenerate = "*"
+	apis = &apiList{allow: []string{"compute:beta"}}
+	for _, tt := range []struct {
+		versions string
+		want     []string
+	}{
+		{"all", []string{"compute:v1", "compute:beta", "storage:v1beta2"}},
+		{"preferred", []string{"compute:v1", "compute:beta"}},
+		{"explicit", []string{"compute:beta"}},
+	} {
+		*versions = tt.versions
+		var got []string
+		for _, a := range filterVersions(items) {
+			got = append(got, a.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("-versions=%s: got %v, want %v", tt.versions, got, tt.want)
+		}
+	}
+}
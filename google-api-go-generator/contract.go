@@ -0,0 +1,153 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+)
+
+// contractString is the value passed for string path parameters by the
+// tests written by -contract_tests. It holds characters which must be
+// escaped in a path segment, and one which must be escaped only there.
+const contractString = "a b/c"
+
+// contractValues maps the Go types of parameters to the Go expressions
+// passed for them by the tests written by -contract_tests, and the
+// values of those expressions in a URL, before escaping.
+var contractValues = map[string][2]string{
+	"string":  {fmt.Sprintf("%q", contractString), contractString},
+	"int64":   {"42", "42"},
+	"uint64":  {"42", "42"},
+	"int32":   {"42", "42"},
+	"uint32":  {"42", "42"},
+	"float64": {"1.5", "1.5"},
+	"bool":    {"true", "true"},
+}
+
+// templateVarRE matches a variable of a discovery path template, with
+// an optional "+" for reserved expansion.
+var templateVarRE = regexp.MustCompile(`\{(\+?)([^}]*)\}`)
+
+// generateContractTests returns the source code of a test for a's
+// package, checking that the URL built by each call is its method's path
+// template expanded with representative values, as computed here rather
+// than by the packages the generated code uses. Methods with parameters
+// of other types are skipped. If no method is left, the code is nil. It
+// must be called after GenerateCode.
+func (a *API) generateContractTests() ([]byte, error) {
+	// Arguments are worked out again, which would record their losses
+	// twice.
+	defer func(losses []loss) { a.losses = losses }(a.losses)
+
+	base := a.apiBaseURL()
+	var cases bytes.Buffer
+	for _, meth := range a.allMethods(a.Resources(a.m, "")) {
+		call, want, ok := meth.contractCase(base)
+		if !ok {
+			vlogf("%s: method id=%s has no URL contract test", a.ID, meth.Id())
+			continue
+		}
+		fmt.Fprintf(&cases, "{%q, %s, %q},\n", meth.Id(), call, want)
+	}
+	if cases.Len() == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format, args...)
+	}
+	p("%s", licenseHeader)
+	p("// Code generated by google-api-go-generator. DO NOT EDIT.\n\n")
+	p("package %s\n\n", a.PackageName())
+	p("import (\n%q\n%q\n\n%q\n)\n\n", "net/http", "testing", *googleapiPkg)
+	p("// TestURLContract checks that the URL built by each call is the path\n")
+	p("// template of its method in the discovery document, expanded with the\n")
+	p("// arguments of the call.\n")
+	p("func TestURLContract(t *testing.T) {\n")
+	p("s, err := New(http.DefaultClient)\n")
+	p("if err != nil { t.Fatal(err) }\n")
+	p("for _, tt := range []struct {\n")
+	p("id string\n")
+	p("call interface { HTTPRequest(...googleapi.CallOption) (*http.Request, error) }\n")
+	p("want string\n")
+	p("}{\n%s} {\n", cases.Bytes())
+	p("req, err := tt.call.HTTPRequest()\n")
+	p("if err != nil {\n")
+	p(`t.Errorf("%%s: %%v", tt.id, err)`)
+	p("\ncontinue\n}\n")
+	p("req.URL.RawQuery = \"\"\n")
+	p("if got := req.URL.String(); got != tt.want {\n")
+	p(`t.Errorf("%%s: got URL %%s, want %%s", tt.id, got, tt.want)`)
+	p("\n}\n}\n}\n")
+	return format.Source(buf.Bytes())
+}
+
+// contractCase returns the Go expression creating a call of meth with
+// representative arguments, and the URL the call should build, without
+// its query, below base. ok is false if meth has arguments of types
+// without representative values, or its path template cannot be
+// expanded here.
+func (meth *Method) contractCase(base string) (call, want string, ok bool) {
+	var exprs []string
+	values := make(map[string]string)
+	for _, arg := range meth.NewArguments().l {
+		if arg.location == "body" {
+			exprs = append(exprs, "new("+strings.TrimPrefix(arg.gotype, "*")+")")
+			continue
+		}
+		if v, ok := contractValues[arg.gotype]; ok {
+			exprs = append(exprs, v[0])
+			values[arg.apiname] = v[1]
+			continue
+		}
+		elem := strings.TrimPrefix(arg.gotype, "[]")
+		v, ok := contractValues[elem]
+		if !ok || arg.location == "path" || elem == arg.gotype {
+			return "", "", false
+		}
+		exprs = append(exprs, fmt.Sprintf("[]%s{%s}", elem, v[0]))
+	}
+
+	want = strings.Replace(resolveRelative(base, jstr(meth.m, "path")), "%7B", "{", -1)
+	want = strings.Replace(want, "%7D", "}", -1)
+	ok = true
+	want = templateVarRE.ReplaceAllStringFunc(want, func(v string) string {
+		m := templateVarRE.FindStringSubmatch(v)
+		value, found := values[m[2]]
+		if !found {
+			ok = false
+			return v
+		}
+		return escapeTemplateValue(value, m[1] == "+")
+	})
+	if !ok {
+		return "", "", false
+	}
+
+	return meth.serviceCall("s", strings.Join(exprs, ", ")), want, true
+}
+
+// escapeTemplateValue escapes v as RFC 6570 does when expanding a
+// variable, leaving reserved characters such as "/" unescaped if
+// reserved is set.
+func escapeTemplateValue(v string, reserved bool) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(v) {
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', strings.IndexByte("-._~", b) >= 0:
+			buf.WriteByte(b)
+		case reserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", b) >= 0:
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeTemplateValue(t *testing.T) {
+	for _, tt := range []struct {
+		v        string
+		reserved bool
+		want     string
+	}{
+		{"a-b_c.d~e", false, "a-b_c.d~e"},
+		{"a b/c", false, "a%20b%2Fc"},
+		{"a b/c", true, "a%20b/c"},
+		{"é", false, "%C3%A9"},
+	} {
+		if got := escapeTemplateValue(tt.v, tt.reserved); got != tt.want {
+			t.Errorf("escapeTemplateValue(%q, %v) = %q, want %q", tt.v, tt.reserved, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateContractTests(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want []string // lines of the test, or none if there is none
+	}{
+		{"blogger-3", []string{
+			`{"blogger.posts.get", s.Posts.Get("a b/c", "a b/c"), "https://www.googleapis.com/blogger/v3/blogs/a%20b%2Fc/posts/a%20b%2Fc"},`,
+			`{"blogger.posts.insert", s.Posts.Insert("a b/c", new(Post)), "https://www.googleapis.com/blogger/v3/blogs/a%20b%2Fc/posts"},`,
+		}},
+		{"noresources", nil},
+	} {
+		api, err := apiFromFile(filepath.Join("testdata", tt.name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.GenerateCode(); err != nil {
+			t.Fatal(err)
+		}
+		code, err := api.generateContractTests()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == nil && code != nil {
+			t.Errorf("%s: got a test, want none", tt.name)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(code), want) {
+				t.Errorf("%s: test does not contain %q", tt.name, want)
+			}
+		}
+	}
+}
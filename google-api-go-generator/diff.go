@@ -0,0 +1,206 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// diffOut receives the diffs printed in -dryrun mode.
+var diffOut io.Writer = os.Stdout
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// printDiff writes to diffOut a unified diff turning the existing contents
+// of file into contents, as writeFile would. A nil contents means the
+// file would be removed.
+func printDiff(file string, existing, contents []byte) {
+	oldName, newName := file, file
+	if existing == nil {
+		oldName = "/dev/null"
+	}
+	if contents == nil {
+		newName = "/dev/null"
+	}
+	d := unifiedDiff(oldName, newName, existing, contents)
+	diffOut.Write(d)
+}
+
+// An edit is one line of a diff: a line common to both inputs (' '),
+// deleted from the first ('-'), or inserted from the second ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff returns a unified diff turning a into b, or nil if they
+// are equal.
+func unifiedDiff(aName, bName string, a, b []byte) []byte {
+	edits := diffLines(splitLines(a), splitLines(b))
+	var buf bytes.Buffer
+	// ai and bi are the 1-based numbers of the next lines of a and b.
+	ai, bi := 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			ai, bi, i = ai+1, bi+1, i+1
+			continue
+		}
+		// A hunk starts diffContext lines before this change, and ends
+		// once a run of more than 2*diffContext common lines is reached.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-end > 2*diffContext {
+				end += min(run-end, diffContext)
+				break
+			}
+			end = run
+		}
+		hunkA, hunkB := ai-(i-start), bi-(i-start)
+		var na, nb int
+		var body bytes.Buffer
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				na++
+			}
+			if e.op != '-' {
+				nb++
+			}
+			body.WriteByte(e.op)
+			body.WriteString(e.line)
+			body.WriteByte('\n')
+		}
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(hunkA, na), hunkRange(hunkB, nb))
+		buf.Write(body.Bytes())
+		for _, e := range edits[i:end] {
+			if e.op != '+' {
+				ai++
+			}
+			if e.op != '-' {
+				bi++
+			}
+		}
+		i = end
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// range starts at the line before it.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start--
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning a into b, using
+// Myers's O(ND) algorithm.
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	// v[max+k] is the furthest x reached on diagonal k = x-y.
+	v := make([]int, 2*max+2)
+	// trace[d] holds v[max-d : max+d+1] as it was before step d.
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[max-d:max+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1] // down: insert b[y-1]
+			} else {
+				x = v[max+k-1] + 1 // right: delete a[x-1]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil // not reached
+}
+
+// backtrack recovers the edit script from the trace kept by diffLines.
+func backtrack(trace [][]int, a, b []string) []edit {
+	var rev []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d] // v[d+k] is the furthest x on diagonal k before step d
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = v[d+prevK]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, edit{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			rev = append(rev, edit{'+', b[y-1]})
+		} else {
+			rev = append(rev, edit{'-', a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	edits := make([]edit, len(rev))
+	for i, e := range rev {
+		edits[len(rev)-1-i] = e
+	}
+	return edits
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(n int) []string {
+		var l []string
+		for i := 1; i <= n; i++ {
+			l = append(l, string(rune('a'+i-1)))
+		}
+		return l
+	}
+	join := func(l []string) []byte {
+		if len(l) == 0 {
+			return nil
+		}
+		return []byte(strings.Join(l, "\n") + "\n")
+	}
+	old := lines(20)
+	changed := append([]string(nil), old...)
+	changed[1] = "B"                                // close to the start
+	changed = append(changed[:17], changed[18:]...) // r deleted
+	changed = append(changed[:10], append([]string{"new"}, changed[10:]...)...)
+
+	for _, tt := range []struct {
+		desc string
+		a, b []byte
+		want string
+	}{
+		{"equal", join(old), join(old), ""},
+		{"new file", nil, join(lines(2)), "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"removed file", join(lines(1)), nil, "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n"},
+		{
+			"merged hunk",
+			join(lines(12)), []byte("a\nB\nc\nd\ne\nf\ng\nH\ni\nj\nk\nl\n"),
+			"--- a\n+++ b\n@@ -1,11 +1,11 @@\n a\n-b\n+B\n c\n d\n e\n f\n g\n-h\n+H\n i\n j\n k\n",
+		},
+		{
+			// As printed by diff -u.
+			"separate hunks",
+			join(old), join(changed),
+			"--- a\n+++ b\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -8,6 +8,7 @@\n h\n i\n j\n+new\n k\n l\n m\n" +
+				"@@ -15,6 +16,5 @@\n o\n p\n q\n-r\n s\n t\n",
+		},
+	} {
+		got := string(unifiedDiff("a", "b", tt.a, tt.b))
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	defer func(d bool, o string) { *dryRun, *output = d, o }(*dryRun, *output)
+	defer func() { diffOut = os.Stdout }()
+
+	dir, err := ioutil.TempDir("", "dryrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	*output = filepath.Join(dir, "noresources-gen.go")
+	const stale = "package noresources\n"
+	if err := ioutil.WriteFile(*output, []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := apiFromFile(filepath.Join("testdata", "noresources.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diffs bytes.Buffer
+	diffOut = &diffs
+	*dryRun = true
+	if err := api.WriteGeneratedCode(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(*output); string(got) != stale {
+		t.Errorf("dry run modified %s", *output)
+	}
+	if !strings.HasPrefix(diffs.String(), "--- "+*output+"\n+++ "+*output+"\n@@ -1 +1,") ||
+		!strings.Contains(diffs.String(), "\n-package noresources\n") {
+		t.Errorf("unexpected diff:\n%s", diffs.String())
+	}
+}
+
+func TestDryRunNewAPI(t *testing.T) {
+	defer func(d bool, g string) { *dryRun, *genDir = d, g }(*dryRun, *genDir)
+	defer func() { diffOut = os.Stdout }()
+
+	dir, err := ioutil.TempDir("", "dryrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	*genDir = dir
+
+	api, err := apiFromFile(filepath.Join("testdata", "noresources.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffOut = ioutil.Discard
+	*dryRun = true
+	if err := api.WriteGeneratedCode(); err != nil {
+		t.Fatal(err)
+	}
+	if fis, err := ioutil.ReadDir(dir); err != nil || len(fis) != 0 {
+		t.Errorf("dry run of a new API left %d entries in -gendir (err %v), want none", len(fis), err)
+	}
+}
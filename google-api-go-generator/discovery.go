@@ -0,0 +1,71 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	discovery "google.golang.org/api/discovery/v1"
+)
+
+// discoveryClient returns the HTTP client used to talk to the Discovery API.
+func discoveryClient() *http.Client {
+	if *publicOnly {
+		return &http.Client{Transport: userIPTransport{}}
+	}
+	return http.DefaultClient
+}
+
+// userIPTransport marks each request as coming from an anonymous user,
+// so that only public APIs are listed.
+type userIPTransport struct{}
+
+func (userIPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Add("X-User-IP", "0.0.0.0") // hack
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// discoveryBasePath returns the base path of the Discovery API named by
+// the -discoveryurl flag, which is the URL of its apis.list method.
+func discoveryBasePath() string {
+	return strings.TrimSuffix(*apisURL, "apis")
+}
+
+// listDirectory lists the available APIs using the generated Discovery
+// API client.
+func listDirectory() (*discovery.DirectoryList, error) {
+	if *useCache {
+		return nil, fmt.Errorf("Invalid use of listDirectory in cached mode")
+	}
+	svc, err := discovery.New(discoveryClient())
+	if err != nil {
+		return nil, err
+	}
+	svc.BasePath = discoveryBasePath()
+	list, err := svc.Apis.List().Do()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing APIs at %s: %v", *apisURL, err)
+	}
+	return list, nil
+}
+
+// fetchDirectory lists the available APIs, returning the list encoded
+// as in api-list.json.
+func fetchDirectory() ([]byte, error) {
+	list, err := listDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(list, "", " ")
+}
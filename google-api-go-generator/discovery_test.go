@@ -0,0 +1,160 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchDirectory(t *testing.T) {
+	var gotUserIP string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/discovery/v1/apis" {
+			http.NotFound(w, r)
+			return
+		}
+		gotUserIP = r.Header.Get("X-User-IP")
+		w.Write([]byte(`{"kind": "discovery#directoryList", "items": [{"id": "tasks:v1", "name": "tasks", "version": "v1", "preferred": true}]}`))
+	}))
+	defer ts.Close()
+
+	defer func(url string, cache, public bool) {
+		*apisURL, *useCache, *publicOnly = url, cache, public
+	}(*apisURL, *useCache, *publicOnly)
+	*apisURL, *useCache, *publicOnly = ts.URL+"/discovery/v1/apis", false, true
+
+	b, err := fetchDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all AllAPIs
+	if err := json.Unmarshal(b, &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Items) != 1 || all.Items[0].ID != "tasks:v1" || !all.Items[0].Preferred {
+		t.Errorf("got items %+v, want tasks:v1", all.Items)
+	}
+	if gotUserIP == "" {
+		t.Error("X-User-IP header not sent")
+	}
+
+	a := &API{ID: "tasks:v1", Name: "tasks", Version: "v1"}
+	if got, _ := a.DiscoveryURL(); got != ts.URL+"/discovery/v1/apis/tasks/v1/rest" {
+		t.Errorf("DiscoveryURL: got %q", got)
+	}
+}
+
+func TestSlurpURLErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not here", http.StatusNotFound)
+	}))
+	defer ts.Close()
+	defer func(cache bool) { *useCache = cache }(*useCache)
+	*useCache = false
+
+	u := ts.URL + "/discovery/v1/apis/missing/v1/rest"
+	defer os.Remove(urlCacheFile(u))
+	if bs, err := slurpURL(u); err == nil {
+		t.Fatalf("slurpURL of a 404 returned %q, want an error", bs)
+	}
+	if _, err := os.Stat(urlCacheFile(u)); !os.IsNotExist(err) {
+		t.Errorf("the 404 response was cached: %v", err)
+	}
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/discovery/v1/apis":
+			w.Write([]byte(`{"items": [
+				{"id": "tasks:v1", "name": "tasks", "version": "v1", "preferred": true, "discoveryRestUrl": "` + ts.URL + `/tasks/v1/rest"},
+				{"id": "tasks:v0", "name": "tasks", "version": "v0", "discoveryRestUrl": "` + ts.URL + `/tasks/v0/rest"},
+				{"id": "plus:v1", "name": "plus", "version": "v1", "preferred": true, "discoveryRestUrl": "` + ts.URL + `/plus/v1/rest"}
+			]}`))
+		case "/tasks/v1/rest":
+			w.Write([]byte(`{"id": "tasks:v1"}`))
+		case "/tasks/v0/rest":
+			w.Write([]byte(`{"id": "tasks:v0"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	defer func(url string, cache bool, vers string, l *apiList) {
+		*apisURL, *useCache, *versions, apis = url, cache, vers, l
+	}(*apisURL, *useCache, *versions, apis)
+	*apisURL, *useCache, *versions = ts.URL+"/discovery/v1/apis", false, "preferred"
+	// The snapshot holds the APIs chosen by -apilist and -versions.
+	file := writeTempAPIList(t, "!plus:v1\n")
+	defer os.Remove(file)
+	var err error
+	if apis, err = loadAPIList(file); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writeSnapshot(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := ioutil.ReadFile(filepath.Join(dir, "tasks", "v1", "tasks-api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(doc), `{"id": "tasks:v1"}`; got != want {
+		t.Errorf("tasks-api.json: got %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks", "v0")); !os.IsNotExist(err) {
+		t.Errorf("non-preferred API tasks:v0 was downloaded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "plus")); !os.IsNotExist(err) {
+		t.Errorf("API plus:v1, excluded by the API list, was downloaded")
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "api-list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all AllAPIs
+	if err := json.Unmarshal(b, &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Items) != 1 || all.Items[0].ID != "tasks:v1" {
+		t.Errorf("index: got items %+v, want only tasks:v1", all.Items)
+	}
+
+	// An API listed explicitly is included even if not preferred.
+	*versions = "explicit"
+	file2 := writeTempAPIList(t, "tasks:v0\n")
+	defer os.Remove(file2)
+	if apis, err = loadAPIList(file2); err != nil {
+		t.Fatal(err)
+	}
+	dir2, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+	if err := writeSnapshot(dir2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir2, "tasks", "v0", "tasks-api.json")); err != nil {
+		t.Errorf("explicitly listed API tasks:v0 was not downloaded: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir2, "tasks", "v1")); !os.IsNotExist(err) {
+		t.Errorf("unlisted API tasks:v1 was downloaded")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+)
+
+// fakeKinds maps the names of methods to what the fake server of
+// package googleapi/fake does for them.
+var fakeKinds = map[string]string{
+	"list":   "List",
+	"get":    "Get",
+	"insert": "Insert",
+	"create": "Insert",
+	"update": "Update",
+	"patch":  "Patch",
+	"delete": "Delete",
+}
+
+// fakePackageName returns the name of the package, and of its directory
+// beside a's code, written by -fake.
+func (a *API) fakePackageName() string {
+	return a.PackageName() + "fake"
+}
+
+// generateFake returns the source code of a package describing the
+// methods of a to the fake server of package googleapi/fake. It must be
+// called after GenerateCode.
+func (a *API) generateFake() ([]byte, error) {
+	var buf bytes.Buffer
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&buf, format, args...)
+	}
+	pkg := a.fakePackageName()
+	title := jstr(a.m, "title")
+	if title == "" {
+		title = a.Name
+	}
+	p("%s", licenseHeader)
+	p("// Code generated by google-api-go-generator. DO NOT EDIT.\n\n")
+	p("%s", asComment("", fmt.Sprintf("Package %s serves an in-memory fake of the %s, for hermetic tests of programs using package %s. See package %s/fake.",
+		pkg, title, a.PackageName(), *googleapiPkg)))
+	p("package %s\n\n", pkg)
+	p("import %q\n\n", *googleapiPkg+"/fake")
+	p("// ServicePath is the path below which the API is served.\n")
+	p("const ServicePath = %q\n\n", jstr(a.m, "servicePath"))
+	p("// Routes describes the methods of the API to the fake.\n")
+	p("var Routes = []fake.Route{\n")
+	for _, meth := range a.allMethods(a.Resources(a.m, "")) {
+		kind, ok := fakeKinds[meth.name]
+		if !ok {
+			kind = "Other"
+		}
+		p("{ID: %q, HTTPMethod: %q, Path: %q, Kind: fake.%s", meth.Id(), jstr(meth.m, "httpMethod"), jstr(meth.m, "path"), kind)
+		if kind == "List" {
+			if f := meth.itemsField(); f != "" && f != "items" {
+				p(", ItemsField: %q", f)
+			}
+		}
+		p("},\n")
+	}
+	p("}\n\n")
+	p("// NewServer starts and returns a fake of the API. The BasePath field of\n")
+	p("// a service using it should be set to that of the server.\n")
+	p("func NewServer() *fake.Server {\n")
+	p("return fake.NewServer(ServicePath, Routes)\n")
+	p("}\n")
+	return format.Source(buf.Bytes())
+}
+
+// itemsField returns the name of the field of the response of meth, a
+// list method, which holds the items: "items" if there is one, or else
+// its only array field, or "" if there is neither.
+func (meth *Method) itemsField() string {
+	s := meth.responseType()
+	if s == nil || !s.Type().IsStruct() {
+		return ""
+	}
+	var arrays []string
+	for _, prop := range s.properties() {
+		if _, ok := prop.Type().ArrayType(); !ok {
+			continue
+		}
+		if prop.APIName() == "items" {
+			return "items"
+		}
+		arrays = append(arrays, prop.APIName())
+	}
+	if len(arrays) == 1 {
+		return arrays[0]
+	}
+	return ""
+}
+
+// writeFake writes the package generated by generateFake to its
+// directory beside a's code, in dir.
+func (a *API) writeFake(dir string) error {
+	code, err := a.generateFake()
+	if err != nil {
+		return err
+	}
+	fakeDir := filepath.Join(dir, a.fakePackageName())
+	name := a.fakePackageName() + ".go"
+	if err := writeFile(filepath.Join(fakeDir, name), code); err != nil {
+		return err
+	}
+	if *output != "" {
+		return nil
+	}
+	return updateOwnedFiles(fakeDir, []string{name})
+}
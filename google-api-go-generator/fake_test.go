@@ -0,0 +1,67 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFake(t *testing.T) {
+	api, err := apiFromFile(filepath.Join("testdata", "blogger-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	code, err := api.generateFake()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(code)
+	for _, want := range []string{
+		"package bloggerfake\n",
+		`const ServicePath = "blogger/v3/"`,
+		`{ID: "blogger.comments.list", HTTPMethod: "GET", Path: "blogs/{blogId}/posts/{postId}/comments", Kind: fake.List},`,
+		`{ID: "blogger.posts.insert", HTTPMethod: "POST", Path: "blogs/{blogId}/posts", Kind: fake.Insert},`,
+		`{ID: "blogger.posts.search", HTTPMethod: "GET", Path: "blogs/{blogId}/posts/search", Kind: fake.Other},`,
+		`{ID: "blogger.blogs.listByUser", HTTPMethod: "GET", Path: "users/{userId}/blogs", Kind: fake.Other},`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generated fake does not contain %q", want)
+		}
+	}
+}
+
+func TestWriteFakeDryRun(t *testing.T) {
+	defer func(d bool) { *dryRun = d }(*dryRun)
+	defer func() { diffOut = os.Stdout }()
+
+	dir, err := ioutil.TempDir("", "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api, err := apiFromFile(filepath.Join("testdata", "blogger-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	diffOut = ioutil.Discard
+	*dryRun = true
+	if err := api.writeFake(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, api.fakePackageName())); !os.IsNotExist(err) {
+		t.Errorf("dry run created the fake package directory: %v", err)
+	}
+}
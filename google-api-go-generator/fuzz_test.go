@@ -0,0 +1,51 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// maxFuzzSeed is the size of the largest document used as a seed.
+const maxFuzzSeed = 4 << 10
+
+// FuzzGenerateCode checks that the generator rejects malformed discovery
+// documents with an error, rather than crashing or failing with an
+// internal error. The seed corpus is the smaller golden test inputs,
+// which the fuzzer mutates far faster than whole APIs. Run it with
+//
+//	go test -run NONE -fuzz FuzzGenerateCode
+func FuzzGenerateCode(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if len(b) > maxFuzzSeed {
+			continue
+		}
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, doc []byte) {
+		api, err := apiFromJSON(doc)
+		if err != nil {
+			return
+		}
+		if _, err := api.GenerateCode(); err != nil {
+			if _, ok := err.(*internalError); ok {
+				t.Fatal(err)
+			}
+		}
+	})
+}
@@ -6,6 +6,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -20,10 +22,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"unicode"
+
+	"google.golang.org/api/gensupport"
+	"google.golang.org/api/googleapi"
 )
 
 const googleDiscoveryURL = "https://www.googleapis.com/discovery/v1/apis"
@@ -43,24 +50,49 @@ var (
 	apiPackageBase = flag.String("api_pkg_base", "google.golang.org/api", "Go package prefix to use for all generated APIs.")
 	baseURL        = flag.String("base_url", "", "(optional) Override the default service API URL. If empty, the service's root URL will be used.")
 	headerPath     = flag.String("header_path", "", "If non-empty, prepend the contents of this file to generated services.")
+	interfaces     = flag.Bool("interfaces", false, "Generate a Doer interface for each call, and an interface for each resource which is used as the type of its field.")
+	builders       = flag.Bool("builders", false, "Generate fluent builder types for schemas nested at least 3 levels deep.")
+	overridesFile  = flag.String("overrides", "", "If non-empty, the path of a JSON file overriding the Go names and types of schemas and fields.")
+	report         = flag.String("report", "", "If non-empty, the path of a JSON file to which a report of each API's generation is written.")
+	pointers       = flag.Bool("pointers", false, "Represent scalar schema fields as pointers, so that unset and zero values are distinct.")
+	apiListPath    = flag.String("apilist", "", "If non-empty, the path of a file listing the IDs of the APIs to generate, one per line; see apilist.go.")
+	versions       = flag.String("versions", "all", "Which versions of each API in the directory to generate: all, preferred (plus any named by -api or -apilist), or explicit (only those named by -api or -apilist).")
+	flatPkg        = flag.Bool("flatpkg", false, "Generate each API version as a single package named for the API and version, such as drive3, rather than as NAME/VERSION.")
+	pkgSuffix      = flag.String("pkg_suffix", "api", "Suffix appended to the Go package name of an API whose name is that of a standard library package, such as \"logapi\" for an API named \"log\".")
+	dryRun         = flag.Bool("dryrun", false, "Generate code in memory and print a unified diff against the files on disk, instead of writing them.")
+	snapshot       = flag.String("snapshot", "", "If non-empty, download the discovery document of every API chosen by -api, -apilist and -versions into this directory, with an index in api-list.json, instead of generating code.")
+	manifest       = flag.String("manifest", "", "If gomod or json, write a manifest of the generated packages and the packages they import to the -gendir root, as go.mod or packages.json.")
+	excludeLabels  = flag.String("exclude_labels", "", "Comma-separated list of discovery labels, such as \"deprecated,limited_availability\". APIs carrying any of them are not generated.")
+	verbose        = flag.Bool("v", false, "Log the URL template, arguments, parameters and Go identifiers chosen for each method.")
+	watch          = flag.Duration("watch", 0, "If non-zero, poll the discovery directory at this interval and run -watch_hook for each new, revised or removed API version, instead of generating code.")
+	watchHook      = flag.String("watch_hook", "", "Shell command run by -watch for each change, with WATCH_API, WATCH_CHANGE (new, revised or removed) and WATCH_REVISION in its environment.")
+	postHook       = flag.String("posthook", "", "Shell command run after each package is generated (and built, with -build), with API_ID and OUT_DIR in its environment. A failure is reported as an error for that API.")
+	surface        = flag.Bool("surface", false, "Write a description of the exported identifiers of each generated package to PKG-surface.json beside its code, for use with -surface_diff.")
+	contractTests  = flag.Bool("contract_tests", false, "Also write a test of each generated package to PKG-contract_test.go beside its code, checking the URL built by each call against its method's discovery path template.")
+	fakeServer     = flag.Bool("fake", false, "Also generate a package describing each API to the in-memory fake server of package googleapi/fake, in the directory PKGfake beside its code.")
+	surfaceDiff    = flag.Bool("surface_diff", false, "Print the changes between the two surface files named as arguments which could break code using the first, and exit with status 1 if there are any, instead of generating code.")
+	watchState     = flag.String("watch_state", "", "If non-empty, the path of a JSON file in which -watch records the revisions it has seen, so that changes are not reported again after a restart.")
 
 	contextHTTPPkg = flag.String("ctxhttp_pkg", "golang.org/x/net/context/ctxhttp", "Go package path of the 'ctxhttp' package.")
 	contextPkg     = flag.String("context_pkg", "golang.org/x/net/context", "Go package path of the 'context' package.")
+	goTarget       = flag.String("gotarget", "go1", "Oldest Go release the generated code must build with: go1, or go1.7 to use the standard library's context package unless -context_pkg is set.")
 	gensupportPkg  = flag.String("gensupport_pkg", "google.golang.org/api/gensupport", "Go package path of the 'api/gensupport' support package.")
 	googleapiPkg   = flag.String("googleapi_pkg", "google.golang.org/api/googleapi", "Go package path of the 'api/googleapi' support package.")
+	runtimeImport  = flag.String("runtime_import", "", "If non-empty, the Go package path under which the googleapi and gensupport support packages are found, such as that of a fork of this repository. It sets -googleapi_pkg and -gensupport_pkg unless they are set.")
 )
 
 // API represents an API to generate, as well as its state while it's
 // generating.
 type API struct {
-	ID            string `json:"id"`
-	Name          string `json:"name"`
-	Version       string `json:"version"`
-	Title         string `json:"title"`
-	DiscoveryLink string `json:"discoveryRestUrl"` // absolute
-	RootURL       string `json:"rootUrl"`
-	ServicePath   string `json:"servicePath"`
-	Preferred     bool   `json:"preferred"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Version       string   `json:"version"`
+	Title         string   `json:"title"`
+	DiscoveryLink string   `json:"discoveryRestUrl"` // absolute
+	RootURL       string   `json:"rootUrl"`
+	ServicePath   string   `json:"servicePath"`
+	Preferred     bool     `json:"preferred"`
+	Labels        []string `json:"labels"` // e.g. "limited_availability" or "deprecated"
 
 	m map[string]interface{}
 
@@ -68,6 +100,16 @@ type API struct {
 	usedNames     namePool
 	schemas       map[string]*Schema // apiName -> schema
 	responseTypes map[string]bool
+	requestTypes  map[string]bool // apiName of schemas used as request bodies
+	pageTypes     map[string]bool // apiName of schemas used as responses of paged methods
+	builderDepth  map[string]int  // apiName -> nesting depth; populated by computeBuilders
+	warnings      []string        // for the generation report; see warnf
+	skipped       []string        // for the generation report; see skipf
+	losses        []loss          // for the warnings file; see lossf
+	extraImports  []string        // import paths used by type overrides; see addImport
+	imports       []string        // import paths of the generated code; set by importBlock
+	resolving     map[string]bool // apiNames of references being resolved by Type.AsGo
+	validated     map[string]bool // apiNames of schemas with a Validate method; see computeValidation
 
 	p  func(format string, args ...interface{}) // print raw
 	pn func(format string, args ...interface{}) // print with newline
@@ -117,17 +159,111 @@ func (e *compileError) Error() string {
 	return fmt.Sprintf("API %s failed to compile:\n%v", e.api.ID, e.output)
 }
 
+// goTool returns the go command to use for -build and -install. It
+// prefers the one in the Go installation the generator was built with,
+// so that neither make nor a go command on the PATH is needed.
+func goTool() string {
+	name := "go"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	p := filepath.Join(runtime.GOROOT(), "bin", name)
+	if _, err := os.Stat(p); err == nil {
+		return p
+	}
+	return "go"
+}
+
+type hookError struct {
+	api *API
+	err error
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("API %s: -posthook failed: %v", e.api.ID, e.err)
+}
+
+// runPostHook runs the -posthook command for api, whose generated files
+// are in dir. It is overridden in tests.
+var runPostHook = func(api *API, dir string) error {
+	cmd := shellCommand(*postHook)
+	cmd.Env = append(os.Environ(),
+		"API_ID="+api.ID,
+		"OUT_DIR="+dir,
+	)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// outDir returns the directory to which the code of a is written.
+func (a *API) outDir() (string, error) {
+	if *output != "" {
+		return filepath.Dir(*output), nil
+	}
+	return a.SourceDir()
+}
+
 func main() {
 	flag.Parse()
 
+	if *surfaceDiff {
+		if flag.NArg() != 2 {
+			log.Fatal("-surface_diff requires two arguments: the old and new surface files")
+		}
+		n, err := compareSurfaceFiles(os.Stdout, flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if *watch != 0 {
+		log.Fatal(watchDirectory(*watch))
+	}
 	if *install {
 		*build = true
 	}
+	switch *versions {
+	case "all", "preferred", "explicit":
+	default:
+		log.Fatalf("-versions must be all, preferred or explicit, not %q", *versions)
+	}
+	if err := applyGoTarget(*goTarget, setFlags()); err != nil {
+		log.Fatal(err)
+	}
+	applyRuntimeImport(*runtimeImport, setFlags())
+	switch *manifest {
+	case "", "gomod", "json":
+	default:
+		log.Fatalf("-manifest must be gomod or json, not %q", *manifest)
+	}
+	if *apiListPath != "" {
+		var err error
+		if apis, err = loadAPIList(*apiListPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *overridesFile != "" {
+		var err error
+		if overrides, err = loadOverrides(*overridesFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *snapshot != "" {
+		if err := writeSnapshot(*snapshot); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	var (
-		apiIds  = []string{}
-		matches = []*API{}
-		errors  = []error{}
+		apiIds    = []string{}
+		matches   = []*API{}
+		generated = []*API{}
+		errors    = []error{}
+		rep       generationReport
 	)
 	for _, api := range getAPIs() {
 		apiIds = append(apiIds, api.ID)
@@ -138,10 +274,12 @@ func main() {
 		log.Printf("Generating API %s", api.ID)
 		err := api.WriteGeneratedCode()
 		if err != nil {
-			errors = append(errors, &generateError{api, err})
+			err = &generateError{api, err}
+			errors = append(errors, err)
+			rep.add(api, err)
 			continue
 		}
-		if *build {
+		if *build && !*dryRun {
 			var args []string
 			if *install {
 				args = append(args, "install")
@@ -149,11 +287,43 @@ func main() {
 				args = append(args, "build")
 			}
 			args = append(args, api.Target())
-			out, err := exec.Command("go", args...).CombinedOutput()
+			out, err := exec.Command(goTool(), args...).CombinedOutput()
 			if err != nil {
-				errors = append(errors, &compileError{api, string(out)})
+				ce := &compileError{api, string(out)}
+				errors = append(errors, ce)
+				rep.add(api, ce)
+				continue
 			}
 		}
+		if *postHook != "" && !*dryRun {
+			dir, err := api.outDir()
+			if err == nil {
+				err = runPostHook(api, dir)
+			}
+			if err != nil {
+				he := &hookError{api, err}
+				errors = append(errors, he)
+				rep.add(api, he)
+				continue
+			}
+		}
+		rep.add(api, nil)
+		generated = append(generated, api)
+	}
+
+	if *report != "" {
+		if err := rep.write(*report); err != nil {
+			log.Fatalf("writing report: %v", err)
+		}
+	}
+	if *manifest != "" && len(generated) > 0 {
+		root, err := genDirRoot()
+		if err == nil {
+			err = writeManifest(root, *manifest, generated)
+		}
+		if err != nil {
+			log.Fatalf("writing manifest: %v", err)
+		}
 	}
 
 	if len(matches) == 0 {
@@ -170,6 +340,10 @@ func main() {
 }
 
 func (a *API) want() bool {
+	if l, ok := a.excludedLabel(); ok {
+		log.Printf("Skipping API %s, which is labeled %q", a.ID, l)
+		return false
+	}
 	if *jsonFile != "" {
 		// Return true early, before calling a.JSONFile()
 		// which will require a GOPATH be set.  This is for
@@ -179,20 +353,54 @@ func (a *API) want() bool {
 	}
 	// Skip this API if we're in cached mode and the files don't exist on disk.
 	if *useCache {
-		if _, err := os.Stat(a.JSONFile()); os.IsNotExist(err) {
+		file, err := a.JSONFile()
+		if err != nil {
+			log.Printf("Skipping API %s: %v", a.ID, err)
+			return false
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
 			return false
 		}
 	}
 	return *apiToGenerate == "*" || *apiToGenerate == a.ID
 }
 
+// excludedLabel returns the first label of a named by -exclude_labels.
+func (a *API) excludedLabel() (string, bool) {
+	if *excludeLabels == "" {
+		return "", false
+	}
+	for _, l := range a.Labels {
+		for _, x := range strings.Split(*excludeLabels, ",") {
+			if l == strings.TrimSpace(x) {
+				return l, true
+			}
+		}
+	}
+	return "", false
+}
+
+// hasLabel reports whether labels contains label.
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
 func getAPIs() []*API {
 	if *jsonFile != "" {
 		return getAPIsFromFile()
 	}
 	var all AllAPIs
 	var disco []byte
-	apiListFile := filepath.Join(genDirRoot(), "api-list.json")
+	root, err := genDirRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiListFile := filepath.Join(root, "api-list.json")
 	if *useCache {
 		if !*publicOnly {
 			log.Fatalf("-cached=true not compatible with -publiconly=false")
@@ -203,7 +411,11 @@ func getAPIs() []*API {
 			log.Fatal(err)
 		}
 	} else {
-		disco = slurpURL(*apisURL)
+		var err error
+		disco, err = fetchDirectory()
+		if err != nil {
+			log.Fatal(err)
+		}
 		if *publicOnly {
 			if err := writeFile(apiListFile, disco); err != nil {
 				log.Fatal(err)
@@ -213,10 +425,36 @@ func getAPIs() []*API {
 	if err := json.Unmarshal(disco, &all); err != nil {
 		log.Fatalf("error decoding JSON in %s: %v", *apisURL, err)
 	}
+	return selectAPIs(&all)
+}
+
+// selectAPIs returns the APIs of the directory all which are chosen by
+// -api, -apilist and -versions.
+func selectAPIs(all *AllAPIs) []*API {
 	if !*publicOnly && *apiToGenerate != "*" {
 		all.addAPI(*apiToGenerate)
 	}
-	return all.Items
+	items := all.Items
+	if apis != nil {
+		items = apis.filter(all)
+	}
+	return filterVersions(items)
+}
+
+// filterVersions returns the APIs of items selected by -versions.
+// APIs named by -api or -apilist are always kept.
+func filterVersions(items []*API) []*API {
+	if *versions == "all" {
+		return items
+	}
+	var out []*API
+	for _, a := range items {
+		explicit := a.ID == *apiToGenerate || apis != nil && apis.allows(a.ID)
+		if explicit || *versions == "preferred" && a.Preferred {
+			out = append(out, a)
+		}
+	}
+	return out
 }
 
 // getAPIsFromFile handles the case of generating exactly one API
@@ -240,26 +478,95 @@ func apiFromFile(file string) (*API, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Error reading %s: %v", file, err)
 	}
+	a, err := apiFromJSON(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Decoding JSON in %s: %v", file, err)
+	}
+	return a, nil
+}
+
+// apiFromJSON returns the API described by the discovery document jsonBytes.
+func apiFromJSON(jsonBytes []byte) (*API, error) {
 	a := &API{
 		forceJSON: jsonBytes,
 	}
 	if err := json.Unmarshal(jsonBytes, a); err != nil {
-		return nil, fmt.Errorf("Decoding JSON in %s: %v", file, err)
+		return nil, err
 	}
 	return a, nil
 }
 
+// longPath returns file in a form which may exceed the limit of 260
+// characters on Windows paths, which the directories of deeply nested
+// APIs can reach. On other systems it returns file unchanged.
+func longPath(file string) string {
+	if runtime.GOOS != "windows" || len(file) < 248 || strings.HasPrefix(file, `\\`) {
+		return file
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return `\\?\` + abs
+}
+
 func writeFile(file string, contents []byte) error {
 	// Don't write it if the contents are identical.
-	existing, err := ioutil.ReadFile(file)
+	existing, err := ioutil.ReadFile(longPath(file))
 	if err == nil && (bytes.Equal(existing, contents) || basicallyEqual(existing, contents)) {
 		return nil
 	}
+	if *dryRun {
+		if err != nil {
+			existing = nil
+		}
+		printDiff(file, existing, contents)
+		return nil
+	}
 	outdir := filepath.Dir(file)
-	if err = os.MkdirAll(outdir, 0755); err != nil {
+	if err = os.MkdirAll(longPath(outdir), 0755); err != nil {
 		return fmt.Errorf("failed to Mkdir %s: %v", outdir, err)
 	}
-	return ioutil.WriteFile(file, contents, 0644)
+	return writeFileAtomic(longPath(file), contents)
+}
+
+// writeFileAtomic writes contents to file by way of a temporary file in
+// the same directory, so that an interrupted run leaves either the old
+// contents or the new, never a mixture that a later run would take as
+// up to date.
+func writeFileAtomic(file string, contents []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(contents)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// removeFile removes file, if it exists.
+func removeFile(file string) error {
+	if *dryRun {
+		if existing, err := ioutil.ReadFile(file); err == nil {
+			printDiff(file, existing, nil)
+		}
+		return nil
+	}
+	if err := os.Remove(longPath(file)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 var ignoreLines = regexp.MustCompile(`(?m)^\s+"(?:etag|revision)": ".+\n`)
@@ -271,32 +578,64 @@ func basicallyEqual(a, b []byte) bool {
 		bytes.Equal(ignoreLines.ReplaceAll(a, nil), ignoreLines.ReplaceAll(b, nil))
 }
 
-func slurpURL(urlStr string) []byte {
+// urlCacheFile returns the file in which slurpURL caches the contents of
+// urlStr. It is named by a hash of the URL, so that its name is short
+// and valid on every operating system, however long the URL.
+func urlCacheFile(urlStr string) string {
+	sum := sha1.Sum([]byte(urlStr))
+	return filepath.Join(os.TempDir(), "google-api-cache-"+hex.EncodeToString(sum[:]))
+}
+
+// slurpURL returns the contents of urlStr. Fetched contents are cached
+// in $TMPDIR, and read from there in cached mode.
+func slurpURL(urlStr string) ([]byte, error) {
+	file := urlCacheFile(urlStr)
 	if *useCache {
-		log.Fatalf("Invalid use of slurpURL in cached mode for URL %s", urlStr)
+		bs, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("No cached copy of URL %s: %v", urlStr, err)
+		}
+		return bs, nil
 	}
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
-		log.Fatal(err)
-	}
-	if *publicOnly {
-		req.Header.Add("X-User-IP", "0.0.0.0") // hack
+		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
+	res, err := discoveryClient().Do(req)
 	if err != nil {
-		log.Fatalf("Error fetching URL %s: %v", urlStr, err)
+		return nil, fmt.Errorf("Error fetching URL %s: %v", urlStr, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("Error fetching URL %s: %s", urlStr, res.Status)
 	}
 	bs, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		log.Fatalf("Error reading body of URL %s: %v", urlStr, err)
+		return nil, fmt.Errorf("Error reading body of URL %s: %v", urlStr, err)
+	}
+	if err := writeFileAtomic(file, bs); err != nil {
+		log.Printf("Caching URL %s: %v", urlStr, err)
 	}
-	return bs
+	return bs, nil
 }
 
 func panicf(format string, args ...interface{}) {
 	panic(fmt.Sprintf(format, args...))
 }
 
+// internalError is returned by GenerateCode when generation fails with a
+// runtime panic, such as a nil dereference, rather than by calling panicf.
+// It indicates a bug in the generator: malformed discovery documents
+// should be reported with panicf.
+type internalError struct {
+	err   error
+	stack []byte
+}
+
+func (e *internalError) Error() string {
+	return fmt.Sprintf("internal error: %v\n%s", e.err, e.stack)
+}
+
 // namePool keeps track of used names and assigns free ones based on a
 // preferred name
 type namePool struct {
@@ -337,26 +676,61 @@ func (p *namePool) Get(preferred string) string {
 	return name
 }
 
-func genDirRoot() string {
+// genDirRoot returns the directory in which the packages are generated:
+// -gendir, or else the google.golang.org/api directory in the first
+// element of GOPATH.
+func genDirRoot() (string, error) {
 	if *genDir != "" {
-		return *genDir
+		return *genDir, nil
 	}
 	paths := filepath.SplitList(os.Getenv("GOPATH"))
 	if len(paths) == 0 {
-		log.Fatalf("No GOPATH set.")
+		return "", errors.New("no GOPATH set; use -gendir to choose where to generate packages")
+	}
+	return filepath.Join(paths[0], "src", "google.golang.org", "api"), nil
+}
+
+func (a *API) SourceDir() (string, error) {
+	root, err := genDirRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(a.relPath())), nil
+}
+
+// relPath returns the slash-separated path of a's package relative to
+// -api_pkg_base: NAME/VERSION, or the package name alone with -flatpkg.
+func (a *API) relPath() string {
+	if *flatPkg {
+		return a.flatName()
 	}
-	return filepath.Join(paths[0], "src", "google.golang.org", "api")
+	return a.Package() + "/" + renameVersion(a.Version)
 }
 
-func (a *API) SourceDir() string {
-	return filepath.Join(genDirRoot(), a.Package(), renameVersion(a.Version))
+// flatName returns the name of a's package and directory with -flatpkg,
+// which combines the API's name and version, e.g. "drive3" for drive:v3
+// and "admindirectory1" for admin:directory_v1.
+func (a *API) flatName() string {
+	v := flatVersionRE.ReplaceAllString(strings.ToLower(a.Version), "$1")
+	v = strings.Replace(v, "_", "", -1)
+	v = strings.Replace(v, ".", "_", -1)
+	return a.Package() + v
 }
 
-func (a *API) DiscoveryURL() string {
-	if a.DiscoveryLink == "" {
-		log.Fatalf("API %s has no DiscoveryLink", a.ID)
+// flatVersionRE matches a "v" which starts a version number.
+var flatVersionRE = regexp.MustCompile(`(?:^|_)v(\d)`)
+
+// DiscoveryURL returns the URL of the API's discovery document. APIs
+// not taken from the directory, such as those added with -api, use the
+// URL of the Discovery API's apis.getRest method.
+func (a *API) DiscoveryURL() (string, error) {
+	if a.DiscoveryLink != "" {
+		return a.DiscoveryLink, nil
+	}
+	if a.Name == "" || a.Version == "" {
+		return "", fmt.Errorf("API %s has no DiscoveryLink", a.ID)
 	}
-	return a.DiscoveryLink
+	return fmt.Sprintf("%sapis/%s/%s/rest", discoveryBasePath(), a.Name, a.Version), nil
 }
 
 func (a *API) Package() string {
@@ -364,7 +738,7 @@ func (a *API) Package() string {
 }
 
 func (a *API) Target() string {
-	return fmt.Sprintf("%s/%s/%s", *apiPackageBase, a.Package(), renameVersion(a.Version))
+	return *apiPackageBase + "/" + a.relPath()
 }
 
 // GetName returns a free top-level function/type identifier in the package.
@@ -395,56 +769,156 @@ func (a *API) needsDataWrapper() bool {
 	return false
 }
 
-func (a *API) jsonBytes() []byte {
+func (a *API) jsonBytes() ([]byte, error) {
 	if v := a.forceJSON; v != nil {
-		return v
+		return v, nil
 	}
 	if *useCache {
-		slurp, err := ioutil.ReadFile(a.JSONFile())
+		file, err := a.JSONFile()
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		return slurp
+		return ioutil.ReadFile(file)
+	}
+	u, err := a.DiscoveryURL()
+	if err != nil {
+		return nil, err
 	}
-	return slurpURL(a.DiscoveryURL())
+	return slurpURL(u)
 }
 
-func (a *API) JSONFile() string {
-	return filepath.Join(a.SourceDir(), a.Package()+"-api.json")
+func (a *API) JSONFile() (string, error) {
+	dir, err := a.SourceDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, a.Package()+"-api.json"), nil
 }
 
 func (a *API) WriteGeneratedCode() error {
 	genfilename := *output
+	var jsonfilename string
 	if genfilename == "" {
-		if err := writeFile(a.JSONFile(), a.jsonBytes()); err != nil {
+		jsonBytes, err := a.jsonBytes()
+		if err != nil {
 			return err
 		}
-		outdir := a.SourceDir()
-		err := os.MkdirAll(outdir, 0755)
+		outdir, err := a.SourceDir()
 		if err != nil {
-			return fmt.Errorf("failed to Mkdir %s: %v", outdir, err)
+			return err
+		}
+		jsonfilename = filepath.Join(outdir, a.Package()+"-api.json")
+		if err := writeFile(jsonfilename, jsonBytes); err != nil {
+			return err
+		}
+		if !*dryRun {
+			if err := os.MkdirAll(longPath(outdir), 0755); err != nil {
+				return fmt.Errorf("failed to Mkdir %s: %v", outdir, err)
+			}
 		}
 		pkg := a.Package()
 		genfilename = filepath.Join(outdir, pkg+"-gen.go")
 	}
 
 	code, err := a.GenerateCode()
+	if code == nil {
+		// Leave any previously generated file in place.
+		return err
+	}
 	errw := writeFile(genfilename, code)
 	if err == nil {
 		err = errw
 	}
+	warnfilename := filepath.Join(filepath.Dir(genfilename), a.Package()+"-warnings.txt")
+	errw = a.writeWarnings(warnfilename)
+	if err == nil {
+		err = errw
+	}
+	var surfacefilename string
+	if *surface && err == nil {
+		surfacefilename = filepath.Join(filepath.Dir(genfilename), a.Package()+"-surface.json")
+		err = writeSurface(surfacefilename, code)
+	}
+	var contractfilename string
+	if *contractTests && err == nil {
+		contractfilename = filepath.Join(filepath.Dir(genfilename), a.Package()+"-contract_test.go")
+		var test []byte
+		test, err = a.generateContractTests()
+		if err == nil && test != nil {
+			err = writeFile(contractfilename, test)
+		} else {
+			contractfilename = ""
+		}
+	}
+	if *fakeServer && err == nil {
+		err = a.writeFake(filepath.Dir(genfilename))
+	}
+	if err == nil && *output == "" {
+		written := []string{filepath.Base(jsonfilename), filepath.Base(genfilename)}
+		if len(a.losses) > 0 {
+			written = append(written, filepath.Base(warnfilename))
+		}
+		if surfacefilename != "" {
+			written = append(written, filepath.Base(surfacefilename))
+		}
+		if contractfilename != "" {
+			written = append(written, filepath.Base(contractfilename))
+		}
+		err = updateOwnedFiles(filepath.Dir(genfilename), written)
+	}
 	return err
 }
 
+// licenseHeader starts each generated file unless -header_path is set.
+const licenseHeader = `// Copyright 2011 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+`
+
+// writeProvenance writes a comment marking the output as generated and
+// recording where it was generated from.
+func (a *API) writeProvenance() {
+	pn := a.pn
+	pn("// Code generated by google-api-go-generator. DO NOT EDIT.")
+	pn("//")
+	if u, err := a.DiscoveryURL(); err == nil {
+		pn("// Source: %s", u)
+	}
+	if rev := jstr(a.m, "revision"); rev != "" {
+		pn("// Revision: %s", rev)
+	}
+	pn("// Generator: google-api-go-generator %s", googleapi.Version)
+	pn("")
+}
+
 var docsLink string
 
-func (a *API) GenerateCode() ([]byte, error) {
-	pkg := a.Package()
+// GenerateCode returns the generated Go source for a. If a cannot be
+// generated, for instance because its discovery document uses an
+// unsupported construct, GenerateCode returns an error rather than
+// panicking, so that other APIs can still be generated.
+func (a *API) GenerateCode() (code []byte, outerr error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if err, ok := r.(runtime.Error); ok {
+			code, outerr = nil, &internalError{err, debug.Stack()}
+			return
+		}
+		code, outerr = nil, fmt.Errorf("%v", r)
+	}()
+	pkg := a.PackageName()
 
 	a.m = make(map[string]interface{})
 	m := a.m
-	jsonBytes := a.jsonBytes()
-	err := json.Unmarshal(jsonBytes, &a.m)
+	jsonBytes, err := a.jsonBytes()
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(jsonBytes, &a.m)
 	if err != nil {
 		return nil, err
 	}
@@ -454,6 +928,9 @@ func (a *API) GenerateCode() ([]byte, error) {
 	if err := json.Unmarshal(jsonBytes, a); err != nil {
 		return nil, err
 	}
+	if err := a.inlineExternalRefs(); err != nil {
+		return nil, err
+	}
 
 	// Buffer the output in memory, for gofmt'ing later.
 	var buf bytes.Buffer
@@ -484,7 +961,10 @@ func (a *API) GenerateCode() ([]byte, error) {
 		if err := wf(*headerPath); err != nil {
 			return nil, err
 		}
+	} else {
+		p("%s", licenseHeader)
 	}
+	a.writeProvenance()
 
 	pn("// Package %s provides access to the %s.", pkg, jstr(m, "title"))
 	docsLink = jstr(m, "documentationLink")
@@ -497,54 +977,60 @@ func (a *API) GenerateCode() ([]byte, error) {
 	pn("//   import %q", a.Target())
 	pn("//   ...")
 	pn("//   %sService, err := %s.New(oauthHttpClient)", pkg, pkg)
+	if hasLabel(a.Labels, "limited_availability") {
+		pn("//")
+		pn("// This API is available only to some users.")
+	}
+	if hasLabel(a.Labels, "deprecated") {
+		pn("//")
+		pn("// Deprecated: The %s is deprecated.", jstr(m, "title"))
+	}
 
 	pn("package %s // import %q", pkg, a.Target())
-	p("\n")
-	pn("import (")
-	for _, imp := range []struct {
-		pkg   string
-		lname string
-	}{
-		{"bytes", ""},
-		{"encoding/json", ""},
-		{"errors", ""},
-		{"fmt", ""},
-		{"io", ""},
-		{"net/http", ""},
-		{"net/url", ""},
-		{"strconv", ""},
-		{"strings", ""},
-		{*contextHTTPPkg, "ctxhttp"},
-		{*contextPkg, "context"},
-		{*gensupportPkg, "gensupport"},
-		{*googleapiPkg, "googleapi"},
-	} {
-		if imp.lname == "" {
-			pn("  %q", imp.pkg)
-		} else {
-			pn("  %s %q", imp.lname, imp.pkg)
-		}
-	}
-	pn(")")
-	pn("\n// Always reference these packages, just in case the auto-generated code")
-	pn("// below doesn't.")
-	pn("var _ = bytes.NewBuffer")
-	pn("var _ = strconv.Itoa")
-	pn("var _ = fmt.Sprintf")
-	pn("var _ = json.NewDecoder")
-	pn("var _ = io.Copy")
-	pn("var _ = url.Parse")
-	pn("var _ = gensupport.MarshalJSON")
-	pn("var _ = googleapi.Version")
-	pn("var _ = errors.New")
-	pn("var _ = strings.Replace")
-	pn("var _ = context.Canceled")
-	pn("var _ = ctxhttp.Do")
+	// The import block is only known once all code has been written, so
+	// that it names just the packages used; it is inserted here afterwards.
+	importsAt := buf.Len()
 	pn("")
 	pn("const apiId = %q", jstr(m, "id"))
 	pn("const apiName = %q", jstr(m, "name"))
 	pn("const apiVersion = %q", jstr(m, "version"))
 	pn("const basePath = %q", a.apiBaseURL())
+	pn("")
+
+	a.GetName("ClientVersion")     // ignore return value; reserved for the constant
+	a.GetName("DiscoveryRevision") // ignore return value; reserved for the constant
+	pn("// ClientVersion is the version of the client library this package was generated for.")
+	pn("const ClientVersion = %q", googleapi.Version)
+	pn("")
+	pn("// DiscoveryRevision is the revision of the discovery document this package was generated from.")
+	pn("const DiscoveryRevision = %q", jstr(m, "revision"))
+	pn("")
+	pn("func init() {")
+	pn(" gensupport.CheckVersion(apiId, %d)", gensupport.Version)
+	pn(" googleapi.RegisterAPI(googleapi.APIInfo{")
+	pn("  ID: apiId,")
+	pn("  Name: apiName,")
+	pn("  Version: apiVersion,")
+	pn("  ClientVersion: ClientVersion,")
+	pn("  DiscoveryRevision: DiscoveryRevision,")
+	if len(a.Labels) > 0 {
+		pn("  Labels: %s,", goStringSlice(a.Labels))
+	}
+	pn("  Methods: []googleapi.MethodInfo{")
+	for _, meth := range a.allMethods(reslist) {
+		labels := ""
+		if l := jstrlist(meth.m, "labels"); len(l) > 0 {
+			labels = ", Labels: " + goStringSlice(l)
+		}
+		scopes := ""
+		if l := jstrlist(meth.m, "scopes"); len(l) > 0 {
+			scopes = ", Scopes: " + goStringSlice(l)
+		}
+		pn("   {ID: %q, HTTPMethod: %q, Idempotent: %v%s%s},", meth.Id(), jstr(meth.m, "httpMethod"), meth.isIdempotent(), labels, scopes)
+	}
+	pn("  },")
+	pn(" })")
+	pn("}")
 
 	a.generateScopeConstants()
 
@@ -559,13 +1045,26 @@ func (a *API) GenerateCode() ([]byte, error) {
 	pn("}")
 
 	a.GetName("Service") // ignore return value; no user-defined names yet
-	pn("\ntype Service struct {")
+	if *interfaces {
+		for _, res := range reslist {
+			res.nameInterfaces()
+		}
+	}
+	p("\n%s", asComment("", "A Service is safe for concurrent use by multiple goroutines once it has been configured: "+
+		"its fields must not be changed, nor its configuration methods such as Retry called, while calls made through it are in progress. "+
+		"A call, as returned by a method of one of its resources, is for use by one goroutine at a time; "+
+		"use its Clone method to make copies for other goroutines."))
+	pn("type Service struct {")
 	pn(" client *http.Client")
 	pn(" BasePath string // API endpoint base URL")
 	pn(" UserAgent string // optional additional User-Agent fragment")
+	pn(" settings gensupport.ServiceSettings")
+	if a.hasUserProject() {
+		pn(" userProject string // default userProject parameter")
+	}
 
 	for _, res := range reslist {
-		pn("\n\t%s\t*%s", res.GoField(), res.GoType())
+		pn("\n\t%s\t%s", res.GoField(), res.fieldType())
 	}
 	pn("}")
 	pn("\nfunc (s *Service) userAgent() string {")
@@ -573,23 +1072,175 @@ func (a *API) GenerateCode() ([]byte, error) {
 	pn(` return googleapi.UserAgent + " " + s.UserAgent`)
 	pn("}\n")
 
+	a.GetName("DryRun") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "DryRun sets whether calls made through s are sent to the server. "+
+		"When enabled, each call builds its request as usual but, instead of sending it, "+
+		"returns a *googleapi.DryRunError holding the request. "+
+		"Use googleapi.IsDryRun to retrieve it. "+
+		"Unless the request is then sent, the caller must close its Body, if non-nil: "+
+		"the body of a media upload is written by a goroutine which otherwise blocks forever."))
+	pn("func (s *Service) DryRun(enabled bool) {")
+	pn(" s.settings.DryRun = enabled")
+	pn("}\n")
+
+	a.GetName("StrictDecoding") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "StrictDecoding sets whether responses to calls made through s "+
+		"must match the generated types exactly. When enabled, a response containing a field "+
+		"unknown to this package causes Do to return an error, rather than the field being "+
+		"silently dropped. This can be used to detect changes to the API's schema."))
+	pn("func (s *Service) StrictDecoding(enabled bool) {")
+	pn(" s.settings.DisallowUnknownFields = enabled")
+	pn("}\n")
+
+	a.GetName("ValidateEnums") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "ValidateEnums sets whether calls made through s check the values of "+
+		"enum parameters and request fields before sending. When enabled, a call using a value "+
+		"which the API does not accept fails with a *googleapi.EnumError listing the accepted "+
+		"values, without being sent. It is disabled by default."))
+	pn("func (s *Service) ValidateEnums(enabled bool) {")
+	pn(" s.settings.ValidateEnums = enabled")
+	pn("}\n")
+
+	a.GetName("APIClientHeader") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "APIClientHeader sets whether calls made through s send the "+
+		"x-goog-api-client header, which reports the versions of Go and of this library "+
+		"to the server to aid debugging. It is enabled by default."))
+	pn("func (s *Service) APIClientHeader(enabled bool) {")
+	pn(" s.settings.OmitAPIClientHeader = !enabled")
+	pn("}\n")
+
+	if a.hasUserProject() {
+		a.GetName("UserProject") // ignore return value; reserved for the Service method
+		p("%s", asComment("", "UserProject sets the project billed for calls made through s "+
+			"which take the userProject parameter, such as calls on requester-pays buckets. "+
+			"It is sent with their media uploads and downloads too, "+
+			"unless a call sets the parameter itself."))
+		pn("func (s *Service) UserProject(project string) {")
+		pn(" s.userProject = project")
+		pn("}\n")
+	}
+
+	a.GetName("MethodOverride") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "MethodOverride sets whether calls made through s which use HTTP methods "+
+		"other than GET and POST, such as PATCH and DELETE, are sent as POST requests "+
+		"with the original method in the X-HTTP-Method-Override header. "+
+		"This allows calls to pass through proxies which only permit GET and POST."))
+	pn("func (s *Service) MethodOverride(enabled bool) {")
+	pn(" s.settings.MethodOverride = enabled")
+	pn("}\n")
+
+	a.GetName("Retry") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "Retry sets whether calls made through s are resent, with exponential "+
+		"backoff, when they fail with a 5xx or 429 status or a temporary network error. "+
+		"Calls exceeding a rate limit are retried, but not those which exhausted a quota; see googleapi.ClassifyLimit. "+
+		"Only calls to idempotent methods, those using GET, PUT or DELETE which do not upload media, "+
+		"are retried. It is disabled by default."))
+	pn("func (s *Service) Retry(enabled bool) {")
+	pn(" s.settings.Retry = enabled")
+	pn("}\n")
+
+	a.GetName("CircuitBreaker") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "CircuitBreaker sets the policy for a circuit breaker and retry budget "+
+		"shared by all calls made through s. After a run of failed calls the breaker opens, "+
+		"and calls fail with a *googleapi.CircuitOpenError without being sent, until a probe "+
+		"call succeeds. A nil policy disables the breaker, which is the default. "+
+		"Setting a policy resets the state of any previous breaker."))
+	pn("func (s *Service) CircuitBreaker(p *googleapi.BreakerPolicy) {")
+	pn(" s.settings.Breaker = gensupport.NewBreaker(p)")
+	pn("}\n")
+
+	a.GetName("Hedge") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "Hedge sets the policy for hedging the GET calls made through s. "+
+		"A call which has not received a response within the hedging delay sends a second, "+
+		"identical request, and uses whichever response arrives first. "+
+		"A nil policy disables hedging, which is the default."))
+	pn("func (s *Service) Hedge(p *googleapi.HedgePolicy) {")
+	pn(" s.settings.Hedger = gensupport.NewHedger(p)")
+	pn("}\n")
+
+	a.GetName("OnRateLimit") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "OnRateLimit sets a function to be called with the quota information "+
+		"reported in the response to each call made through s, such as the number of requests "+
+		"remaining, so that bulk clients can slow down before exhausting their quota. "+
+		"It is called with the discovery method ID of the call. Responses which report no "+
+		"quota information are ignored. The function may be called concurrently. "+
+		"The same information is available from the RateLimit method of each response's ServerResponse."))
+	pn("func (s *Service) OnRateLimit(f func(methodID string, rl *googleapi.RateLimit)) {")
+	pn(" s.settings.OnRateLimit = f")
+	pn("}\n")
+
+	a.GetName("OnDeprecation") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "OnDeprecation sets a function to be called when the response to a call made through s "+
+		"announces, in a Sunset header or a Warning header with code 299, that the method is deprecated "+
+		"or will be shut down. It is called with the discovery method ID of the call and the announcement, "+
+		"and may be called concurrently. By default, the first announcement for each method is logged."))
+	pn("func (s *Service) OnDeprecation(f func(methodID string, d *googleapi.Deprecation)) {")
+	pn(" s.settings.OnDeprecation = f")
+	pn("}\n")
+
+	a.GetName("SetCodec") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "SetCodec sets the Codec used to encode request bodies and decode "+
+		"responses for calls made through s, for instance to use a faster JSON implementation "+
+		"when decoding large responses. A nil Codec selects googleapi.JSONCodec, which is the default."))
+	pn("func (s *Service) SetCodec(c googleapi.Codec) {")
+	pn(" s.settings.Codec = c")
+	pn("}\n")
+
+	a.GetName("SetTracer") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "SetTracer sets the tracer used to create a span for each call made "+
+		"through s. Spans are named by the discovery method ID of the call. "+
+		"A nil tracer disables tracing, which is the default."))
+	pn("func (s *Service) SetTracer(t googleapi.Tracer) {")
+	pn(" s.settings.Tracer = t")
+	pn("}\n")
+
+	a.GetName("OnDecode") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "OnDecode sets a function to be called with the discovery method ID of each call made through s "+
+		"and the value decoded from its response, before the value is returned by Do or DecodeInto. "+
+		"It may modify the value, for instance to normalize or scrub it, or record it, for instance to populate a cache. "+
+		"The function may be called concurrently. A nil function, the default, disables the hook."))
+	pn("func (s *Service) OnDecode(f func(methodID string, v interface{})) {")
+	pn(" s.settings.OnDecode = f")
+	pn("}\n")
+
+	a.GetName("OnSLOViolation") // ignore return value; reserved for the Service method
+	p("%s", asComment("", "OnSLOViolation sets a function to be called with each call made through s "+
+		"which takes longer than the budget of the latency class declared for its method "+
+		"with googleapi.SetLatencyClass. The function may be called concurrently. "+
+		"A nil function, the default, leaves violations to the handler set by googleapi.HandleSLOViolations."))
+	pn("func (s *Service) OnSLOViolation(f func(v *googleapi.SLOViolation)) {")
+	pn(" s.settings.OnSLOViolation = f")
+	pn("}\n")
+
+	a.writePing(reslist)
+
 	for _, res := range reslist {
 		res.generateType()
 	}
 
-	a.PopulateSchemas()
+	if err := a.PopulateSchemas(); err != nil {
+		return nil, err
+	}
 
 	a.responseTypes = make(map[string]bool)
+	a.requestTypes = make(map[string]bool)
+	a.pageTypes = make(map[string]bool)
 	for _, meth := range a.APIMethods() {
-		meth.cacheResponseTypes(a)
+		meth.cacheTypes(a)
 	}
 	for _, res := range reslist {
-		res.cacheResponseTypes(a)
+		res.cacheTypes(a)
 	}
 
+	if *builders {
+		a.computeBuilders()
+	}
+	a.computeValidation()
+
 	for _, name := range a.sortedSchemaNames() {
 		a.schemas[name].writeSchemaCode(a)
 	}
+	a.writeKinds()
 
 	for _, meth := range a.APIMethods() {
 		meth.generateCode()
@@ -599,9 +1250,16 @@ func (a *API) GenerateCode() ([]byte, error) {
 		res.generateMethods()
 	}
 
-	clean, err := format.Source(buf.Bytes())
+	src := buf.Bytes()
+	imps, err := a.importBlock(src[importsAt:])
 	if err != nil {
-		return buf.Bytes(), err
+		return src, err
+	}
+	src = append(src[:importsAt:importsAt], append(imps, src[importsAt:]...)...)
+
+	clean, err := format.Source(src)
+	if err != nil {
+		return src, err
 	}
 	return clean, nil
 }
@@ -630,12 +1288,38 @@ func (a *API) generateScopeConstants() {
 		}
 		n++
 		ident := scopeIdentifierFromURL(scopeName)
-		if des := jstr(mi.(map[string]interface{}), "description"); des != "" {
+		if des := jstr(asObject(mi, "scope %q", scopeName), "description"); des != "" {
 			a.p("%s", asComment("\t", des))
 		}
 		a.pn("\t%s = %q", ident, scopeName)
 	}
 	a.p(")\n\n")
+
+	descs := a.GetName("ScopeDescriptions")
+	a.p("%s", asComment("", fmt.Sprintf("%s maps the OAuth2 scopes used by this API to their descriptions, "+
+		"for showing users what access is requested, as on consent screens.", descs)))
+	a.pn("var %s = map[string]string{", descs)
+	for _, scopeName := range sortedKeys(scopes) {
+		des := jstr(asObject(scopes[scopeName], "scope %q", scopeName), "description")
+		a.pn("\t%s: %q,", scopeIdentifierFromURL(scopeName), des)
+	}
+	a.pn("}\n")
+
+	lookup := a.GetName("ScopeIdentifier")
+	idents := a.GetName("scopeIdentifiers")
+	a.p("%s", asComment("", fmt.Sprintf("%s returns the name of the constant in this package for scope, "+
+		"such as %q, or \"\" if scope is not used by this API. "+
+		"It is meant for tools which report the scopes of tokens, such as audit tools.",
+		lookup, scopeIdentifierFromURL(sortedKeys(scopes)[0]))))
+	a.pn("func %s(scope string) string {", lookup)
+	a.pn("\treturn %s[scope]", idents)
+	a.pn("}\n")
+	a.pn("var %s = map[string]string{", idents)
+	for _, scopeName := range sortedKeys(scopes) {
+		ident := scopeIdentifierFromURL(scopeName)
+		a.pn("\t%s: %q,", ident, ident)
+	}
+	a.pn("}\n")
 }
 
 func scopeIdentifierFromURL(urlStr string) string {
@@ -643,7 +1327,7 @@ func scopeIdentifierFromURL(urlStr string) string {
 	if !strings.HasPrefix(urlStr, prefix) {
 		const https = "https://"
 		if !strings.HasPrefix(urlStr, https) {
-			log.Fatalf("Unexpected oauth2 scope %q doesn't start with %q", urlStr, https)
+			panicf("Unexpected oauth2 scope %q doesn't start with %q", urlStr, https)
 		}
 		ident := validGoIdentifer(depunct(urlStr[len(https):], true)) + "Scope"
 		return ident
@@ -661,6 +1345,7 @@ type Schema struct {
 	apiName      string // the native API-defined name of this type
 	goName       string // lazily populated by GoName
 	goReturnType string // lazily populated by GoReturnType
+	builderName  string // name of the generated builder type, if any; set by computeBuilders
 }
 
 type Property struct {
@@ -679,6 +1364,9 @@ func (p *Property) Type() *Type {
 }
 
 func (p *Property) GoName() string {
+	if o := p.override(); o != nil && o.GoName != "" {
+		return o.GoName
+	}
 	return initialCap(p.apiName)
 }
 
@@ -720,6 +1408,18 @@ func (p *Property) EnumDescriptions() []string {
 	return nil
 }
 
+// IsRequired reports whether discovery marks p as required, either
+// directly or for at least one method taking p's schema as its request.
+func (p *Property) IsRequired() bool {
+	if v, _ := p.m["required"].(bool); v {
+		return true
+	}
+	if ann := jobj(p.m, "annotations"); ann != nil {
+		return len(jstrlist(ann, "required")) > 0
+	}
+	return false
+}
+
 func (p *Property) Pattern() (string, bool) {
 	if s, ok := p.m["pattern"].(string); ok {
 		return s, true
@@ -762,6 +1462,9 @@ func (p *Property) forcePointerType() bool {
 	if p.UnfortunateDefault() {
 		return true
 	}
+	if *pointers && p.Type().IsSimple() && p.Type().AsGo() != "interface{}" {
+		return true
+	}
 
 	name := fieldName{api: p.s.api.ID, schema: p.s.GoName(), field: p.GoName()}
 	for _, pf := range pointerFields {
@@ -791,9 +1494,12 @@ func (p *Property) UnfortunateDefault() bool {
 		pattern, hasPat := p.Pattern()
 		enum, hasEnum := p.Enum()
 		if hasPat && hasEnum {
-			log.Printf("Encountered enum property which also has a pattern: %#v", p)
+			p.s.api.warnf("Encountered enum property which also has a pattern: %s.%s", p.s.apiName, p.apiName)
 			return false // don't know how to handle this, so ignore.
 		}
+		if hasPat && !validPattern(pattern) {
+			p.s.api.warnf("Encountered bad pattern: %s", pattern)
+		}
 		return (hasPat && emptyPattern(pattern)) ||
 			(hasEnum && emptyEnum(enum))
 
@@ -814,10 +1520,15 @@ func emptyPattern(pattern string) bool {
 	if re, err := regexp.Compile(pattern); err == nil {
 		return re.MatchString("")
 	}
-	log.Printf("Encountered bad pattern: %s", pattern)
 	return false
 }
 
+// validPattern reports whether pattern is a valid regular expression.
+func validPattern(pattern string) bool {
+	_, err := regexp.Compile(pattern)
+	return err == nil
+}
+
 // emptyEnum reports whether a property enum list contains the empty string.
 func emptyEnum(enum []string) bool {
 	for _, val := range enum {
@@ -856,6 +1567,15 @@ func (t *Type) asSimpleGoType() (goType string, ok bool) {
 	return simpleTypeConvert(t.apiType(), t.apiTypeFormat())
 }
 
+// subject returns the discovery name of the schema or property of type t,
+// for the warnings file.
+func (t *Type) subject() string {
+	if name := jstr(t.m, "_apiName"); name != "" {
+		return name
+	}
+	return jstr(t.m, "id")
+}
+
 func (t *Type) String() string {
 	return fmt.Sprintf("[type=%q, map=%s]", t.apiType(), prettyJSON(t.m))
 }
@@ -889,6 +1609,17 @@ func (t *Type) AsGo() string {
 			panic(fmt.Sprintf("in Type.AsGo(), failed to find referenced type %q for %s",
 				ref, prettyJSON(t.m)))
 		}
+		// References to struct schemas become pointers to named types, so
+		// only references through arrays, maps and other references can
+		// lead back to ref without a named type in between.
+		if t.api.resolving[ref] {
+			panicf("schema %q refers to itself without an object in between, so it has no Go type", ref)
+		}
+		if t.api.resolving == nil {
+			t.api.resolving = make(map[string]bool)
+		}
+		t.api.resolving[ref] = true
+		defer delete(t.api.resolving, ref)
 		return s.Type().AsGo()
 	}
 	if typ, ok := t.MapType(); ok {
@@ -962,13 +1693,13 @@ func (t *Type) MapType() (typ string, ok bool) {
 		if s == "" { // Check for reference
 			s = jstr(props, "$ref")
 			if s != "" {
-				return "map[string]" + s, true
+				return "map[string]" + t.api.refGoName(s), true
 			}
 		}
 		if s == "any" {
 			return "map[string]interface{}", true
 		}
-		log.Printf("Warning: found map to type %q which is not implemented yet.", s)
+		t.api.skipf("map", t.subject(), "Warning: found map to type %q which is not implemented yet.", s)
 		return "", false
 	}
 	items := jobj(props, "items")
@@ -980,14 +1711,14 @@ func (t *Type) MapType() (typ string, ok bool) {
 		if s == "" { // Check for reference
 			s = jstr(items, "$ref")
 			if s != "" {
-				return "map[string][]" + s, true
+				return "map[string][]" + t.api.refGoName(s), true
 			}
 		}
 		if s == "any" {
 			return "map[string][]interface{}", true
 		}
 
-		log.Printf("Warning: found map of arrays of type %q which is not implemented yet.", s)
+		t.api.skipf("map", t.subject(), "Warning: found map of arrays of type %q which is not implemented yet.", s)
 		return "", false
 	}
 	return "map[string][]string", true
@@ -1005,8 +1736,7 @@ func (t *Type) ReferenceSchema() (s *Schema, ok bool) {
 
 	s = t.api.schemas[apiName]
 	if s == nil {
-		panicf("failed to find t.api.schemas[%q] while resolving reference",
-			apiName)
+		panicf("reference to undefined schema %q", apiName)
 	}
 	return s, true
 }
@@ -1036,7 +1766,7 @@ func (s *Schema) properties() []*Property {
 	pl := []*Property{}
 	propMap := jobj(s.m, "properties")
 	for _, name := range sortedKeys(propMap) {
-		m := propMap[name].(map[string]interface{})
+		m := asObject(propMap[name], "property %q of schema %q", name, s.apiName)
 		pl = append(pl, &Property{
 			s:       s,
 			m:       m,
@@ -1147,7 +1877,9 @@ func (s *Schema) GoName() string {
 			s.goName = name
 		} else {
 			base := initialCap(s.apiName)
-			if s.api.Name == "appengine" && s.apiName == "Service" {
+			if so := s.api.schemaOverride(s.apiName); so != nil && so.GoName != "" {
+				base = so.GoName
+			} else if s.api.Name == "appengine" && s.apiName == "Service" {
 				// Avoid getting "Service1".
 				base = "Module"
 			}
@@ -1183,11 +1915,14 @@ func (s *Schema) writeSchemaCode(api *API) {
 	}
 
 	if _, ok := s.Type().ArrayType(); ok {
-		log.Printf("TODO writeSchemaCode for arrays for %s", s.GoName())
+		s.api.skipf("array", s.apiName, "TODO writeSchemaCode for arrays for %s", s.GoName())
 		return
 	}
 
 	if destSchema, ok := s.Type().ReferenceSchema(); ok {
+		if s.referencesItself() {
+			panicf("schema %q refers to itself without an object in between, so it has no Go type", s.apiName)
+		}
 		// Convert it to a struct using embedding.
 		s.api.pn("\ntype %s struct {", s.GoName())
 		s.api.pn(" %s", destSchema.GoName())
@@ -1215,7 +1950,7 @@ func (s *Schema) writeVariant(api *API, v map[string]interface{}) {
 
 	// Write out the "Type" method that identifies the variant type.
 	s.api.pn("func (t %s) Type() string {", s.GoName())
-	s.api.pn("  return googleapi.VariantType(t)")
+	s.api.pn("  return gensupport.VariantType(t)")
 	s.api.p("}\n\n")
 
 	// Write out helper methods to convert each possible variant.
@@ -1223,21 +1958,21 @@ func (s *Schema) writeVariant(api *API, v map[string]interface{}) {
 		val := jstr(m, "type_value")
 		reftype := jstr(m, "$ref")
 		if val == "" && reftype == "" {
-			log.Printf("TODO variant %s ref %s not yet supported.", val, reftype)
+			s.api.skipf("variant", s.apiName, "TODO variant %s ref %s not yet supported.", val, reftype)
 			continue
 		}
 
 		_, ok := api.schemas[reftype]
 		if !ok {
-			log.Printf("TODO variant %s ref %s not yet supported.", val, reftype)
+			s.api.skipf("variant", s.apiName, "TODO variant %s ref %s not yet supported.", val, reftype)
 			continue
 		}
 
-		s.api.pn("func (t %s) %s() (r %s, ok bool) {", s.GoName(), initialCap(val), reftype)
+		s.api.pn("func (t %s) %s() (r %s, ok bool) {", s.GoName(), initialCap(val), api.refGoName(reftype))
 		s.api.pn(" if t.Type() != %q {", initialCap(val))
 		s.api.pn("  return r, false")
 		s.api.pn(" }")
-		s.api.pn(" ok = googleapi.ConvertVariant(map[string]interface{}(t), &r)")
+		s.api.pn(" ok = gensupport.ConvertVariant(map[string]interface{}(t), &r)")
 		s.api.pn(" return r, ok")
 		s.api.p("}\n\n")
 	}
@@ -1266,11 +2001,19 @@ func (s *Schema) writeSchemaStruct(api *API) {
 	}
 
 	firstFieldName := "" // used to store a struct field name for use in documentation.
+	var fields, required, converted []schemaField
+	var enums []enumField
 	for i, p := range s.properties() {
 		if i > 0 {
 			s.api.p("\n")
 		}
 		pname := np.Get(p.GoName())
+		if d := droppedRunes(p.APIName()); d != "" {
+			s.api.lossf("identifier", s.apiName+"."+p.APIName(), "characters %q dropped from Go name %s", d, pname)
+		}
+		if t := p.Type(); t.IsSimple() && !knownFormat(t.apiType(), t.apiTypeFormat()) {
+			s.api.lossf("format", s.apiName+"."+p.APIName(), "unknown format %q of %s; using %s", t.apiTypeFormat(), t.apiType(), t.AsGo())
+		}
 		des := p.Description()
 		if des != "" {
 			s.api.p("%s", asComment("\t", fmt.Sprintf("%s: %s", pname, des)))
@@ -1286,8 +2029,40 @@ func (s *Schema) writeSchemaStruct(api *API) {
 		if p.forcePointerType() {
 			typ = "*" + typ
 		}
+		to := p.typeOverride()
+		if to != nil {
+			if to.hasHelpers() {
+				converted = append(converted, schemaField{
+					field:   pname,
+					typ:     p.Type().AsGo(),
+					apiName: p.APIName(),
+					conv:    to,
+					convOpt: extraOpt,
+				})
+			}
+			typ, extraOpt = to.GoType, ""
+			s.api.addImport(to.Import)
+			s.api.addImport(to.HelperImport)
+		}
 
 		s.api.pn(" %s %s `json:\"%s,omitempty%s\"`", pname, typ, p.APIName(), extraOpt)
+		f := schemaField{field: pname, typ: typ, apiName: p.APIName()}
+		if sub, ok := p.structSchema(); ok && to == nil {
+			f.sub = sub
+		}
+		if elem, ok := p.elemStructSchema(); ok && to == nil && typ == "[]*"+elem.GoName() {
+			f.elem = elem
+		}
+		if enum, ok := p.validatedEnum(); ok {
+			f.enum = enum
+		}
+		fields = append(fields, f)
+		if enum, ok := p.Enum(); ok && to == nil && (p.Type().apiType() == "string" || p.Type().apiType() == "array") {
+			enums = append(enums, enumField{field: pname, values: enum, descs: p.EnumDescriptions()})
+		}
+		if p.IsRequired() {
+			required = append(required, f)
+		}
 		if firstFieldName == "" {
 			firstFieldName = pname
 		}
@@ -1305,6 +2080,9 @@ func (s *Schema) writeSchemaStruct(api *API) {
 		// There were no fields in the struct, so there is no point
 		// adding any custom JSON marshaling code.
 		s.api.pn("}")
+		if s.builderName != "" {
+			s.writeSchemaBuilder("", nil)
+		}
 		return
 	}
 
@@ -1321,10 +2099,248 @@ func (s *Schema) writeSchemaStruct(api *API) {
 
 	s.api.pn("\t%s []string `json:\"-\"`", forceSendName)
 	s.api.pn("}")
-	s.writeSchemaMarshal(forceSendName)
+	if len(converted) > 0 {
+		s.writeSchemaConvertingMarshal(forceSendName, converted)
+	} else {
+		s.writeSchemaMarshal(forceSendName)
+	}
+	if s.api.requestTypes[s.apiName] && len(required) > 0 {
+		s.writeSchemaConstructor(forceSendName, required)
+	}
+	for _, e := range enums {
+		s.writeEnumConstants(e)
+	}
+	if s.api.validated[s.apiName] {
+		s.writeSchemaValidate(fields)
+	}
+	if s.api.pageTypes[s.apiName] {
+		s.writeGetNextPageToken()
+	}
+	s.writeLinkFetchers(fields)
+	if s.builderName != "" {
+		s.writeSchemaBuilder(forceSendName, fields)
+	}
 	return
 }
 
+// linkFetchers maps the names of fields which are known to hold
+// fetchable URLs to the names of the methods which fetch them.
+var linkFetchers = map[string]string{
+	"downloadUrl": "Download",
+	"exportLinks": "Export",
+	"selfLink":    "FetchSelfLink",
+}
+
+// writeLinkFetchers writes a method for each of fields which holds a URL
+// named in linkFetchers, unless the name of the method is taken by a
+// field. exportLinks, which maps MIME types to URLs, is fetched by MIME
+// type.
+func (s *Schema) writeLinkFetchers(fields []schemaField) {
+	taken := make(map[string]bool)
+	for _, f := range fields {
+		taken[f.field] = true
+	}
+	pn := s.api.pn
+	for _, f := range fields {
+		name, ok := linkFetchers[f.apiName]
+		if !ok || taken[name] {
+			continue
+		}
+		switch {
+		case f.apiName == "exportLinks" && f.typ == "map[string]string":
+			pn("\n// %s fetches the export of s to the MIME type mimeType, whose URL", name)
+			pn("// is held in s.%s. client must be authorized as the service's is.", f.field)
+			pn("// The caller must close the body of the response.")
+			pn("func (s *%s) %s(ctx context.Context, client *http.Client, mimeType string) (*http.Response, error) {", s.GoName(), name)
+			pn(` return gensupport.FetchLink(ctx, client, "%s["+mimeType+"]", s.%s[mimeType])`, f.apiName, f.field)
+			pn("}")
+		case f.apiName != "exportLinks" && f.typ == "string":
+			pn("\n// %s fetches the URL held in s.%s. client must be authorized as", name, f.field)
+			pn("// the service's is. The caller must close the body of the response.")
+			pn("func (s *%s) %s(ctx context.Context, client *http.Client) (*http.Response, error) {", s.GoName(), name)
+			pn(" return gensupport.FetchLink(ctx, client, %q, s.%s)", f.apiName, f.field)
+			pn("}")
+		}
+	}
+}
+
+// enumField describes a string field of a schema struct, or a slice of
+// strings, whose values are limited to an enum.
+type enumField struct {
+	field  string   // Go name of the struct field
+	values []string // permitted values
+	descs  []string // descriptions of values, if any
+}
+
+// writeGetNextPageToken writes a GetNextPageToken method for s, so that
+// it implements googleapi.PageResponse.
+func (s *Schema) writeGetNextPageToken() {
+	prop, _ := s.nextPageToken()
+	name := prop.GoName()
+	pn := s.api.pn
+	pn("\n// GetNextPageToken returns %s, or \"\" if s is the last page.", name)
+	pn("// It implements googleapi.PageResponse.")
+	pn("func (s *%s) GetNextPageToken() string {", s.GoName())
+	if prop.forcePointerType() {
+		pn(" if s.%s == nil {", name)
+		pn(`  return ""`)
+		pn(" }")
+		pn(" return *s.%s", name)
+	} else {
+		pn(" return s.%s", name)
+	}
+	pn("}")
+}
+
+// writeSchemaValidate writes a Validate method for s, which checks the
+// enum fields among fields, and calls Validate on the fields holding
+// structs which have the method.
+func (s *Schema) writeSchemaValidate(fields []schemaField) {
+	pn := s.api.pn
+	pn("\n// Validate returns a *googleapi.EnumError if an enum field of s, or of")
+	pn("// a struct it holds, has a value which the API does not accept.")
+	pn("// Empty fields are accepted.")
+	pn("func (s *%s) Validate() error {", s.GoName())
+	pn(" if s == nil {")
+	pn("  return nil")
+	pn(" }")
+	for _, f := range fields {
+		name := s.GoName() + "." + f.field
+		switch {
+		case f.enum != nil && f.typ == "*string":
+			pn(" if s.%s != nil {", f.field)
+			pn("  if err := gensupport.CheckEnum(%q, %s, *s.%s); err != nil {", name, goStringSlice(f.enum), f.field)
+			pn("   return err")
+			pn("  }")
+			pn(" }")
+		case f.enum != nil && f.typ == "[]string":
+			pn(" if err := gensupport.CheckEnum(%q, %s, s.%s...); err != nil {", name, goStringSlice(f.enum), f.field)
+			pn("  return err")
+			pn(" }")
+		case f.enum != nil:
+			pn(" if err := gensupport.CheckEnum(%q, %s, s.%s); err != nil {", name, goStringSlice(f.enum), f.field)
+			pn("  return err")
+			pn(" }")
+		case f.sub != nil && s.api.validated[f.sub.apiName]:
+			pn(" if err := s.%s.Validate(); err != nil {", f.field)
+			pn("  return err")
+			pn(" }")
+		case f.elem != nil && s.api.validated[f.elem.apiName]:
+			pn(" for _, v := range s.%s {", f.field)
+			pn("  if err := v.Validate(); err != nil {")
+			pn("   return err")
+			pn("  }")
+			pn(" }")
+		}
+	}
+	pn(" return nil")
+	pn("}")
+}
+
+// goStringSlice returns a Go expression for a []string holding list.
+func goStringSlice(list []string) string {
+	q := make([]string, len(list))
+	for i, v := range list {
+		q[i] = strconv.Quote(v)
+	}
+	return "[]string{" + strings.Join(q, ", ") + "}"
+}
+
+// writeEnumConstants writes a constant for each non-empty value permitted
+// in e, named after s, the field and the value.
+func (s *Schema) writeEnumConstants(e enumField) {
+	if len(e.values) == 0 || len(e.values) == 1 && e.values[0] == "" {
+		return
+	}
+	s.api.pn("\n// Possible values of %s.%s.", s.GoName(), e.field)
+	s.api.pn("const (")
+	for i, v := range e.values {
+		if v == "" {
+			continue
+		}
+		name := s.api.GetName(s.GoName() + e.field + enumConstName(v))
+		if i < len(e.descs) && e.descs[i] != "" {
+			s.api.p("%s", asComment("\t", fmt.Sprintf("%s: %s", name, e.descs[i])))
+		}
+		s.api.pn("\t%s = %q", name, v)
+	}
+	s.api.pn(")")
+}
+
+// enumConstName returns the suffix of the name of the constant for the
+// enum value v. Words in upper case, as in "TYPE_UNSPECIFIED", are
+// converted to mixed case, as in "TypeUnspecified".
+func enumConstName(v string) string {
+	words := strings.FieldsFunc(v, func(r rune) bool {
+		return r == '_' || !isIdentRune(r)
+	})
+	var buf bytes.Buffer
+	for _, w := range words {
+		if strings.ToUpper(w) == w {
+			w = strings.ToLower(w)
+		}
+		buf.WriteString(initialCap(w))
+	}
+	return buf.String()
+}
+
+// schemaField describes a field of a schema struct.
+type schemaField struct {
+	field   string   // Go name of the struct field
+	typ     string   // Go type of the struct field
+	apiName string   // API name of the property
+	sub     *Schema  // schema of the field's struct type, if it is a struct pointer
+	elem    *Schema  // schema of the field's element type, if it is a slice of struct pointers
+	enum    []string // values permitted in the field, if it is validated
+
+	// conv, if non-nil, holds the helpers which convert the field to and
+	// from its JSON form. typ is then the type the helpers convert to, and
+	// convOpt holds any extra JSON tag options for it (e.g. ",string").
+	conv    *typeOverride
+	convOpt string
+}
+
+// isScalarPointer reports whether f is a scalar represented as a pointer
+// so that it may be explicitly set to its zero value.
+func (f schemaField) isScalarPointer() bool {
+	return strings.HasPrefix(f.typ, "*") && f.sub == nil
+}
+
+// writeSchemaConstructor writes a NewFoo function for s which takes the
+// required fields of s as arguments. The required fields are listed in
+// the field identified by forceSendFieldName, so that they are sent even
+// when given their zero values.
+func (s *Schema) writeSchemaConstructor(forceSendFieldName string, required []schemaField) {
+	name := s.api.GetName("New" + s.GoName())
+	np := new(namePool)
+	var params, inits []string
+	var fields, quoted []string
+	for _, f := range required {
+		arg := np.Get(validGoIdentifer(f.apiName))
+		if f.isScalarPointer() {
+			params = append(params, fmt.Sprintf("%s %s", arg, f.typ[1:]))
+			inits = append(inits, fmt.Sprintf("%s: &%s,", f.field, arg))
+		} else {
+			params = append(params, fmt.Sprintf("%s %s", arg, f.typ))
+			inits = append(inits, fmt.Sprintf("%s: %s,", f.field, arg))
+		}
+		fields = append(fields, f.field)
+		quoted = append(quoted, fmt.Sprintf("%q", f.field))
+	}
+	s.api.p("\n")
+	s.api.p("%s", asComment("", fmt.Sprintf("%s returns a %s with its required fields (%s) set. "+
+		"Optional fields may be set on the result before it is used in a request.",
+		name, s.GoName(), strings.Join(fields, ", "))))
+	s.api.pn("func %s(%s) *%s {", name, strings.Join(params, ", "), s.GoName())
+	s.api.pn(" return &%s{", s.GoName())
+	for _, init := range inits {
+		s.api.pn("  %s", init)
+	}
+	s.api.pn("  %s: []string{%s},", forceSendFieldName, strings.Join(quoted, ", "))
+	s.api.pn(" }")
+	s.api.pn("}")
+}
+
 // writeSchemaMarshal writes a custom MarshalJSON function for s, which allows
 // fields to be explicitly transmitted by listing them in the field identified
 // by forceSendFieldName.
@@ -1337,6 +2353,373 @@ func (s *Schema) writeSchemaMarshal(forceSendFieldName string) {
 	s.api.pn("}")
 }
 
+// writeSchemaConvertingMarshal writes MarshalJSON and UnmarshalJSON
+// functions for s which use the helpers of each of the converted fields
+// to translate them to and from their JSON form. The other fields are
+// handled as by writeSchemaMarshal.
+func (s *Schema) writeSchemaConvertingMarshal(forceSendFieldName string, converted []schemaField) {
+	p, pn := s.api.p, s.api.pn
+	pn("func (s *%s) MarshalJSON() ([]byte, error) {", s.GoName())
+	pn("\ttype noMethod %s", s.GoName())
+	// The fields of raw shadow the converted fields of the embedded noMethod.
+	pn("\traw := struct {")
+	pn("\t\tnoMethod")
+	for _, f := range converted {
+		pn("\t\t%s %s `json:\"%s,omitempty%s\"`", f.field, f.typ, f.apiName, f.convOpt)
+	}
+	pn("\t}{noMethod: noMethod(*s)}")
+	pn("\tvar err error")
+	for _, f := range converted {
+		pn("\tif raw.%s, err = %s(s.%s); err != nil {", f.field, f.conv.Marshal, f.field)
+		pn("\t\treturn nil, err")
+		pn("\t}")
+	}
+	pn("\treturn gensupport.MarshalJSON(raw, s.%s)", forceSendFieldName)
+	pn("}")
+	p("\n")
+	pn("func (s *%s) UnmarshalJSON(data []byte) error {", s.GoName())
+	pn("\ttype noMethod %s", s.GoName())
+	pn("\tvar raw struct {")
+	pn("\t\t*noMethod")
+	for _, f := range converted {
+		pn("\t\t%s *%s `json:\"%s%s\"`", f.field, strings.TrimPrefix(f.typ, "*"), f.apiName, f.convOpt)
+	}
+	pn("\t}")
+	pn("\traw.noMethod = (*noMethod)(s)")
+	pn("\tif err := json.Unmarshal(data, &raw); err != nil {")
+	pn("\t\treturn err")
+	pn("\t}")
+	for _, f := range converted {
+		pn("\tif raw.%s != nil {", f.field)
+		pn("\t\tv, err := %s(*raw.%s)", f.conv.Unmarshal, f.field)
+		pn("\t\tif err != nil {")
+		pn("\t\t\treturn err")
+		pn("\t\t}")
+		pn("\t\ts.%s = v", f.field)
+		pn("\t}")
+	}
+	pn("\treturn nil")
+	pn("}")
+}
+
+// writeSchemaBuilder writes a builder type for s with one chainable
+// method per field. Fields whose type has its own builder are set through
+// a function which receives that builder. The other methods add their
+// field to the one identified by forceSendFieldName, so that a field set
+// to its zero value is still sent.
+func (s *Schema) writeSchemaBuilder(forceSendFieldName string, fields []schemaField) {
+	p, pn := s.api.p, s.api.pn
+	bn, typ := s.builderName, s.GoName()
+	p("\n")
+	p("%s", asComment("", fmt.Sprintf("%s builds a %s through chained method calls.", bn, typ)))
+	pn("type %s struct {", bn)
+	pn(" v *%s", typ)
+	pn("}")
+	p("\n")
+	p("%s", asComment("", fmt.Sprintf("New%s returns a builder for a new, empty %s.", bn, typ)))
+	pn("func New%s() *%s {", bn, bn)
+	pn(" return &%s{v: new(%s)}", bn, typ)
+	pn("}")
+	p("\n")
+	p("%s", asComment("", fmt.Sprintf("Build returns the %s built by b.", typ)))
+	pn("func (b *%s) Build() *%s {", bn, typ)
+	pn(" return b.v")
+	pn("}")
+
+	np := new(namePool)
+	np.Get("Build") // reserve the name
+	for _, f := range fields {
+		meth := np.Get(f.field)
+		p("\n")
+		if f.sub != nil && f.sub.builderName != "" {
+			p("%s", asComment("", fmt.Sprintf("%s calls fn with a builder for the %s field, "+
+				"allocating the field first if it is nil.", meth, f.field)))
+			pn("func (b *%s) %s(fn func(*%s)) *%s {", bn, meth, f.sub.builderName, bn)
+			pn(" if b.v.%s == nil {", f.field)
+			pn("  b.v.%s = new(%s)", f.field, f.sub.GoName())
+			pn(" }")
+			pn(" fn(&%s{v: b.v.%s})", f.sub.builderName, f.field)
+			pn(" return b")
+			pn("}")
+			continue
+		}
+		p("%s", asComment("", fmt.Sprintf("%s sets the %s field, which is sent even if v is "+
+			"its zero value.", meth, f.field)))
+		if f.isScalarPointer() {
+			pn("func (b *%s) %s(v %s) *%s {", bn, meth, f.typ[1:], bn)
+			pn(" b.v.%s = &v", f.field)
+		} else {
+			pn("func (b *%s) %s(v %s) *%s {", bn, meth, f.typ, bn)
+			pn(" b.v.%s = v", f.field)
+		}
+		pn(" b.v.%s = append(b.v.%s, %q)", forceSendFieldName, forceSendFieldName, f.field)
+		pn(" return b")
+		pn("}")
+	}
+}
+
+// structSchema returns the schema of p's type if p is represented as a
+// pointer to a generated struct.
+func (p *Property) structSchema() (*Schema, bool) {
+	return p.Type().structSchema()
+}
+
+// elemStructSchema returns the schema of the elements of p's type if p is
+// represented as a slice of pointers to a generated struct.
+func (p *Property) elemStructSchema() (*Schema, bool) {
+	at, ok := p.Type().ArrayType()
+	if !ok {
+		return nil, false
+	}
+	return at.structSchema()
+}
+
+// structSchema returns the schema of t if t is represented as a pointer
+// to a generated struct.
+func (t *Type) structSchema() (*Schema, bool) {
+	var s *Schema
+	if ref, ok := t.ReferenceSchema(); ok {
+		s = ref
+	} else if apiName, ok := t.m["_apiName"].(string); ok {
+		s = t.api.schemas[apiName]
+	}
+	if s == nil || !s.Type().IsStruct() || s.Type().IsMap() || s.Type().IsAny() || jobj(s.m, "variant") != nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// validatedEnum returns the values permitted for p if p is a string, or
+// a slice of strings, limited to an enum.
+func (p *Property) validatedEnum() ([]string, bool) {
+	enum, ok := p.Enum()
+	if !ok || p.typeOverride() != nil {
+		return nil, false
+	}
+	if typ := p.Type().AsGo(); typ != "string" && typ != "[]string" {
+		return nil, false
+	}
+	return enum, true
+}
+
+// computeValidation chooses the schemas which get a Validate method:
+// those with enum fields, along with those holding them in struct fields
+// or slices of structs.
+func (a *API) computeValidation() {
+	a.validated = make(map[string]bool)
+	canValidate := func(s *Schema) bool {
+		if !s.Type().IsStruct() || s.Type().IsMap() || s.Type().IsAny() || jobj(s.m, "variant") != nil {
+			return false
+		}
+		for _, p := range s.properties() {
+			if p.GoName() == "Validate" {
+				return false
+			}
+		}
+		return true
+	}
+	needsValidate := func(s *Schema) bool {
+		for _, p := range s.properties() {
+			if p.typeOverride() != nil {
+				continue
+			}
+			if _, ok := p.validatedEnum(); ok {
+				return true
+			}
+			if sub, ok := p.structSchema(); ok && a.validated[sub.apiName] {
+				return true
+			}
+			if sub, ok := p.elemStructSchema(); ok && a.validated[sub.apiName] {
+				return true
+			}
+		}
+		return false
+	}
+	// Repeat until no more schemas are added, since schemas may contain
+	// each other.
+	for changed := true; changed; {
+		changed = false
+		for _, name := range a.sortedSchemaNames() {
+			s := a.schemas[name]
+			if !a.validated[name] && canValidate(s) && needsValidate(s) {
+				a.validated[name] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// computeBuilders chooses the schemas which get builder types: those nested
+// at least 3 levels deep, along with every struct schema reachable from them
+// so that nested fields can be built in the same chain.
+func (a *API) computeBuilders() {
+	names := a.sortedSchemaNames()
+	// Assign schema names before any builder names, so that enabling
+	// builders never renames a schema.
+	for _, name := range names {
+		a.schemas[name].GoName()
+	}
+	a.builderDepth = make(map[string]int)
+	var mark func(s *Schema)
+	mark = func(s *Schema) {
+		if s.builderName != "" {
+			return
+		}
+		s.builderName = a.GetName(s.GoName() + "Builder")
+		a.GetName("New" + s.builderName) // ignore return value; reserved for the constructor
+		for _, p := range s.properties() {
+			if sub, ok := p.structSchema(); ok {
+				mark(sub)
+			}
+		}
+	}
+	for _, name := range names {
+		s := a.schemas[name]
+		if !s.Type().IsStruct() || s.Type().IsMap() || jobj(s.m, "variant") != nil {
+			continue
+		}
+		if a.schemaDepth(s) >= 3 {
+			mark(s)
+		}
+	}
+}
+
+// schemaDepth returns the number of levels of struct nesting in s,
+// counting s itself. Recursive schemas count each schema once.
+func (a *API) schemaDepth(s *Schema) int {
+	d, _ := a.schemaDepthFrom(s, make(map[string]bool))
+	return d
+}
+
+// schemaDepthFrom does the work of schemaDepth. stack holds the schemas
+// being measured. A schema nested in itself ends the nesting there, so
+// the depth of a schema which reaches a cycle depends on where the cycle
+// was entered; only the depths of the others are saved in a.builderDepth.
+// schemaDepthFrom also reports whether s reaches a cycle.
+func (a *API) schemaDepthFrom(s *Schema, stack map[string]bool) (depth int, cyclic bool) {
+	if d, ok := a.builderDepth[s.apiName]; ok {
+		return d, false
+	}
+	if stack[s.apiName] {
+		return 0, true
+	}
+	stack[s.apiName] = true
+	max := 0
+	for _, p := range s.properties() {
+		if sub, ok := p.structSchema(); ok {
+			d, c := a.schemaDepthFrom(sub, stack)
+			if d > max {
+				max = d
+			}
+			cyclic = cyclic || c
+		}
+	}
+	delete(stack, s.apiName)
+	if !cyclic {
+		a.builderDepth[s.apiName] = 1 + max
+	}
+	return 1 + max, cyclic
+}
+
+// referencesItself reports whether the chain of references starting at s,
+// a schema which is only a reference, loops without reaching a schema
+// which is not a reference.
+func (s *Schema) referencesItself() bool {
+	seen := map[string]bool{s.apiName: true}
+	for t := s.Type(); ; {
+		ref, ok := t.Reference()
+		if !ok {
+			return false
+		}
+		if seen[ref] {
+			return true
+		}
+		seen[ref] = true
+		dest, ok := t.ReferenceSchema()
+		if !ok {
+			return false
+		}
+		t = dest.Type()
+	}
+}
+
+// kind returns the fixed value of the "kind" property of s, such as
+// "drive#file", if it has one.
+func (s *Schema) kind() (string, bool) {
+	if !s.Type().IsStruct() || s.Type().IsMap() || jobj(s.m, "variant") != nil {
+		return "", false
+	}
+	for _, p := range s.properties() {
+		if p.APIName() == "kind" && p.Type().apiType() == "string" {
+			k := jstr(p.m, "default")
+			return k, k != ""
+		}
+	}
+	return "", false
+}
+
+// writeKinds writes a constant for the kind of each schema which has a
+// fixed one, and KindTypes and DecodeKind, which map kinds to Go types.
+func (a *API) writeKinds() {
+	type schemaKind struct {
+		s     *Schema
+		kind  string
+		cname string
+	}
+	var kinds []schemaKind
+	count := make(map[string]int)
+	for _, name := range a.sortedSchemaNames() {
+		s := a.schemas[name]
+		if k, ok := s.kind(); ok {
+			kinds = append(kinds, schemaKind{s: s, kind: k})
+			count[k]++
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+	pn := a.pn
+	pn("\n// Kinds of the resources of this API, from their \"kind\" fields.")
+	pn("const (")
+	for i := range kinds {
+		k := &kinds[i]
+		k.cname = a.GetName(k.s.GoName() + "Kind")
+		pn("%s = %q", k.cname, k.kind)
+	}
+	pn(")")
+
+	typesName := a.GetName("KindTypes")
+	decodeName := a.GetName("DecodeKind")
+	pn("\n// %s maps each kind to a function returning a new value of the", typesName)
+	pn("// type of resources of that kind. Kinds shared by several types are omitted.")
+	pn("var %s = map[string]func() interface{}{", typesName)
+	for _, k := range kinds {
+		if count[k.kind] == 1 {
+			pn("%s: func() interface{} { return new(%s) },", k.cname, k.s.GoName())
+		}
+	}
+	pn("}")
+
+	pn("\n// %s decodes the JSON object data into a new value of the type", decodeName)
+	pn("// given by its \"kind\" field in %s, returning a pointer to it. This", typesName)
+	pn("// helps to decode collections holding resources of several types.")
+	pn("func %s(data []byte) (interface{}, error) {", decodeName)
+	pn(" k, err := googleapi.KindOf(data)")
+	pn(" if err != nil {")
+	pn("  return nil, err")
+	pn(" }")
+	pn(" newValue, ok := %s[k]", typesName)
+	pn(" if !ok {")
+	pn(`  return nil, fmt.Errorf("%%s: unknown kind %%q", apiId, k)`)
+	pn(" }")
+	pn(" v := newValue()")
+	pn(" if err := json.Unmarshal(data, v); err != nil {")
+	pn("  return nil, err")
+	pn(" }")
+	pn(" return v, nil")
+	pn("}")
+}
+
 // isResponseType returns true for all types that are used as a response.
 func (s *Schema) isResponseType() bool {
 	return s.api.responseTypes["*"+s.goName]
@@ -1352,17 +2735,21 @@ func (s *Schema) isResponseType() bool {
 //
 // A resource "Foo" of type "array" with an "items" of type "object"
 // will get a synthetic API name of "Foo.Item".
-func (a *API) PopulateSchemas() {
+func (a *API) PopulateSchemas() error {
 	m := jobj(a.m, "schemas")
 	if a.schemas != nil {
 		panic("")
 	}
 	a.schemas = make(map[string]*Schema)
 	for name, mi := range m {
+		sm, ok := mi.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("schema %q is not a JSON object", name)
+		}
 		s := &Schema{
 			api:     a,
 			apiName: name,
-			m:       mi.(map[string]interface{}),
+			m:       sm,
 		}
 
 		// And a little gross hack, so a map alone is good
@@ -1370,11 +2757,11 @@ func (a *API) PopulateSchemas() {
 		s.m["_apiName"] = name
 
 		a.schemas[name] = s
-		err := s.populateSubSchemas()
-		if err != nil {
-			panicf("Error populating schema with API name %q: %v", name, err)
+		if err := s.populateSubSchemas(); err != nil {
+			return fmt.Errorf("Error populating schema with API name %q: %v", name, err)
 		}
 	}
+	return nil
 }
 
 type Resource struct {
@@ -1383,11 +2770,15 @@ type Resource struct {
 	parent    string
 	m         map[string]interface{}
 	resources []*Resource
+
+	iface string   // name of the resource's interface, if -interfaces is set
+	calls []string // signatures of the resource's call constructors, for iface
 }
 
 func (r *Resource) generateType() {
 	pn := r.api.pn
 	t := r.GoType()
+	r.api.GetName("New" + t) // ignore return value; reserved so schema constructors don't collide
 	pn(fmt.Sprintf("func New%s(s *Service) *%s {", t, t))
 	pn("rs := &%s{s : s}", t)
 	for _, res := range r.resources {
@@ -1399,7 +2790,7 @@ func (r *Resource) generateType() {
 	pn("\ntype %s struct {", t)
 	pn(" s *Service")
 	for _, res := range r.resources {
-		pn("\n\t%s\t*%s", res.GoField(), res.GoType())
+		pn("\n\t%s\t%s", res.GoField(), res.fieldType())
 	}
 	pn("}")
 
@@ -1408,12 +2799,12 @@ func (r *Resource) generateType() {
 	}
 }
 
-func (r *Resource) cacheResponseTypes(api *API) {
+func (r *Resource) cacheTypes(api *API) {
 	for _, meth := range r.Methods() {
-		meth.cacheResponseTypes(api)
+		meth.cacheTypes(api)
 	}
 	for _, res := range r.resources {
-		res.cacheResponseTypes(api)
+		res.cacheTypes(api)
 	}
 }
 
@@ -1421,11 +2812,58 @@ func (r *Resource) generateMethods() {
 	for _, meth := range r.Methods() {
 		meth.generateCode()
 	}
+	if r.iface != "" {
+		r.generateInterface()
+	}
 	for _, res := range r.resources {
 		res.generateMethods()
 	}
 }
 
+// nameInterfaces sets the names of the interfaces of r and its sub-resources.
+func (r *Resource) nameInterfaces() {
+	r.iface = r.api.GetName(r.GoType() + "Interface")
+	for _, res := range r.resources {
+		res.nameInterfaces()
+	}
+}
+
+// fieldType returns the type of the field holding r in its parent.
+func (r *Resource) fieldType() string {
+	if r.iface != "" {
+		return r.iface
+	}
+	return "*" + r.GoType()
+}
+
+// generateInterface writes the interface implemented by r's type. Since
+// interfaces cannot have fields, r's sub-resources are reached through
+// methods, which are written here too.
+func (r *Resource) generateInterface() {
+	p, pn := r.api.p, r.api.pn
+	t := r.GoType()
+	var accessors []string
+	for _, res := range r.resources {
+		name := res.GoField() + "Service"
+		accessors = append(accessors, fmt.Sprintf("%s() %s", name, res.iface))
+		p("\n%s", asComment("", fmt.Sprintf("%s returns the %s field of r.", name, res.GoField())))
+		pn("func (r *%s) %s() %s {", t, name, res.iface)
+		pn(" return r.%s", res.GoField())
+		pn("}")
+	}
+	p("\n%s", asComment("", fmt.Sprintf("%s is implemented by *%s. It is used as the type of the field "+
+		"holding the resource so that calls can be decorated, for example with caching or metrics, "+
+		"by embedding it in another type.", r.iface, t)))
+	pn("type %s interface {", r.iface)
+	for _, sig := range r.calls {
+		pn(" %s", sig)
+	}
+	for _, sig := range accessors {
+		pn(" %s", sig)
+	}
+	pn("}")
+}
+
 func (r *Resource) GoField() string {
 	return initialCap(r.name)
 }
@@ -1444,7 +2882,7 @@ func (r *Resource) Methods() []*Method {
 			api:  r.api,
 			r:    r,
 			name: mname,
-			m:    mi.(map[string]interface{}),
+			m:    asObject(mi, "method %q", mname),
 		})
 	}
 	return ms
@@ -1463,6 +2901,17 @@ func (m *Method) Id() string {
 	return jstr(m.m, "id")
 }
 
+// isIdempotent reports whether calling m more than once has the same
+// effect as calling it once. Discovery documents do not say, so this is
+// judged from the HTTP method; media uploads are never idempotent.
+func (m *Method) isIdempotent() bool {
+	switch jstr(m.m, "httpMethod") {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return !m.supportsMediaUpload()
+	}
+	return false
+}
+
 func (m *Method) responseType() *Schema {
 	ref := jstr(jobj(m.m, "response"), "$ref")
 	return m.api.schemas[ref]
@@ -1489,30 +2938,45 @@ func (m *Method) supportsMediaDownload() bool {
 	return false
 }
 
-func (m *Method) supportsPaging() (callField, respField string, ok bool) {
+// hasBody reports whether requests for m may carry a body.
+func (m *Method) hasBody() bool {
+	if m.supportsMediaUpload() {
+		return true
+	}
+	return jobj(m.m, "request") != nil && jstr(m.m, "httpMethod") != "GET"
+}
+
+func (m *Method) supportsPaging() (callField string, respField *Property, ok bool) {
 	if jstr(m.m, "httpMethod") != "GET" {
 		// Probably a POST, like "calendar.acl.watch",
 		// which, despite having a pageToken parameter,
 		// isn't actually a paged method.
-		return "", "", false
+		return "", nil, false
 	}
 	if pt := jobj(jobj(m.m, "parameters"), "pageToken"); pt == nil {
-		return "", "", false
+		return "", nil, false
 	} else if jbool(pt, "required") {
 		// The page token is a required parameter (e.g. because there is
 		// a separate API call to start an iteration), and so the relevant
 		// call factory method takes the page token instead.
-		return "", "", false
+		return "", nil, false
 	}
 
 	// Check that the response type has the next page token.
-	// It may appear under different names.
 	s := m.responseType()
 	if s == nil || !s.Type().IsStruct() {
-		return "", "", false
+		return "", nil, false
 	}
-	props := s.properties()
+	if prop, ok := s.nextPageToken(); ok {
+		return "PageToken", prop, true
+	}
+	return "", nil, false
+}
 
+// nextPageToken returns the property of s holding the token of the next
+// page of results. It may appear under different names.
+func (s *Schema) nextPageToken() (*Property, bool) {
+	props := s.properties()
 	opts := [...]string{
 		"nextPageToken",
 		"pageToken",
@@ -1520,12 +2984,33 @@ func (m *Method) supportsPaging() (callField, respField string, ok bool) {
 	for _, n := range opts {
 		for _, prop := range props {
 			if prop.apiName == n && prop.Type().apiType() == "string" {
-				return "PageToken", prop.GoName(), true
+				return prop, true
 			}
 		}
 	}
+	return nil, false
+}
 
-	return "", "", false
+// userProjectParam is the parameter naming the project billed for a
+// call, as for Cloud Storage's requester-pays buckets.
+const userProjectParam = "userProject"
+
+// hasUserProject reports whether m takes the userProject query parameter.
+func (m *Method) hasUserProject() bool {
+	return len(m.grepParams(func(p *Param) bool {
+		return p.name == userProjectParam && p.Location() == "query"
+	})) > 0
+}
+
+// hasUserProject reports whether any method of a takes the userProject
+// query parameter, so that its Service may set a default for it.
+func (a *API) hasUserProject() bool {
+	for _, meth := range a.allMethods(a.Resources(a.m, "")) {
+		if meth.hasUserProject() {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *Method) Params() []*Param {
@@ -1533,7 +3018,7 @@ func (m *Method) Params() []*Param {
 		parameters := jobj(m.m, "parameters")
 		for _, name := range sortedKeys(parameters) {
 			mi := parameters[name]
-			pm := mi.(map[string]interface{})
+			pm := asObject(mi, "parameter %q of method %q", name, m.Id())
 			m.params = append(m.params, &Param{
 				name:   name,
 				m:      pm,
@@ -1573,10 +3058,125 @@ func (m *Method) OptParams() []*Param {
 	})
 }
 
-func (meth *Method) cacheResponseTypes(api *API) {
+// maxListSetters is the number of optional parameters above which a list
+// method gets an options struct as well as its setters.
+const maxListSetters = 8
+
+// writeListOptions writes a struct holding the optional parameters of
+// meth, and an ApplyOptions method setting them on the call, so that
+// callers of list methods with many options can build them up
+// conditionally rather than in a chain of setters.
+func (meth *Method) writeListOptions(callName string) {
+	a := meth.api
+	p, pn := a.p, a.pn
+	optsName := a.GetName(strings.TrimSuffix(callName, "Call") + "Options")
+	p("\n%s", asComment("", fmt.Sprintf("%s holds optional parameters for %s, to be set with its ApplyOptions method. Parameters left nil are not set.", optsName, callName)))
+	pn("type %s struct {", optsName)
+	for i, opt := range meth.OptParams() {
+		field := initialCap(opt.name)
+		des := strings.TrimSpace(strings.Replace(jstr(opt.m, "description"), "Optional.", "", 1))
+		if i > 0 {
+			pn("")
+		}
+		if des != "" {
+			p("%s", asComment("\t", fmt.Sprintf("%s: %s", field, des)))
+		}
+		if opt.IsRepeated() {
+			pn(" %s []%s", field, opt.GoType())
+		} else {
+			pn(" %s *%s", field, opt.GoType())
+		}
+	}
+	pn("}")
+
+	p("\n%s", asComment("", "ApplyOptions sets each optional parameter of the call which is set in o."))
+	pn("func (c *%s) ApplyOptions(o *%s) *%s {", callName, optsName, callName)
+	for _, opt := range meth.OptParams() {
+		field := initialCap(opt.name)
+		if opt.IsRepeated() {
+			pn(" if o.%s != nil { c.%s(o.%s...) }", field, field, field)
+		} else {
+			pn(" if o.%s != nil { c.%s(*o.%s) }", field, field, field)
+		}
+	}
+	pn(" return c")
+	pn("}")
+}
+
+// vlogf prints the messages enabled by -v. It is overridden in tests.
+var vlogf = log.Printf
+
+// logDecisions logs how meth is generated, as a line of key=value pairs
+// for the method followed by one for each of its arguments and optional
+// parameters.
+func (meth *Method) logDecisions(methodName, callName, retType string, args *arguments) {
+	recv := "Service"
+	if meth.r != nil {
+		recv = meth.r.GoType()
+	}
+	paging := "none"
+	if _, rprop, ok := meth.supportsPaging(); ok {
+		paging = rprop.APIName()
+	}
+	vlogf("%s: method id=%s http=%s path=%q func=%s.%s call=%s response=%q paging=%s upload=%v download=%v",
+		meth.api.ID, meth.Id(), jstr(meth.m, "httpMethod"), jstr(meth.m, "path"),
+		recv, methodName, callName, retType, paging, meth.supportsMediaUpload(), meth.supportsMediaDownload())
+	for _, arg := range args.l {
+		vlogf("%s: method id=%s arg=%s location=%s go=%s type=%s", meth.api.ID, meth.Id(), arg.apiname, arg.location, arg.goname, arg.gotype)
+	}
+	for _, opt := range meth.OptParams() {
+		vlogf("%s: method id=%s param=%s location=%s setter=%s type=%s repeated=%v", meth.api.ID, meth.Id(), opt.name, opt.Location(), initialCap(opt.name), opt.GoType(), opt.IsRepeated())
+	}
+}
+
+// writeEnumChecks writes code for buildRequest which, when enum
+// validation is enabled, checks the values of the enum parameters and
+// request body of meth.
+func (meth *Method) writeEnumChecks(args *arguments) {
+	var checks []string
+	for _, p := range meth.Params() {
+		enum, ok := p.Enum()
+		if !ok || p.GoType() != "string" || p.Location() != "query" {
+			continue
+		}
+		checks = append(checks, fmt.Sprintf("gensupport.CheckEnum(%q, %s, urlParams[%q]...)", p.name, goStringSlice(enum), p.name))
+	}
+	for _, arg := range args.forLocation("path") {
+		enum, ok := meth.NamedParam(arg.apiname).Enum()
+		if !ok || (arg.gotype != "string" && arg.gotype != "[]string") {
+			continue
+		}
+		expr := "c." + arg.goname
+		if arg.gotype == "[]string" {
+			expr += "..."
+		}
+		checks = append(checks, fmt.Sprintf("gensupport.CheckEnum(%q, %s, %s)", arg.apiname, goStringSlice(enum), expr))
+	}
+	if ba := args.bodyArg(); ba != nil && jstr(meth.m, "httpMethod") != "GET" && meth.api.validated[ba.apitype] {
+		checks = append(checks, fmt.Sprintf("c.%s.Validate()", ba.goname))
+	}
+	if len(checks) == 0 {
+		return
+	}
+	pn := meth.api.pn
+	pn("if c.s.settings.ValidateEnums {")
+	for _, c := range checks {
+		pn(" if err := %s; err != nil { return nil, err }", c)
+	}
+	pn("}")
+}
+
+// cacheTypes records the request and response types of meth in api.
+func (meth *Method) cacheTypes(api *API) {
 	if retType := responseType(api, meth.m); retType != "" && strings.HasPrefix(retType, "*") {
 		api.responseTypes[retType] = true
 	}
+	if _, _, ok := meth.supportsPaging(); ok {
+		api.pageTypes[meth.responseType().apiName] = true
+	}
+	if ro := jobj(meth.m, "request"); ro != nil {
+		api.requestTypes[jstr(ro, "$ref")] = true
+	}
 }
 
 // convertMultiParams builds a []string temp variable from a slice
@@ -1609,6 +3209,9 @@ func (meth *Method) generateCode() {
 		prefix = initialCap(fmt.Sprintf("%s.%s", res.parent, res.name))
 	}
 	callName := a.GetName(prefix + methodName + "Call")
+	if *verbose {
+		meth.logDecisions(methodName, callName, retType, args)
+	}
 
 	pn("\ntype %s struct {", callName)
 	pn(" s *Service")
@@ -1622,6 +3225,15 @@ func (meth *Method) generateCode() {
 	if httpMethod == "GET" {
 		pn(" ifNoneMatch_ string")
 	}
+	if meth.hasBody() {
+		pn(" compress_ bool")
+	}
+	if meth.supportsMediaDownload() {
+		pn(" skipChecksum_ bool")
+		pn(" rangeOffset_ int64")
+		pn(" rangeLength_ int64")
+		pn(" maxResumes_ int")
+	}
 
 	if meth.supportsMediaUpload() {
 		// At most one of media_ and resumbableBuffer_ will be set.
@@ -1630,11 +3242,22 @@ func (meth *Method) generateCode() {
 		pn(" mediaType_ string")
 		pn(" mediaSize_  int64 // mediaSize, if known.  Used only for calls to progressUpdater_.")
 		pn(" progressUpdater_  googleapi.ProgressUpdater")
+		pn(" sessionURI_ string")
+		pn(" sessionFunc_ func(sessionURI string)")
 	}
+	if meth.supportsMediaUpload() || meth.supportsMediaDownload() {
+		pn(" throttle_ *gensupport.Throttle")
+	}
+	pn(" baseURL_ string")
+	pn(" client_ *http.Client")
 	pn(" ctx_ context.Context")
 	pn("}")
 
 	p("\n%s", asComment("", methodName+": "+jstr(meth.m, "description")))
+	if hasLabel(jstrlist(meth.m, "labels"), "deprecated") {
+		pn("//")
+		pn("// Deprecated: This method is deprecated.")
+	}
 	if res != nil {
 		if url := canonicalDocsURL[fmt.Sprintf("%v%v/%v", docsLink, res.name, meth.name)]; url != "" {
 			pn("// For details, see %v", url)
@@ -1648,6 +3271,7 @@ func (meth *Method) generateCode() {
 	} else {
 		pn("func (r *%s) %s(%s) *%s {", res.GoType(), methodName, args, callName)
 		servicePtr = "r.s"
+		res.calls = append(res.calls, fmt.Sprintf("%s(%s) *%s", methodName, args, callName))
 	}
 
 	pn(" c := &%s{s: %s, urlParams_: make(gensupport.URLParams)}", callName, servicePtr)
@@ -1715,12 +3339,71 @@ func (meth *Method) generateCode() {
 		pn("}")
 	}
 
+	if meth.name == "list" && len(meth.OptParams()) > maxListSetters {
+		meth.writeListOptions(callName)
+	}
+
+	if meth.hasBody() {
+		comment := "Compress causes the request body to be gzip-compressed " +
+			"and sent with \"Content-Encoding: gzip\", reducing the data sent for large requests."
+		if meth.supportsMediaUpload() {
+			comment += " Media sent in the same request as the metadata is compressed too, " +
+				"as it is sent rather than in advance, so that it is not held in memory; " +
+				"use this only with APIs which accept compressed uploads."
+		}
+		p("\n%s", asComment("", comment))
+		pn("func (c *%s) Compress() *%s {", callName, callName)
+		pn(" c.compress_ = true")
+		pn(" return c")
+		pn("}")
+	}
+
+	if meth.supportsMediaDownload() {
+		p("\n%s", asComment("", "SkipChecksum stops Download from verifying the media "+
+			"it fetches against the checksum sent by the server, which saves the cost of "+
+			"computing the checksum for large downloads."))
+		pn("func (c *%s) SkipChecksum() *%s {", callName, callName)
+		pn(" c.skipChecksum_ = true")
+		pn(" return c")
+		pn("}")
+
+		p("\n%s", asComment("", "Range causes Download to fetch only length bytes of the media, "+
+			"starting at offset. A length of zero or less fetches the rest of the media. "+
+			"The server replies with a 206 Partial Content response, whose body is not "+
+			"verified against the checksum of the whole media."))
+		pn("func (c *%s) Range(offset, length int64) *%s {", callName, callName)
+		pn(" c.rangeOffset_ = offset")
+		pn(" c.rangeLength_ = length")
+		pn(" return c")
+		pn("}")
+
+		p("\n%s", asComment("", "AutoResume causes Download to continue an interrupted download "+
+			"from the last byte received, rather than failing, making at most maxResumes "+
+			"further requests. This suits large downloads over unreliable networks."))
+		pn("func (c *%s) AutoResume(maxResumes int) *%s {", callName, callName)
+		pn(" c.maxResumes_ = maxResumes")
+		pn(" return c")
+		pn("}")
+
+		p("\n%s", asComment("", "BandwidthLimit limits the rate at which Download reads media "+
+			"to bytesPerSec bytes per second, on average. If bytesPerSec is zero, "+
+			"the rate is not limited, which is the default. "+
+			"Media uploads may be limited with googleapi.WithBandwidthLimit."))
+		pn("func (c *%s) BandwidthLimit(bytesPerSec int64) *%s {", callName, callName)
+		pn(" c.throttle_ = gensupport.NewThrottle(bytesPerSec)")
+		pn(" return c")
+		pn("}")
+	}
+
 	if meth.supportsMediaUpload() {
 		comment := "Media specifies the media to upload in one or more chunks. " +
 			"The chunk size may be controlled by supplying a MediaOption generated by googleapi.ChunkSize. " +
 			"The chunk size defaults to googleapi.DefaultUploadChunkSize." +
 			"The Content-Type header used in the upload request will be determined by sniffing the contents of r, " +
 			"unless a MediaOption generated by googleapi.ContentType is supplied." +
+			"\nThe length of r need not be known in advance, so r may be a pipe or os.Stdin: " +
+			"each chunk is buffered in memory before it is sent, and the upload is completed " +
+			"once r reports io.EOF." +
 			"\nAt most one of Media and ResumableMedia may be set."
 		// TODO(mcgreevy): Ensure that r is always closed before Do returns, and document this.
 		// See comments on https://code-review.googlesource.com/#/c/3970/
@@ -1732,6 +3415,8 @@ func (meth *Method) generateCode() {
 		pn("  r, c.mediaType_ = gensupport.DetermineContentType(r, opts.ContentType)")
 		pn(" }")
 		pn(" c.media_, c.mediaBuffer_ = gensupport.PrepareUpload(r, chunkSize)")
+		pn(` c.sessionURI_ = ""`)
+		pn(" c.throttle_ = gensupport.NewThrottle(opts.BandwidthLimit)")
 		pn(" return c")
 		pn("}")
 		comment = "ResumableMedia specifies the media to upload in chunks and can be canceled with ctx. " +
@@ -1747,6 +3432,7 @@ func (meth *Method) generateCode() {
 		pn(" rdr := gensupport.ReaderAtToReader(r, size)")
 		pn(" rdr, c.mediaType_ = gensupport.DetermineContentType(rdr, mediaType)")
 		pn(" c.mediaBuffer_ = gensupport.NewMediaBuffer(rdr, googleapi.DefaultUploadChunkSize)")
+		pn(` c.sessionURI_ = ""`)
 		pn(" c.media_ = nil")
 		pn(" c.mediaSize_ = size")
 		pn(" return c")
@@ -1759,6 +3445,36 @@ func (meth *Method) generateCode() {
 		pn(`c.progressUpdater_ = pu`)
 		pn("return c")
 		pn("}")
+		comment = "UploadSession provides a callback function that will be called with " +
+			"the URI of the upload session once a chunked upload has begun. " +
+			"A process which saves the URI can later finish an interrupted " +
+			"upload with ResumeUpload. f is called again with the new URI if the " +
+			"server moves the session, for example to another host."
+		p("\n%s", asComment("", comment))
+		pn("func (c *%s) UploadSession(f func(sessionURI string)) *%s {", callName, callName)
+		pn("c.sessionFunc_ = f")
+		pn("return c")
+		pn("}")
+		comment = "ResumeUpload continues a chunked upload begun earlier, possibly by another process, " +
+			"instead of starting a new one. sessionURI is the URI passed to the UploadSession callback. " +
+			"r must supply the whole media from its start; Do asks the server how much of it was received, " +
+			"and skips that much of r. " +
+			"The metadata of the call is not sent again. " +
+			"Only the ChunkSize and WithBandwidthLimit options are used." +
+			"\n\nAt most one of Media, ResumableMedia and ResumeUpload may be set."
+		p("\n%s", asComment("", comment))
+		pn("func (c *%s) ResumeUpload(sessionURI string, r io.Reader, options ...googleapi.MediaOption) *%s {", callName, callName)
+		pn(" opts := googleapi.ProcessMediaOptions(options)")
+		pn(" chunkSize := opts.ChunkSize")
+		pn(" if chunkSize <= 0 {")
+		pn("  chunkSize = googleapi.DefaultUploadChunkSize")
+		pn(" }")
+		pn(" c.sessionURI_ = sessionURI")
+		pn(" c.media_ = nil")
+		pn(" c.mediaBuffer_ = gensupport.NewMediaBuffer(r, chunkSize)")
+		pn(" c.throttle_ = gensupport.NewThrottle(opts.BandwidthLimit)")
+		pn(" return c")
+		pn("}")
 	}
 
 	comment := "Fields allows partial responses to be retrieved. " +
@@ -1766,7 +3482,7 @@ func (meth *Method) generateCode() {
 		"for more information."
 	p("\n%s", asComment("", comment))
 	pn("func (c *%s) Fields(s ...googleapi.Field) *%s {", callName, callName)
-	pn(`c.urlParams_.Set("fields", googleapi.CombineFields(s))`)
+	pn(`c.urlParams_.Set("fields", gensupport.CombineFields(s))`)
 	pn("return c")
 	pn("}")
 	if httpMethod == "GET" {
@@ -1802,7 +3518,57 @@ func (meth *Method) generateCode() {
 	pn("return c")
 	pn("}")
 
-	pn("\nfunc (c *%s) doRequest(alt string) (*http.Response, error) {", callName)
+	p("\n%s", asComment("", "BaseURL sends this call to baseURL rather than to the BasePath of the Service, "+
+		"for instance to route it to a regional endpoint. It takes precedence over a URL set in the call's context with googleapi.WithBaseURL."))
+	pn("func (c *%s) BaseURL(baseURL string) *%s {", callName, callName)
+	pn(" c.baseURL_ = baseURL")
+	pn(" return c")
+	pn("}")
+
+	p("\n%s", asComment("", "WithClient sends this call with client rather than with the client the Service was created with, "+
+		"so that a single Service can act on behalf of many users. "+
+		"To use a particular oauth2.TokenSource, pass the client returned by oauth2.NewClient."))
+	pn("func (c *%s) WithClient(client *http.Client) *%s {", callName, callName)
+	pn(" c.client_ = client")
+	pn(" return c")
+	pn("}")
+
+	comment = "Clone returns a copy of c whose parameters and options may be changed without affecting c, " +
+		"so that a prepared call can be sent several times with variations, or from several goroutines. " +
+		"The request body, if any, is shared with c."
+	if meth.supportsMediaUpload() {
+		comment += " So is any media to be uploaded, which can be sent only once."
+	}
+	p("\n%s", asComment("", comment))
+	pn("func (c *%s) Clone() *%s {", callName, callName)
+	pn(" cc := *c")
+	pn(" cc.urlParams_ = c.urlParams_.Copy()")
+	for _, arg := range args.l {
+		if arg.location != "query" && strings.HasPrefix(arg.gotype, "[]") {
+			pn(" cc.%s = append(%s(nil), c.%s...)", arg.goname, arg.gotype, arg.goname)
+		}
+	}
+	pn(" return &cc")
+	pn("}")
+
+	pn("\nfunc (c *%s) doRequest(alt string, opts ...googleapi.CallOption) (*http.Response, error) {", callName)
+	pn("req, err := c.buildRequest(alt, opts...)")
+	pn("if err != nil { return nil, err }")
+	pn("client := c.s.client")
+	pn("if c.client_ != nil { client = c.client_ }")
+	pn("if err := gensupport.WaitLimiter(c.ctx_, opts...); err != nil { return nil, err }")
+	pn("return gensupport.SendRequest(c.ctx_, client, req, &c.s.settings, %q)", jstr(meth.m, "id"))
+	pn("}")
+
+	// The call's own parameters are copied so that building a request
+	// leaves the call unchanged, and safe to execute concurrently.
+	pn("\nfunc (c *%s) buildRequest(alt string, opts ...googleapi.CallOption) (*http.Request, error) {", callName)
+	pn("urlParams := c.urlParams_.Copy()")
+	pn("gensupport.SetOptions(urlParams, opts...)")
+	if meth.hasUserProject() {
+		pn("urlParams.SetDefault(%q, c.s.userProject)", userProjectParam)
+	}
+	meth.writeEnumChecks(args)
 	pn(`reqHeaders := make(http.Header)`)
 	pn(`reqHeaders.Set("User-Agent",c.s.userAgent())`)
 	if httpMethod == "GET" {
@@ -1810,19 +3576,26 @@ func (meth *Method) generateCode() {
 		pn(` reqHeaders.Set("If-None-Match",  c.ifNoneMatch_)`)
 		pn("}")
 	}
+	if meth.supportsMediaDownload() {
+		pn(`if alt == "media" && (c.rangeOffset_ > 0 || c.rangeLength_ > 0) {`)
+		pn(` reqHeaders.Set("Range", gensupport.RangeHeader(c.rangeOffset_, c.rangeLength_))`)
+		pn("}")
+	}
 	pn("var body io.Reader = nil")
 	if ba := args.bodyArg(); ba != nil && httpMethod != "GET" {
 		style := "WithoutDataWrapper"
 		if a.needsDataWrapper() {
 			style = "WithDataWrapper"
 		}
-		pn("body, err := googleapi.%s.JSONReader(c.%s)", style, ba.goname)
+		pn("body, err := gensupport.JSONBody(googleapi.%s, c.%s, &c.s.settings)", style, ba.goname)
 		pn("if err != nil { return nil, err }")
 		pn(`reqHeaders.Set("Content-Type", "application/json")`)
 	}
-	pn(`c.urlParams_.Set("alt", alt)`)
+	pn(`urlParams.Set("alt", alt)`)
 
-	pn("urls := googleapi.ResolveRelative(c.s.BasePath, %q)", jstr(meth.m, "path"))
+	pn("base, err := gensupport.BasePath(c.ctx_, c.s.BasePath, c.baseURL_)")
+	pn("if err != nil { return nil, err }")
+	pn("urls := gensupport.ResolveRelative(base, %q)", jstr(meth.m, "path"))
 	if meth.supportsMediaUpload() {
 		pn("if c.media_ != nil || c.mediaBuffer_ != nil{")
 		// Hack guess, since we get a 404 otherwise:
@@ -1833,61 +3606,142 @@ func (meth *Method) generateCode() {
 		pn("  if c.mediaBuffer_ != nil {")
 		pn(`   protocol = "resumable"`)
 		pn("  }")
-		pn(`  c.urlParams_.Set("uploadType", protocol)`)
-		pn("}")
-
-		pn("if body == nil {")
-		pn(" body = new(bytes.Buffer)")
-		pn(` reqHeaders.Set("Content-Type", "application/json")`)
+		pn(`  urlParams.Set("uploadType", protocol)`)
+		// Calls without media send no body at all, rather than an empty
+		// JSON one, which some endpoints reject.
+		pn("  if body == nil {")
+		pn("   body = new(bytes.Buffer)")
+		pn(`   reqHeaders.Set("Content-Type", "application/json")`)
+		pn("  }")
 		pn("}")
 		pn(`if c.media_ != nil {`)
 		pn(`  combined, ctype := gensupport.CombineBodyMedia(body, "application/json", c.media_, c.mediaType_)`)
-		pn("  defer combined.Close()")
 		pn(`  reqHeaders.Set("Content-Type", ctype)`)
-		pn("  body = combined")
+		// The media is compressed as it is sent, rather than buffered,
+		// and the throttle limits the compressed bytes.
+		pn("  if c.compress_ {")
+		pn("   combined = gensupport.GzipStream(combined)")
+		pn(`   reqHeaders.Set("Content-Encoding", "gzip")`)
+		pn("  }")
+		pn("  body = c.throttle_.ReadCloser(combined)")
 		pn("}")
 		pn(`if c.mediaBuffer_ != nil && c.mediaType_ != ""{`)
 		pn(` reqHeaders.Set("X-Upload-Content-Type", c.mediaType_)`)
 		pn("}")
 	}
-	pn("urls += \"?\" + c.urlParams_.Encode()")
-	pn("req, _ := http.NewRequest(%q, urls, body)", httpMethod)
+	if meth.hasBody() {
+		if meth.supportsMediaUpload() {
+			pn("if c.compress_ && body != nil && c.media_ == nil {")
+		} else {
+			pn("if c.compress_ && body != nil {")
+		}
+		pn(" gz, err := gensupport.GzipBody(body)")
+		pn(" if err != nil { return nil, err }")
+		pn(" body = gz")
+		pn(` reqHeaders.Set("Content-Encoding", "gzip")`)
+		pn("}")
+	}
+	pn("urls += \"?\" + urlParams.Encode()")
+	pn("req, err := http.NewRequest(%q, urls, body)", httpMethod)
+	pn("if err != nil { return nil, err }")
 	pn("req.Header = reqHeaders")
 
 	// Replace param values after NewRequest to avoid reencoding them.
 	// E.g. Cloud Storage API requires '%2F' in entity param to be kept, but url.Parse replaces it with '/'.
 	argsForLocation := args.forLocation("path")
 	if len(argsForLocation) > 0 {
-		pn(`googleapi.Expand(req.URL, map[string]string{`)
+		pn(`gensupport.Expand(req.URL, map[string]string{`)
 		for _, arg := range argsForLocation {
 			pn(`"%s": %s,`, arg.apiname, arg.exprAsString("c."))
 		}
 		pn(`})`)
 	} else {
 		// Just call SetOpaque since we aren't calling Expand
-		pn(`googleapi.SetOpaque(req.URL)`)
+		pn(`gensupport.SetOpaque(req.URL)`)
 	}
 
-	pn("if c.ctx_ != nil {")
-	pn(" return ctxhttp.Do(c.ctx_, c.s.client, req)")
+	pn("return req, nil")
+	pn("}")
+
+	comment = "MarshalRequest builds the request for the call without sending it " +
+		"and returns it in a serialized form suitable for storing in a durable queue. " +
+		"The request may be sent later, possibly by another process, with googleapi.Execute."
+	if meth.supportsMediaUpload() {
+		comment += " Calls using chunked or resumable media uploads cannot be serialized."
+	}
+	p("\n%s", asComment("", comment))
+	pn("func (c *%s) MarshalRequest(opts ...googleapi.CallOption) ([]byte, error) {", callName)
+	if meth.supportsMediaUpload() {
+		pn("if c.mediaBuffer_ != nil {")
+		pn(` return nil, errors.New("cannot serialize a call with a resumable media upload")`)
+		pn("}")
+	}
+	pn(`req, err := c.buildRequest("json", opts...)`)
+	pn("if err != nil { return nil, err }")
+	pn("return gensupport.MarshalRequest(req)")
 	pn("}")
-	pn("return c.s.client.Do(req)")
+
+	comment = "HTTPRequest builds the request for the call, as Do would send it, and returns it without sending it, " +
+		"so that it may be signed, inspected or sent by other means. " +
+		"Its ContentLength is set, and so is GetBody if it has a body. " +
+		"Headers which are added as the request is sent, such as X-Goog-Api-Client, are not included."
+	if meth.supportsMediaUpload() {
+		comment += " For a call with a resumable media upload, the request is the one starting the upload session."
+	}
+	p("\n%s", asComment("", comment))
+	pn("func (c *%s) HTTPRequest(opts ...googleapi.CallOption) (*http.Request, error) {", callName)
+	pn(`req, err := c.buildRequest("json", opts...)`)
+	pn("if err != nil { return nil, err }")
+	pn("gensupport.PrepareRequest(req)")
+	pn("return req, nil")
 	pn("}")
 
 	if meth.supportsMediaDownload() {
 		pn("\n// Download fetches the API endpoint's \"media\" value, instead of the normal")
 		pn("// API response value. If the returned error is nil, the Response is guaranteed to")
 		pn("// have a 2xx status code. Callers must close the Response.Body as usual.")
+		pn("// Unless SkipChecksum was called, reading the body returns a")
+		pn("// *googleapi.ChecksumError in place of io.EOF if the media was corrupted in transit.")
 		pn("func (c *%s) Download(opts ...googleapi.CallOption) (*http.Response, error) {", callName)
-		pn(`gensupport.SetOptions(c.urlParams_, opts...)`)
-		pn(`res, err := c.doRequest("media")`)
+		pn(`res, err := c.doRequest("media", opts...)`)
 		pn("if err != nil { return nil, err }")
 		pn("if err := googleapi.CheckMediaResponse(res); err != nil {")
 		pn("res.Body.Close()")
 		pn("return nil, err")
 		pn("}")
+		pn("if c.maxResumes_ > 0 {")
+		pn(" gensupport.ResumeDownload(c.ctx_, res, c.maxResumes_, func(received int64) (*http.Response, error) {")
+		pn("  rc := *c")
+		pn("  rc.rangeOffset_ += received")
+		pn("  if rc.rangeLength_ > 0 {")
+		pn("   rc.rangeLength_ -= received")
+		pn("  }")
+		pn(`  res, err := rc.doRequest("media", opts...)`)
+		pn("  if err != nil { return nil, err }")
+		pn("  if err := googleapi.CheckMediaResponse(res); err != nil {")
+		pn("   res.Body.Close()")
+		pn("   return nil, err")
+		pn("  }")
+		pn("  return res, nil")
+		pn(" })")
+		pn("}")
+		pn("if !c.skipChecksum_ {")
+		pn(" gensupport.VerifyChecksum(res)")
+		pn("}")
+		pn("res.Body = c.throttle_.ReadCloser(res.Body)")
 		pn("return res, nil")
 		pn("}")
+
+		p("\n%s", asComment("", "DownloadSpill is like Download, but reads the whole media value before "+
+			"returning it. A value of at most threshold bytes is held in memory; a larger one is written "+
+			"to a temporary file, so that large downloads do not exhaust memory. "+
+			"Callers must close the returned body to remove any temporary file."))
+		pn("func (c *%s) DownloadSpill(threshold int64, opts ...googleapi.CallOption) (*googleapi.SpilledBody, error) {", callName)
+		pn("res, err := c.Download(opts...)")
+		pn("if err != nil { return nil, err }")
+		pn("defer res.Body.Close()")
+		pn(`return googleapi.Spill(res.Body, threshold, "")`)
+		pn("}")
 	}
 
 	mapRetType := strings.HasPrefix(retTypeComma, "map[")
@@ -1907,8 +3761,15 @@ func (meth *Method) generateCode() {
 	if retTypeComma != "" {
 		nilRet = "nil, "
 	}
-	pn(`gensupport.SetOptions(c.urlParams_, opts...)`)
-	pn(`res, err := c.doRequest("json")`)
+	if meth.supportsMediaUpload() {
+		pn("var res *http.Response")
+		pn("var err error")
+		pn(`if c.sessionURI_ == "" {`)
+		pn(` res, err = c.doRequest("json", opts...)`)
+		pn("}")
+	} else {
+		pn(`res, err := c.doRequest("json", opts...)`)
+	}
 
 	if retTypeComma != "" && !mapRetType {
 		pn("if res != nil && res.StatusCode == http.StatusNotModified {")
@@ -1920,13 +3781,35 @@ func (meth *Method) generateCode() {
 		pn("}")
 	}
 	pn("if err != nil { return %serr }", nilRet)
-	pn("defer googleapi.CloseBody(res)")
-	pn("if err := googleapi.CheckResponse(res); err != nil { return %serr }", nilRet)
+	pn("defer gensupport.CloseBody(res)")
+	if meth.supportsMediaUpload() {
+		pn("if res != nil {")
+		pn(" if err := googleapi.CheckResponse(res); err != nil { return %serr }", nilRet)
+		pn("}")
+	} else {
+		pn("if err := googleapi.CheckResponse(res); err != nil { return %serr }", nilRet)
+	}
 	if meth.supportsMediaUpload() {
 		pn("if c.mediaBuffer_ != nil {")
-		pn(` loc := res.Header.Get("Location")`)
+		pn(" loc := c.sessionURI_")
+		pn(` if loc == "" {`)
+		pn(`  loc = res.Header.Get("Location")`)
+		pn(" }")
+		if meth.hasUserProject() {
+			// Requester-pays buckets bill each request of the session.
+			pn(" userProject := c.urlParams_.Get(%q)", userProjectParam)
+			pn(` if userProject == "" {`)
+			pn("  userProject = c.s.userProject")
+			pn(" }")
+			pn(" loc = gensupport.AddParam(loc, %q, userProject)", userProjectParam)
+		}
+		pn(" if c.sessionFunc_ != nil {")
+		pn("  c.sessionFunc_(loc)")
+		pn(" }")
+		pn(" client := c.s.client")
+		pn(" if c.client_ != nil { client = c.client_ }")
 		pn(" rx := &gensupport.ResumableUpload{")
-		pn("  Client:        c.s.client,")
+		pn("  Client:        client,")
 		pn("  UserAgent:     c.s.userAgent(),")
 		pn("  URI:           loc,")
 		pn("  Media:         c.mediaBuffer_,")
@@ -1936,13 +3819,21 @@ func (meth *Method) generateCode() {
 		pn("    c.progressUpdater_(curr, c.mediaSize_)")
 		pn("   }")
 		pn("  },")
+		pn("  Tracer:        c.s.settings.Tracer,")
+		pn("  Throttle:      c.throttle_,")
+		pn("  MethodID:      %q,", jstr(meth.m, "id"))
+		pn("  SessionMoved:  c.sessionFunc_,")
 		pn(" }")
 		pn(" ctx := c.ctx_")
 		pn(" if ctx == nil {")
 		// TODO(mcgreevy): Require context when calling Media, or Do.
 		pn("  ctx = context.TODO()")
 		pn(" }")
-		pn(" res, err = rx.Upload(ctx)")
+		pn(` if c.sessionURI_ != "" {`)
+		pn("  res, err = rx.Resume(ctx)")
+		pn(" } else {")
+		pn("  res, err = rx.Upload(ctx)")
+		pn(" }")
 		pn(" if err != nil { return %serr }", nilRet)
 		pn(" defer res.Body.Close()")
 		pn(" if err := googleapi.CheckResponse(res); err != nil { return %serr }", nilRet)
@@ -1969,7 +3860,8 @@ func (meth *Method) generateCode() {
 			pn("target := &ret")
 		}
 
-		pn("if err := json.NewDecoder(res.Body).Decode(target); err != nil { return nil, err }")
+		pn("if err := gensupport.DecodeResponse(target, res, &c.s.settings); err != nil { return nil, err }")
+		pn("c.s.settings.AfterDecode(%q, ret)", jstr(meth.m, "id"))
 		pn("return ret, nil")
 	}
 
@@ -1977,22 +3869,77 @@ func (meth *Method) generateCode() {
 	pn("// %s\n", string(bs))
 	pn("}")
 
-	if cname, rname, ok := meth.supportsPaging(); ok {
+	if retTypeComma != "" && !meth.supportsMediaUpload() {
+		p("\n%s", asComment("", "DecodeInto executes the call like Do, but decodes the response into v "+
+			"rather than a new "+retType+". "+
+			"v may be a pointer to any type, such as a struct holding just the fields requested with Fields, "+
+			"which saves allocating the parts of the response which are not needed."))
+		pn("func (c *%s) DecodeInto(v interface{}, opts ...googleapi.CallOption) error {", callName)
+		pn(`res, err := c.doRequest("json", opts...)`)
+		pn("if err != nil { return err }")
+		pn("defer gensupport.CloseBody(res)")
+		pn("if err := googleapi.CheckResponse(res); err != nil { return err }")
+		if a.needsDataWrapper() {
+			pn("target := &struct {")
+			pn("  Data interface{} `json:\"data\"`")
+			pn("}{v}")
+			pn("if err := gensupport.DecodeResponse(target, res, &c.s.settings); err != nil { return err }")
+		} else {
+			pn("if err := gensupport.DecodeResponse(v, res, &c.s.settings); err != nil { return err }")
+		}
+		pn("c.s.settings.AfterDecode(%q, v)", jstr(meth.m, "id"))
+		pn("return nil")
+		pn("}")
+	}
+
+	if *interfaces {
+		doer := a.GetName(strings.TrimSuffix(callName, "Call") + "Doer")
+		p("\n%s", asComment("", fmt.Sprintf("%s is implemented by *%s. Code which only executes "+
+			"the call may accept a %s, so that the call can be decorated, for example with caching "+
+			"or metrics, by wrapping its Do method.", doer, callName, doer)))
+		pn("type %s interface {", doer)
+		pn(" Do(opts ...googleapi.CallOption) (%serror)", retTypeComma)
+		pn("}")
+	}
+
+	if cname, rprop, ok := meth.supportsPaging(); ok {
 		// We can assume retType is non-empty.
 		pn("")
 		pn("// Pages invokes f for each page of results.")
 		pn("// A non-nil error returned from f will halt the iteration.")
 		pn("// The provided context supersedes any context provided to the Context method.")
+		pn("// c is not modified, so it may be used again afterwards.")
 		pn("func (c *%s) Pages(ctx context.Context, f func(%s) error) error {", callName, retType)
-		pn(" c.ctx_ = ctx")
-		pn(` defer c.%s(c.urlParams_.Get(%q)) // reset paging to original point`, cname, "pageToken")
+		pn(" // Page with a copy of c, leaving c itself unchanged.")
+		pn(" cc := c.Clone()")
+		pn(" cc.ctx_ = ctx")
 		pn(" for {")
-		pn("  x, err := c.Do()")
+		pn("  x, err := cc.Do()")
 		pn("  if err != nil { return err }")
 		pn("  if err := f(x); err != nil { return err }")
-		pn(`  if x.%s == "" { return nil }`, rname)
-		pn("  c.%s(x.%s)", cname, rname)
+		rname := rprop.GoName()
+		if rprop.forcePointerType() {
+			pn(`  if x.%s == nil || *x.%s == "" { return nil }`, rname, rname)
+			pn("  cc.%s(*x.%s)", cname, rname)
+		} else {
+			pn(`  if x.%s == "" { return nil }`, rname)
+			pn("  cc.%s(x.%s)", cname, rname)
+		}
+		pn(" }")
+		pn("}")
+
+		pn("\n// SetPageToken sets the token of the page of results to fetch.")
+		pn("// It implements googleapi.PageIterator.")
+		pn("func (c *%s) SetPageToken(token string) {", callName)
+		pn(" c.%s(token)", cname)
+		pn("}")
+		pn("\n// DoPage is like Do. It implements googleapi.PageIterator.")
+		pn("func (c *%s) DoPage(opts ...googleapi.CallOption) (googleapi.PageResponse, error) {", callName)
+		pn(" x, err := c.Do(opts...)")
+		pn(" if err != nil {")
+		pn("  return nil, err")
 		pn(" }")
+		pn(" return x, nil")
 		pn("}")
 	}
 }
@@ -2043,7 +3990,7 @@ func (p *Param) IsRepeated() bool {
 }
 
 func (p *Param) Location() string {
-	return p.m["location"].(string)
+	return jstr(p.m, "location")
 }
 
 func (p *Param) GoType() string {
@@ -2077,19 +4024,40 @@ func (a *API) APIMethods() []*Method {
 			api:  a,
 			r:    nil, // to be explicit
 			name: name,
-			m:    mi.(map[string]interface{}),
+			m:    asObject(mi, "method %q", name),
 		})
 	}
 	return meths
 }
 
+// allMethods returns the top-level methods of the API followed by those
+// of each resource in reslist and its sub-resources.
+func (a *API) allMethods(reslist []*Resource) []*Method {
+	ms := a.APIMethods()
+	var walk func([]*Resource)
+	walk = func(rs []*Resource) {
+		for _, r := range rs {
+			ms = append(ms, r.Methods()...)
+			walk(r.resources)
+		}
+	}
+	walk(reslist)
+	return ms
+}
+
 func (a *API) Resources(m map[string]interface{}, p string) []*Resource {
 	res := []*Resource{}
 	resMap := jobj(m, "resources")
 	for _, rname := range sortedKeys(resMap) {
 		rmi := resMap[rname]
-		rm := rmi.(map[string]interface{})
-		res = append(res, &Resource{a, rname, p, rm, a.Resources(rm, fmt.Sprintf("%s.%s", p, rname))})
+		rm := asObject(rmi, "resource %q", rname)
+		res = append(res, &Resource{
+			api:       a,
+			name:      rname,
+			parent:    p,
+			m:         rm,
+			resources: a.Resources(rm, fmt.Sprintf("%s.%s", p, rname)),
+		})
 	}
 	return res
 }
@@ -2115,7 +4083,7 @@ func (meth *Method) NewArguments() (args *arguments) {
 	po, ok := meth.m["parameterOrder"].([]interface{})
 	if ok {
 		for _, poi := range po {
-			pname := poi.(string)
+			pname := asString(poi, "parameterOrder of method %q", meth.Id())
 			arg := meth.NewArg(pname, meth.NamedParam(pname))
 			args.AddArg(arg)
 		}
@@ -2129,9 +4097,10 @@ func (meth *Method) NewArguments() (args *arguments) {
 func (meth *Method) NewBodyArg(m map[string]interface{}) *argument {
 	reftype := jstr(m, "$ref")
 	return &argument{
+		method:   meth,
 		goname:   validGoIdentifer(strings.ToLower(reftype)),
 		apiname:  "REQUEST",
-		gotype:   "*" + reftype,
+		gotype:   "*" + meth.api.refGoName(reftype),
 		apitype:  reftype,
 		location: "body",
 	}
@@ -2142,6 +4111,9 @@ func (meth *Method) NewArg(apiname string, p *Param) *argument {
 	apitype := jstr(m, "type")
 	des := jstr(m, "description")
 	goname := validGoIdentifer(apiname) // but might be changed later, if conflicts
+	if d := droppedRunes(apiname); d != "" {
+		meth.api.lossf("identifier", jstr(meth.m, "id")+"."+apiname, "characters %q dropped from Go name %s", d, goname)
+	}
 	if strings.Contains(des, "identifier") && !strings.HasSuffix(strings.ToLower(goname), "id") {
 		goname += "id" // yay
 		p.callFieldName = goname
@@ -2151,6 +4123,7 @@ func (meth *Method) NewArg(apiname string, p *Param) *argument {
 		gotype = "[]" + gotype
 	}
 	return &argument{
+		method:   meth,
 		apiname:  apiname,
 		apitype:  apitype,
 		goname:   goname,
@@ -2173,7 +4146,7 @@ func (a *argument) String() string {
 func (a *argument) exprAsString(prefix string) string {
 	switch a.gotype {
 	case "[]string":
-		log.Printf("TODO(bradfitz): only including the first parameter in path query.")
+		a.method.api.warnf("TODO(bradfitz): only including the first parameter in path query for %s.", jstr(a.method.m, "id"))
 		return prefix + a.goname + `[0]`
 	case "string":
 		return prefix + a.goname
@@ -2295,6 +4268,30 @@ func simpleTypeConvert(apiType, format string) (gotype string, ok bool) {
 	return gotype, gotype != ""
 }
 
+// knownFormat reports whether format is a discovery format of apiType
+// which the generator represents faithfully.
+func knownFormat(apiType, format string) bool {
+	if format == "" {
+		return true
+	}
+	switch apiType {
+	case "string":
+		switch format {
+		case "int64", "uint64", "int32", "uint32", "byte", "date", "date-time",
+			"google-datetime", "google-duration", "google-fieldmask":
+			return true
+		}
+	case "integer":
+		switch format {
+		case "int32", "uint32", "int64", "uint64":
+			return true
+		}
+	case "number":
+		return format == "double" || format == "float"
+	}
+	return false
+}
+
 func mustSimpleTypeConvert(apiType, format string) string {
 	if gotype, ok := simpleTypeConvert(apiType, format); ok {
 		return gotype
@@ -2331,7 +4328,7 @@ func responseType(api *API, m map[string]interface{}) string {
 			if s := api.schemas[ref]; s != nil {
 				return s.GoReturnType()
 			}
-			return "*" + ref
+			return "*" + api.refGoName(ref)
 		}
 	}
 	return ""
@@ -2368,8 +4365,9 @@ func validGoIdentifer(ident string) string {
 	return id
 }
 
-// depunct removes '-', '.', '$', '/', '_' from identifers, making the
-// following character uppercase. Multiple '_' are preserved.
+// depunct removes '-', '.', '$', '/', '_' and any other characters which
+// may not appear in Go identifiers from identifers, making the following
+// character uppercase. Multiple '_' are preserved.
 func depunct(ident string, needCap bool) string {
 	var buf bytes.Buffer
 	preserve_ := false
@@ -2384,7 +4382,7 @@ func depunct(ident string, needCap bool) string {
 		} else {
 			preserve_ = false
 		}
-		if c == '-' || c == '.' || c == '$' || c == '/' {
+		if c == '-' || c == '.' || c == '$' || c == '/' || !isIdentRune(c) {
 			needCap = true
 			continue
 		}
@@ -2398,6 +4396,26 @@ func depunct(ident string, needCap bool) string {
 
 }
 
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// droppedRunes returns the characters of the discovery name ident which
+// depunct drops without using them as word separators.
+func droppedRunes(ident string) string {
+	var dropped []rune
+	for _, c := range ident {
+		switch c {
+		case '-', '.', '$', '/':
+		default:
+			if !isIdentRune(c) {
+				dropped = append(dropped, c)
+			}
+		}
+	}
+	return string(dropped)
+}
+
 func prettyJSON(m map[string]interface{}) string {
 	bs, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
@@ -2436,6 +4454,25 @@ func jobj(m map[string]interface{}, key string) map[string]interface{} {
 	return nil
 }
 
+// asObject returns v as a JSON object, or panics with an error naming
+// the value described by format and args if it is not one.
+func asObject(v interface{}, format string, args ...interface{}) map[string]interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		panicf("%s is not a JSON object", fmt.Sprintf(format, args...))
+	}
+	return m
+}
+
+// asString is like asObject, for JSON strings.
+func asString(v interface{}, format string, args ...interface{}) string {
+	s, ok := v.(string)
+	if !ok {
+		panicf("%s is not a JSON string", fmt.Sprintf(format, args...))
+	}
+	return s
+}
+
 // jobj looks up the list of JSON objects indexed by key in m.
 func jobjlist(m map[string]interface{}, key string) []map[string]interface{} {
 	si, ok := m[key].([]interface{})
@@ -2444,7 +4481,7 @@ func jobjlist(m map[string]interface{}, key string) []map[string]interface{} {
 	}
 	var sl []map[string]interface{}
 	for _, si := range si {
-		sl = append(sl, si.(map[string]interface{}))
+		sl = append(sl, asObject(si, "element of %q", key))
 	}
 	return sl
 }
@@ -2456,7 +4493,7 @@ func jstrlist(m map[string]interface{}, key string) []string {
 	}
 	sl := make([]string, 0)
 	for _, si := range si {
-		sl = append(sl, si.(string))
+		sl = append(sl, asString(si, "element of %q", key))
 	}
 	return sl
 }
@@ -3,8 +3,13 @@ package main
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -18,48 +23,81 @@ func TestAPIs(t *testing.T) {
 		"arrayofmapofobjects",
 		"arrayofmapofstrings",
 		"blogger-3",
+		"bodyless",
 		"getwithoutbody",
+		"labels",
+		"links",
 		"mapofany",
 		"mapofarrayofobjects",
 		"mapofobjects",
 		"mapofstrings-1",
+		"media-download",
+		"media-upload",
+		"noresources",
+		"noschemas",
 		"param-rename",
 		"quotednum",
+		"recursive",
 		"repeated",
+		"required-fields",
 		"resource-named-service", // blogger/v3/blogger-api.json + s/BlogUserInfo/Service/
 		"unfortunatedefaults",
+		"userproject",
 		"variants",
 		"wrapnewlines",
 	}
 	for _, name := range names {
-		api, err := apiFromFile(filepath.Join("testdata", name+".json"))
-		if err != nil {
-			t.Errorf("Error loading API testdata/%s.json: %v", name, err)
-			continue
-		}
-		clean, err := api.GenerateCode()
-		if err != nil {
-			t.Errorf("Error generating code for %s: %v", name, err)
-			continue
-		}
-		goldenFile := filepath.Join("testdata", name+".want")
-		if *updateGolden {
-			if err := ioutil.WriteFile(goldenFile, clean, 0644); err != nil {
-				t.Fatal(err)
-			}
-		}
-		want, err := ioutil.ReadFile(goldenFile)
-		if err != nil {
-			t.Error(err)
-			continue
-		}
-		if !bytes.Equal(want, clean) {
-			tf, _ := ioutil.TempFile("", "api-"+name+"-got-json.")
-			tf.Write(clean)
-			tf.Close()
-			t.Errorf("Output for API %s differs: diff -u %s %s", name, goldenFile, tf.Name())
+		checkGolden(t, name)
+	}
+}
+
+// TestFlaggedAPIs checks the output of generator modes enabled by flags.
+func TestFlaggedAPIs(t *testing.T) {
+	tests := []struct {
+		name string
+		flag *bool
+	}{
+		{"builders", builders},
+		{"pointers", pointers},
+		{"interfaces", interfaces},
+	}
+	for _, tt := range tests {
+		*tt.flag = true
+		checkGolden(t, tt.name)
+		*tt.flag = false
+	}
+}
+
+// checkGolden generates code for testdata/name.json and compares it
+// with testdata/name.want.
+func checkGolden(t *testing.T, name string) {
+	api, err := apiFromFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Errorf("Error loading API testdata/%s.json: %v", name, err)
+		return
+	}
+	clean, err := api.GenerateCode()
+	if err != nil {
+		t.Errorf("Error generating code for %s: %v", name, err)
+		return
+	}
+	goldenFile := filepath.Join("testdata", name+".want")
+	if *updateGolden {
+		if err := ioutil.WriteFile(goldenFile, clean, 0644); err != nil {
+			t.Fatal(err)
 		}
 	}
+	want, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(want, clean) {
+		tf, _ := ioutil.TempFile("", "api-"+name+"-got-json.")
+		tf.Write(clean)
+		tf.Close()
+		t.Errorf("Output for API %s differs: diff -u %s %s", name, goldenFile, tf.Name())
+	}
 }
 
 func TestScope(t *testing.T) {
@@ -175,3 +213,333 @@ func TestRenameVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateCodeError(t *testing.T) {
+	// The "items" property is an array without an "items" key, which the
+	// generator cannot handle.
+	const doc = `{
+		"id": "bad:v1",
+		"name": "bad",
+		"version": "v1",
+		"rootUrl": "https://www.googleapis.com/",
+		"servicePath": "bad/v1/",
+		"schemas": {
+			"List": {
+				"id": "List",
+				"type": "object",
+				"properties": {"items": {"type": "array"}}
+			}
+		}
+	}`
+	api := &API{forceJSON: []byte(doc)}
+	code, err := api.GenerateCode()
+	if err == nil {
+		t.Fatal("got nil error, want one")
+	}
+	if code != nil {
+		t.Errorf("got code %q, want nil", code)
+	}
+}
+
+func TestGenDirRootNoGOPATH(t *testing.T) {
+	defer func(dir, gopath string) {
+		*genDir = dir
+		os.Setenv("GOPATH", gopath)
+	}(*genDir, os.Getenv("GOPATH"))
+	*genDir = ""
+	os.Setenv("GOPATH", "")
+
+	if _, err := genDirRoot(); err == nil {
+		t.Error("genDirRoot succeeded without GOPATH or -gendir")
+	}
+	api := &API{ID: "tasks:v1", Name: "tasks", Version: "v1"}
+	if _, err := api.SourceDir(); err == nil {
+		t.Error("SourceDir succeeded without GOPATH or -gendir")
+	}
+
+	*genDir = "/gen"
+	if got, err := api.SourceDir(); err != nil || got != filepath.FromSlash("/gen/tasks/v1") {
+		t.Errorf("SourceDir with -gendir = %q, %v, want %q", got, err, filepath.FromSlash("/gen/tasks/v1"))
+	}
+}
+
+// TestMalformedDiscovery checks that malformed discovery documents are
+// reported as errors describing the problem, not as internal errors.
+func TestMalformedDiscovery(t *testing.T) {
+	const head = `{"id": "bad:v1", "name": "bad", "version": "v1", "rootUrl": "https://www.googleapis.com/", "servicePath": "bad/v1/", `
+	for _, tt := range []struct {
+		desc, doc, want string
+	}{
+		{"schema not an object", `"schemas": {"Item": "string"}}`, `schema "Item" is not a JSON object`},
+		{"resource not an object", `"resources": {"items": []}}`, `resource "items" is not a JSON object`},
+		{"method not an object", `"resources": {"items": {"methods": {"get": 1}}}}`, `method "get" is not a JSON object`},
+		{
+			"parameterOrder not strings",
+			`"resources": {"items": {"methods": {"get": {"id": "bad.items.get", "path": "items", "httpMethod": "GET", "parameterOrder": [1]}}}}}`,
+			`parameterOrder of method "bad.items.get" is not a JSON string`,
+		},
+		{
+			"parameter without location",
+			`"resources": {"items": {"methods": {"get": {"id": "bad.items.get", "path": "items", "httpMethod": "GET", "parameters": {"x": {"type": "string"}}}}}}}`,
+			`unsupported location ""`,
+		},
+		{
+			"undefined reference",
+			`"schemas": {"Item": {"id": "Item", "type": "object", "properties": {"x": {"$ref": "Missing"}}}}}`,
+			`reference to undefined schema "Missing"`,
+		},
+		{
+			"unsupported external reference",
+			`"schemas": {"Item": {"id": "Item", "type": "object", "properties": {"x": {"$ref": "https://example.com/doc#/definitions/X"}}}}}`,
+			`unsupported reference`,
+		},
+		{
+			"reference cycle",
+			`"schemas": {"A": {"id": "A", "$ref": "B"}, "B": {"id": "B", "$ref": "A"}}}`,
+			`schema "A" refers to itself`,
+		},
+		{
+			"array of itself",
+			`"schemas": {"List": {"id": "List", "type": "array", "items": {"$ref": "List"}}, "Item": {"id": "Item", "type": "object", "properties": {"list": {"$ref": "List"}}}}}`,
+			`schema "List" refers to itself`,
+		},
+	} {
+		api, err := apiFromJSON([]byte(head + tt.doc))
+		if err != nil {
+			t.Errorf("%s: %v", tt.desc, err)
+			continue
+		}
+		_, err = api.GenerateCode()
+		if _, ok := err.(*internalError); ok || err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.desc, err, tt.want)
+		}
+	}
+}
+
+func TestExternalRefs(t *testing.T) {
+	const shared = "https://www.googleapis.com/discovery/v1/apis/shared/v1/rest"
+	docs := map[string]string{
+		shared: `{"name": "shared", "schemas": {
+			"Money": {"id": "Money", "type": "object", "properties": {"amount": {"type": "string"}, "currency": {"$ref": "Currency"}}},
+			"Currency": {"id": "Currency", "type": "object", "properties": {"code": {"type": "string"}, "rate": {"$ref": "Money"}}}
+		}}`,
+	}
+	defer func(f func(string) ([]byte, error)) { fetchExternalDoc = f }(fetchExternalDoc)
+	var fetches int
+	fetchExternalDoc = func(url string) ([]byte, error) {
+		fetches++
+		doc, ok := docs[url]
+		if !ok {
+			return nil, fmt.Errorf("no document at %s", url)
+		}
+		return []byte(doc), nil
+	}
+
+	api, err := apiFromJSON([]byte(`{"id": "shop:v1", "name": "shop", "version": "v1", "rootUrl": "https://www.googleapis.com/", "servicePath": "shop/v1/",
+		"schemas": {
+			"Currency": {"id": "Currency", "type": "string"},
+			"Item": {"id": "Item", "type": "object", "properties": {
+				"price": {"$ref": "` + shared + `#/schemas/Money"},
+				"cost": {"$ref": "` + shared + `#/schemas/Money"}
+			}}
+		}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := api.GenerateCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type Money struct",
+		"Currency *SharedCurrency `json:\"currency,omitempty\"`",
+		"type SharedCurrency struct",
+		"Rate *Money `json:\"rate,omitempty\"`",
+		"Price *Money `json:\"price,omitempty\"`",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q", want)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetched %d documents, want 1", fetches)
+	}
+
+	api, err = apiFromJSON([]byte(`{"id": "shop:v1", "name": "shop", "version": "v1", "rootUrl": "https://www.googleapis.com/", "servicePath": "shop/v1/",
+		"schemas": {"Item": {"id": "Item", "type": "object", "properties": {"price": {"$ref": "` + shared + `#/schemas/Price"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GenerateCode(); err == nil || !strings.Contains(err.Error(), `defines no schema "Price"`) {
+		t.Errorf("got error %v, want one for the missing schema", err)
+	}
+}
+
+func TestSchemaDepth(t *testing.T) {
+	const doc = `{"id": "loop:v1", "name": "loop", "version": "v1", "rootUrl": "https://www.googleapis.com/", "servicePath": "loop/v1/",
+		"schemas": {
+			"A": {"id": "A", "type": "object", "properties": {"b": {"$ref": "B"}}},
+			"B": {"id": "B", "type": "object", "properties": {"a": {"$ref": "A"}}},
+			"C": {"id": "C", "type": "object", "properties": {"a": {"$ref": "A"}}}
+		}}`
+	want := map[string]int{"A": 2, "B": 2, "C": 3}
+	// The depths must not depend on the order in which they are computed.
+	for _, order := range [][]string{{"A", "B", "C"}, {"B", "A", "C"}, {"C", "B", "A"}} {
+		api, err := apiFromJSON([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.GenerateCode(); err != nil {
+			t.Fatal(err)
+		}
+		api.builderDepth = make(map[string]int)
+		for _, name := range order {
+			if got := api.schemaDepth(api.schemas[name]); got != want[name] {
+				t.Errorf("order %v: depth of %s = %d, want %d", order, name, got, want[name])
+			}
+		}
+	}
+}
+
+func TestEnumConstName(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"confirmed", "Confirmed"},
+		{"clickUrl", "ClickUrl"},
+		{"TYPE_UNSPECIFIED", "TypeUnspecified"},
+		{"image/png", "ImagePng"},
+		{"v1.2", "V12"},
+	} {
+		if got := enumConstName(tt.in); got != tt.want {
+			t.Errorf("enumConstName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	defer func(v bool) { *verbose, vlogf = v, log.Printf }(*verbose)
+	var lines []string
+	vlogf = func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	*verbose = true
+
+	api, err := apiFromFile(filepath.Join("testdata", "blogger-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(lines, "\n")
+	for _, want := range []string{
+		`blogger:v3: method id=blogger.comments.list http=GET path="blogs/{blogId}/posts/{postId}/comments" func=CommentsService.List call=CommentsListCall response="*CommentList" paging=nextPageToken upload=false download=false`,
+		`blogger:v3: method id=blogger.comments.list arg=blogId location=path go=blogId type=string`,
+		`blogger:v3: method id=blogger.comments.list param=statuses location=query setter=Statuses type=string repeated=true`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log does not contain %q", want)
+		}
+	}
+}
+
+func TestExcludeLabels(t *testing.T) {
+	defer func(x string, j string) { *excludeLabels, *jsonFile = x, j }(*excludeLabels, *jsonFile)
+	*jsonFile = filepath.Join("testdata", "labels.json")
+	api := &API{ID: "labels:v1", Labels: []string{"limited_availability", "deprecated"}}
+	for _, tt := range []struct {
+		exclude string
+		want    bool
+	}{
+		{"", true},
+		{"beta", true},
+		{"beta, deprecated", false},
+		{"limited_availability", false},
+	} {
+		*excludeLabels = tt.exclude
+		if got := api.want(); got != tt.want {
+			t.Errorf("-exclude_labels=%q: want() = %v, want %v", tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestURLCacheFile(t *testing.T) {
+	long := "https://www.googleapis.com/discovery/v1/apis/x/v1/rest?fields=" + strings.Repeat("a,", 200)
+	for _, u := range []string{"https://example.com/a?b=c:d", long} {
+		name := filepath.Base(urlCacheFile(u))
+		if len(name) > 64 {
+			t.Errorf("urlCacheFile(%q) has %d-character name %q", u, len(name), name)
+		}
+		if strings.ContainsAny(name, `<>:"/\|?*%`) {
+			t.Errorf("urlCacheFile(%q) = %q, which is not a valid file name on every system", u, name)
+		}
+	}
+	if urlCacheFile(long) == urlCacheFile(long+"a") {
+		t.Error("different URLs share a cache file")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "x-gen.go")
+	for _, contents := range []string{"old", "new"} {
+		if err := writeFile(file, []byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != contents {
+			t.Errorf("got %q, want %q", got, contents)
+		}
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 {
+		var names []string
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		t.Errorf("directory contains %q, want only x-gen.go", names)
+	}
+	if fi, err := os.Stat(file); err == nil && fi.Mode().Perm() != 0644 {
+		t.Errorf("mode %v, want 0644", fi.Mode().Perm())
+	}
+}
+
+func TestPostHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "posthook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(h string) { *postHook = h }(*postHook)
+	*postHook = `echo "$API_ID $OUT_DIR" > hook.out`
+	if runtime.GOOS == "windows" {
+		*postHook = `echo %API_ID% %OUT_DIR%> hook.out`
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := runPostHook(&API{ID: "tasks:v1"}, "/out/tasks/v1"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "hook.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "tasks:v1 /out/tasks/v1"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("hook wrote %q, want %q", got, want)
+	}
+}
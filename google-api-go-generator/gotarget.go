@@ -0,0 +1,41 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// goTargetPackages maps the releases accepted by -gotarget to the
+// package paths they imply for flags which were not set. Generated code
+// has always used the Go 1 names of the standard library, so only the
+// packages which later moved into it differ.
+var goTargetPackages = map[string]map[string]string{
+	"go1":   {},
+	"go1.7": {"context_pkg": "context"},
+}
+
+// setFlags returns the names of the flags set on the command line.
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// applyGoTarget sets the package path flags not in set to those implied
+// by target, a value of -gotarget.
+func applyGoTarget(target string, set map[string]bool) error {
+	pkgs, ok := goTargetPackages[target]
+	if !ok {
+		return fmt.Errorf("-gotarget must be go1 or go1.7, not %q", target)
+	}
+	for name, path := range pkgs {
+		if !set[name] {
+			flag.Set(name, path)
+		}
+	}
+	return nil
+}
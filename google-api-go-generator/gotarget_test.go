@@ -0,0 +1,33 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestApplyGoTarget(t *testing.T) {
+	defer func(old string) { *contextPkg = old }(*contextPkg)
+	const xcontext = "golang.org/x/net/context"
+
+	for _, tt := range []struct {
+		target string
+		set    map[string]bool
+		want   string
+	}{
+		{"go1", nil, xcontext},
+		{"go1.7", nil, "context"},
+		{"go1.7", map[string]bool{"context_pkg": true}, xcontext},
+	} {
+		*contextPkg = xcontext
+		if err := applyGoTarget(tt.target, tt.set); err != nil {
+			t.Fatal(err)
+		}
+		if *contextPkg != tt.want {
+			t.Errorf("applyGoTarget(%q, %v): -context_pkg is %q, want %q", tt.target, tt.set, *contextPkg, tt.want)
+		}
+	}
+	if err := applyGoTarget("go0.9", nil); err == nil {
+		t.Error("applyGoTarget(go0.9): got nil error, want one")
+	}
+}
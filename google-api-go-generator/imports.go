@@ -0,0 +1,157 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// importSpec is a package which generated code may refer to.
+type importSpec struct {
+	path  string
+	lname string // local name, if the import is renamed
+}
+
+// name returns the name by which code refers to the package.
+func (imp importSpec) name() string {
+	if imp.lname != "" {
+		return imp.lname
+	}
+	return path.Base(imp.path)
+}
+
+// isStd reports whether imp is a standard library package, which
+// goimports places in a group before all others.
+func (imp importSpec) isStd() bool {
+	return !strings.Contains(strings.SplitN(imp.path, "/", 2)[0], ".")
+}
+
+// helperImports returns the packages which generated code may refer to.
+func helperImports() []importSpec {
+	return []importSpec{
+		{"bytes", ""},
+		{"encoding/json", ""},
+		{"errors", ""},
+		{"fmt", ""},
+		{"io", ""},
+		{"net/http", ""},
+		{"net/url", ""},
+		{"strconv", ""},
+		{"strings", ""},
+		{*contextHTTPPkg, "ctxhttp"},
+		{*contextPkg, "context"},
+		{*gensupportPkg, "gensupport"},
+		{*googleapiPkg, "googleapi"},
+	}
+}
+
+// applyRuntimeImport sets -googleapi_pkg and -gensupport_pkg, unless
+// they are in set, to the packages of those names below base, the value
+// of -runtime_import. An empty base changes nothing.
+func applyRuntimeImport(base string, set map[string]bool) {
+	if base == "" {
+		return
+	}
+	base = strings.TrimSuffix(base, "/")
+	if !set["googleapi_pkg"] {
+		*googleapiPkg = base + "/googleapi"
+	}
+	if !set["gensupport_pkg"] {
+		*gensupportPkg = base + "/gensupport"
+	}
+}
+
+// importBlock returns the import declaration for body, the generated code
+// following the package clause. It imports just the helper packages which
+// body refers to, along with those needed by type overrides, grouping
+// standard library packages first as goimports does.
+func (a *API) importBlock(body []byte) ([]byte, error) {
+	used, err := referencedNames(body)
+	if err != nil {
+		return nil, err
+	}
+	var imps []importSpec
+	seen := make(map[string]bool)
+	for _, imp := range helperImports() {
+		if used[imp.name()] && !seen[imp.path] {
+			imps = append(imps, imp)
+			seen[imp.path] = true
+		}
+	}
+	for _, p := range a.extraImports {
+		if !seen[p] {
+			imps = append(imps, importSpec{path: p})
+			seen[p] = true
+		}
+	}
+	if len(imps) == 0 {
+		return nil, nil
+	}
+	sort.Sort(byGroupAndPath(imps))
+	a.imports = nil
+	for _, imp := range imps {
+		a.imports = append(a.imports, imp.path)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\nimport (\n")
+	for i, imp := range imps {
+		if i > 0 && imp.isStd() != imps[i-1].isStd() {
+			buf.WriteString("\n")
+		}
+		if imp.lname != "" && imp.lname != path.Base(imp.path) {
+			fmt.Fprintf(&buf, "\t%s %q\n", imp.lname, imp.path)
+		} else {
+			fmt.Fprintf(&buf, "\t%q\n", imp.path)
+		}
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes(), nil
+}
+
+type byGroupAndPath []importSpec
+
+func (s byGroupAndPath) Len() int      { return len(s) }
+func (s byGroupAndPath) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byGroupAndPath) Less(i, j int) bool {
+	if s[i].isStd() != s[j].isStd() {
+		return s[i].isStd()
+	}
+	return s[i].path < s[j].path
+}
+
+// referencedNames returns the names of the packages which body, a file
+// lacking its package clause and imports, refers to: the identifiers
+// qualifying selector expressions which are not declared in body.
+func referencedNames(body []byte) (map[string]bool, error) {
+	src := append([]byte("package p\n"), body...)
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	return qualifiers(f), nil
+}
+
+// qualifiers returns the undeclared identifiers which qualify selector
+// expressions in f.
+func qualifiers(f *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
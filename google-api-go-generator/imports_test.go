@@ -0,0 +1,62 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestGoldenImports checks that each golden file imports exactly the
+// helper packages which it uses.
+func TestGoldenImports(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.want"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		used := qualifiers(f)
+		imported := make(map[string]bool)
+		for _, imp := range f.Imports {
+			p, _ := strconv.Unquote(imp.Path.Value)
+			name := path.Base(p)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			imported[name] = true
+			if !used[name] {
+				t.Errorf("%s: %q imported but not used", file, p)
+			}
+		}
+		for _, imp := range helperImports() {
+			if used[imp.name()] && !imported[imp.name()] {
+				t.Errorf("%s: %q used but not imported", file, imp.path)
+			}
+		}
+	}
+}
+
+func TestApplyRuntimeImport(t *testing.T) {
+	defer func(googleapi, gensupport string) {
+		*googleapiPkg, *gensupportPkg = googleapi, gensupport
+	}(*googleapiPkg, *gensupportPkg)
+
+	applyRuntimeImport("github.com/example/api/", map[string]bool{"gensupport_pkg": true})
+	if want := "github.com/example/api/googleapi"; *googleapiPkg != want {
+		t.Errorf("-googleapi_pkg is %q, want %q", *googleapiPkg, want)
+	}
+	if want := "google.golang.org/api/gensupport"; *gensupportPkg != want {
+		t.Errorf("-gensupport_pkg, which was set, is %q, want %q", *gensupportPkg, want)
+	}
+}
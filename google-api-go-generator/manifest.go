@@ -0,0 +1,92 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A packageManifest lists the packages generated in a run of the
+// generator, and the packages outside the standard library which they
+// import, for vendoring tools. It is written by -manifest=json.
+type packageManifest struct {
+	Packages     []manifestPackage `json:"packages"`
+	Dependencies []string          `json:"dependencies"`
+}
+
+type manifestPackage struct {
+	ID         string `json:"id"`         // API ID, e.g. "tasks:v1"
+	ImportPath string `json:"importPath"` // e.g. "google.golang.org/api/tasks/v1"
+	Dir        string `json:"dir"`        // slash-separated, relative to -gendir
+}
+
+// newManifest returns the manifest for the generated packages of apis.
+func newManifest(apis []*API) *packageManifest {
+	m := &packageManifest{Dependencies: []string{}}
+	generated := make(map[string]bool)
+	for _, a := range apis {
+		m.Packages = append(m.Packages, manifestPackage{ID: a.ID, ImportPath: a.Target(), Dir: a.relPath()})
+		generated[a.Target()] = true
+	}
+	seen := make(map[string]bool)
+	for _, a := range apis {
+		for _, imp := range a.imports {
+			if seen[imp] || generated[imp] || (importSpec{path: imp}).isStd() {
+				continue
+			}
+			seen[imp] = true
+			m.Dependencies = append(m.Dependencies, imp)
+		}
+	}
+	sort.Sort(byImportPath(m.Packages))
+	sort.Strings(m.Dependencies)
+	return m
+}
+
+// writeManifest writes the manifest of the packages generated for apis
+// to dir, as go.mod if format is "gomod" or as packages.json if it is
+// "json". The go.mod file declares the module and lists the packages in
+// comments; "go mod tidy" adds the requirements with their versions.
+func writeManifest(dir, format string, apis []*API) error {
+	m := newManifest(apis)
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(m, "", " ")
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, "packages.json"), append(b, '\n'))
+	case "gomod":
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "module %s\n", *apiPackageBase)
+		buf.WriteString("\n// Generated packages:\n")
+		for _, p := range m.Packages {
+			fmt.Fprintf(&buf, "//\t%s\n", p.ImportPath)
+		}
+		if len(m.Dependencies) > 0 {
+			buf.WriteString("//\n// Imported packages:\n")
+			for _, d := range m.Dependencies {
+				if strings.HasPrefix(d, *apiPackageBase+"/") {
+					fmt.Fprintf(&buf, "//\t%s (in this module)\n", d)
+				} else {
+					fmt.Fprintf(&buf, "//\t%s\n", d)
+				}
+			}
+		}
+		return writeFile(filepath.Join(dir, "go.mod"), buf.Bytes())
+	}
+	return fmt.Errorf("unknown manifest format %q", format)
+}
+
+type byImportPath []manifestPackage
+
+func (s byImportPath) Len() int           { return len(s) }
+func (s byImportPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byImportPath) Less(i, j int) bool { return s[i].ImportPath < s[j].ImportPath }
@@ -0,0 +1,81 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	var apis []*API
+	for _, name := range []string{"noresources", "bodyless"} {
+		api, err := apiFromFile(filepath.Join("testdata", name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := api.GenerateCode(); err != nil {
+			t.Fatal(err)
+		}
+		apis = append(apis, api)
+	}
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeManifest(dir, "json", apis); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "packages.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got packageManifest
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := packageManifest{
+		Packages: []manifestPackage{
+			{"bodyless:v1", "google.golang.org/api/bodyless/v1", "bodyless/v1"},
+			{"noresources:v1", "google.golang.org/api/noresources/v1", "noresources/v1"},
+		},
+		Dependencies: []string{
+			"golang.org/x/net/context",
+			"google.golang.org/api/gensupport",
+			"google.golang.org/api/googleapi",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got manifest %+v, want %+v", got, want)
+	}
+
+	if err := writeManifest(dir, "gomod", apis); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantMod = `module google.golang.org/api
+
+// Generated packages:
+//	google.golang.org/api/bodyless/v1
+//	google.golang.org/api/noresources/v1
+//
+// Imported packages:
+//	golang.org/x/net/context
+//	google.golang.org/api/gensupport (in this module)
+//	google.golang.org/api/googleapi (in this module)
+`
+	if string(b) != wantMod {
+		t.Errorf("go.mod:\n%s\nwant:\n%s", b, wantMod)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+)
+
+// overrides holds the contents of the file named by -overrides, keyed by
+// API ID (e.g. "storage:v1"). It lets forks choose better Go names than
+// the generator's defaults without editing generated code. For example:
+//
+//   {
+//     "storage:v1": {
+//       "schemas": {
+//         "Bucket": {
+//           "goName": "StorageBucket",
+//           "fields": {
+//             "type": {"goName": "Kind", "goType": "string"}
+//           }
+//         }
+//       }
+//     }
+//   }
+//
+// Schemas are keyed by their discovery name, including the synthetic
+// names of nested schemas (e.g. "Bucket.cors"); fields by their JSON
+// property name.
+//
+// The Go package name may be changed with "package", for instance to
+// avoid clashing with another package used alongside the API:
+//
+//   {"logging:v2": {"package": "cloudlogging"}}
+//
+// A field's type may also be replaced by a type from another package,
+// either for that field alone or for every simple field of an API with a
+// given discovery format. If the new type does not marshal to the field's
+// JSON form itself, helper functions may be given to convert between the
+// two:
+//
+//   {
+//     "container:v1": {
+//       "formats": {
+//         "google-duration": {
+//           "goType": "time.Duration",
+//           "import": "time",
+//           "marshal": "durationjson.Format",
+//           "unmarshal": "durationjson.Parse",
+//           "helperImport": "example.com/durationjson"
+//         }
+//       }
+//     }
+//   }
+//
+// Here durationjson.Format has type func(time.Duration) (string, error)
+// and durationjson.Parse has type func(string) (time.Duration, error),
+// string being the type the field would have had without the override.
+var overrides map[string]*apiOverride
+
+type apiOverride struct {
+	Package string                     `json:"package"` // Go package name
+	Schemas map[string]*schemaOverride `json:"schemas"`
+	Formats map[string]*typeOverride   `json:"formats"` // keyed by discovery format
+}
+
+type schemaOverride struct {
+	GoName string                    `json:"goName"`
+	Fields map[string]*fieldOverride `json:"fields"`
+}
+
+type fieldOverride struct {
+	GoName string `json:"goName"`
+	typeOverride
+}
+
+type typeOverride struct {
+	GoType       string `json:"goType"`       // e.g. "string", "[]int64" or "money.Amount"
+	Import       string `json:"import"`       // import path of the package of GoType, if any
+	Marshal      string `json:"marshal"`      // converts GoType to the field's default type
+	Unmarshal    string `json:"unmarshal"`    // converts the field's default type to GoType
+	HelperImport string `json:"helperImport"` // import path of the package of Marshal and Unmarshal, if any
+}
+
+// hasHelpers reports whether fields of type t are converted by helper
+// functions when marshaled and unmarshaled.
+func (t *typeOverride) hasHelpers() bool {
+	return t.Marshal != ""
+}
+
+func (t *typeOverride) check() error {
+	if t.GoType == "" && (t.Import != "" || t.Marshal != "" || t.Unmarshal != "" || t.HelperImport != "") {
+		return fmt.Errorf("goType must be set")
+	}
+	if (t.Marshal == "") != (t.Unmarshal == "") {
+		return fmt.Errorf("marshal and unmarshal must be set together")
+	}
+	if t.Marshal == "" && t.HelperImport != "" {
+		return fmt.Errorf("helperImport set without marshal and unmarshal")
+	}
+	return nil
+}
+
+var exportedIdent = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*$`)
+
+// packageIdent matches conventional package names.
+var packageIdent = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// loadOverrides reads and validates an overrides file.
+func loadOverrides(file string) (map[string]*apiOverride, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var o map[string]*apiOverride
+	if err := json.Unmarshal(b, &o); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", file, err)
+	}
+	for id, ao := range o {
+		if ao.Package != "" && !packageIdent.MatchString(ao.Package) {
+			return nil, fmt.Errorf("%s: %s: package %q is not a valid package name", file, id, ao.Package)
+		}
+		for schema, so := range ao.Schemas {
+			if so.GoName != "" && !exportedIdent.MatchString(so.GoName) {
+				return nil, fmt.Errorf("%s: %s schema %s: goName %q is not an exported Go identifier", file, id, schema, so.GoName)
+			}
+			for field, fo := range so.Fields {
+				if fo.GoName != "" && !exportedIdent.MatchString(fo.GoName) {
+					return nil, fmt.Errorf("%s: %s field %s.%s: goName %q is not an exported Go identifier", file, id, schema, field, fo.GoName)
+				}
+				if err := fo.check(); err != nil {
+					return nil, fmt.Errorf("%s: %s field %s.%s: %v", file, id, schema, field, err)
+				}
+			}
+		}
+		for format, to := range ao.Formats {
+			if to.GoType == "" {
+				return nil, fmt.Errorf("%s: %s format %s: goType must be set", file, id, format)
+			}
+			if err := to.check(); err != nil {
+				return nil, fmt.Errorf("%s: %s format %s: %v", file, id, format, err)
+			}
+		}
+	}
+	return o, nil
+}
+
+// schemaOverride returns the override for the schema with the given
+// discovery name, or nil.
+func (a *API) schemaOverride(apiName string) *schemaOverride {
+	ao := overrides[a.ID]
+	if ao == nil {
+		return nil
+	}
+	return ao.Schemas[apiName]
+}
+
+// refGoName returns the Go name of the schema referred to by ref.
+func (a *API) refGoName(ref string) string {
+	if so := a.schemaOverride(ref); so != nil && so.GoName != "" {
+		return so.GoName
+	}
+	return ref
+}
+
+// override returns the override for p, or nil.
+func (p *Property) override() *fieldOverride {
+	so := p.s.api.schemaOverride(p.s.apiName)
+	if so == nil {
+		return nil
+	}
+	return so.Fields[p.apiName]
+}
+
+// typeOverride returns the replacement type for p, or nil. An override
+// for p itself takes precedence over one for its format.
+func (p *Property) typeOverride() *typeOverride {
+	if o := p.override(); o != nil && o.GoType != "" {
+		return &o.typeOverride
+	}
+	ao := overrides[p.s.api.ID]
+	if ao == nil || !p.Type().IsSimple() {
+		return nil
+	}
+	return ao.Formats[p.Type().apiTypeFormat()]
+}
+
+// addImport records that the generated code uses the package with the
+// given import path.
+func (a *API) addImport(path string) {
+	if path != "" {
+		a.extraImports = appendUnique(a.extraImports, path)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	defer func() { overrides = nil }()
+	for _, name := range []string{"overrides", "type-overrides"} {
+		o, err := loadOverrides(filepath.Join("testdata", name+"-config.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		overrides = o
+		checkGolden(t, name)
+	}
+}
+
+func TestLoadOverridesInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		desc, config string
+	}{
+		{"unexported goName", `{"storage:v1": {"schemas": {"Bucket": {"goName": "bucket"}}}}`},
+		{"import without goType", `{"storage:v1": {"schemas": {"Bucket": {"fields": {"id": {"import": "time"}}}}}}`},
+		{"marshal without unmarshal", `{"storage:v1": {"formats": {"int64": {"goType": "big.Int", "marshal": "f"}}}}`},
+		{"format without goType", `{"storage:v1": {"formats": {"int64": {}}}}`},
+		{"invalid package", `{"storage:v1": {"package": "Storage"}}`},
+	} {
+		f, err := ioutil.TempFile("", "overrides")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(tt.config)
+		f.Close()
+		if _, err := loadOverrides(f.Name()); err == nil {
+			t.Errorf("%s: got nil error, want one", tt.desc)
+		}
+		os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ownedFilesName names the file, in each package directory, listing the
+// files the generator last wrote there, one name per line. Files listed
+// there which a later run does not write, because the API or its package
+// name changed, are removed. Files not listed are never touched.
+const ownedFilesName = ".generated-files"
+
+// updateOwnedFiles removes the files of dir which the previous run wrote
+// and the current one did not, and records written as the files now
+// owned by the generator.
+func updateOwnedFiles(dir string, written []string) error {
+	list := filepath.Join(dir, ownedFilesName)
+	keep := make(map[string]bool)
+	for _, name := range written {
+		keep[name] = true
+	}
+	old, err := ioutil.ReadFile(longPath(list))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, name := range strings.Split(string(old), "\n") {
+		if name == "" || keep[name] || name != filepath.Base(name) || name == ".." {
+			continue
+		}
+		if err := removeFile(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	names := append([]string(nil), written...)
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+	return writeFile(list, buf.Bytes())
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateOwnedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "owned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"old-gen.go", "old-api.json", "handwritten.go"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := updateOwnedFiles(dir, []string{"old-api.json", "old-gen.go"}); err != nil {
+		t.Fatal(err)
+	}
+	// The package was renamed: the files of the old name are stale.
+	if err := updateOwnedFiles(dir, []string{"new-gen.go", "new-api.json"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"old-gen.go":     false,
+		"old-api.json":   false,
+		"handwritten.go": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists: %v, want %v", name, got, want)
+		}
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, ownedFilesName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "new-api.json\nnew-gen.go\n"; string(got) != want {
+		t.Errorf("%s = %q, want %q", ownedFilesName, got, want)
+	}
+}
+
+func TestUpdateOwnedFilesOutsideDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "owned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "pkg")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(root, "outside.go")
+	if err := ioutil.WriteFile(outside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ownedFilesName), []byte("../outside.go\n..\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateOwnedFiles(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the package directory was removed: %v", err)
+	}
+}
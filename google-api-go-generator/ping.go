@@ -0,0 +1,86 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// pingLimits are the parameters which limit the results of a list method,
+// set to 1 by Ping to keep its response small.
+var pingLimits = []string{"maxResults", "pageSize"}
+
+// pingMethod returns the method called by the Ping method of a's Service:
+// a GET method without required parameters, preferring one whose results
+// can be limited, or nil if there is none.
+func (a *API) pingMethod(reslist []*Resource) (meth *Method, limit string) {
+	for _, m := range a.allMethods(reslist) {
+		if jstr(m.m, "httpMethod") != "GET" || len(m.grepParams(func(p *Param) bool { return p.IsRequired() })) > 0 {
+			continue
+		}
+		for _, name := range pingLimits {
+			if len(m.grepParams(func(p *Param) bool { return p.name == name && p.GoType() != "string" })) > 0 {
+				return m, name
+			}
+		}
+		if meth == nil {
+			meth = m
+		}
+	}
+	return meth, ""
+}
+
+// writePing writes the Ping method of a's Service.
+func (a *API) writePing(reslist []*Resource) {
+	a.GetName("Ping") // ignore return value; reserved for the Service method
+	for _, m := range a.APIMethods() {
+		if initialCap(m.name) == "Ping" {
+			vlogf("%s: method id=%s takes the name of Service.Ping", a.ID, m.Id())
+			return
+		}
+	}
+	for _, res := range reslist {
+		if res.GoField() == "Ping" {
+			vlogf("%s: resource %s takes the name of Service.Ping", a.ID, res.name)
+			return
+		}
+	}
+	meth, limit := a.pingMethod(reslist)
+	if meth == nil {
+		a.p("%s", asComment("", "Ping checks, for instance at startup, that s is configured so that calls can succeed: "+
+			"that the network, credentials and base URL are sound. "+
+			"As the API has no method cheap enough to call, it sends an OPTIONS request to the base URL."))
+		a.pn("func (s *Service) Ping(ctx context.Context) *googleapi.PingResult {")
+		a.pn(" return gensupport.PingOptions(ctx, s.client, s.BasePath, s.userAgent())")
+		a.pn("}\n")
+		return
+	}
+	a.p("%s", asComment("", "Ping checks, for instance at startup, that s is configured so that calls can succeed: "+
+		"that the network, credentials and base URL are sound. "+
+		"It calls "+meth.Id()+", the cheapest method of the API."))
+	a.pn("func (s *Service) Ping(ctx context.Context) *googleapi.PingResult {")
+	call := meth.serviceCall("s", "")
+	if limit != "" {
+		call += "." + initialCap(limit) + "(1)"
+	}
+	a.pn(" c := %s.Context(ctx)", call)
+	a.pn(" return gensupport.Ping(%q, func() (*http.Response, error) {", meth.Id())
+	a.pn(`  return c.doRequest("json")`)
+	a.pn(" })")
+	a.pn("}\n")
+}
+
+// serviceCall returns the Go expression calling meth, with the given
+// arguments, on the Service recv.
+func (meth *Method) serviceCall(recv, args string) string {
+	call := recv
+	if meth.r != nil {
+		for _, name := range strings.Split(meth.r.parent, ".") {
+			if name != "" {
+				call += "." + initialCap(name)
+			}
+		}
+		call += "." + meth.r.GoField()
+	}
+	return call + "." + initialCap(meth.name) + "(" + args + ")"
+}
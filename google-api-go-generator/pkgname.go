@@ -0,0 +1,53 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// reservedPackageNames holds the names of standard library packages, and
+// of packages which generated code uses, that an API's package should not
+// shadow in code importing both.
+var reservedPackageNames = make(map[string]bool)
+
+func init() {
+	for _, name := range strings.Fields(`
+		adler32 aes ascii85 asn1 ast atomic base32 base64 big binary bufio
+		build bytes bzip2 cgi cgo cipher cmplx color constant context
+		cookiejar crc32 crc64 crypto csv debug des doc draw driver dsa dwarf
+		ecdsa elf elliptic encoding errors exec expvar fcgi filepath flag
+		flate fmt fnv format gif gob gosym gzip hash heap hex hmac html http
+		httptest httputil image importer io iotest ioutil jpeg json jsonrpc
+		list log lzw macho mail math md5 mime multipart net os palette parser
+		path pe pem pkix plan9obj png pprof printer quick quotedprintable race
+		rand rc4 reflect regexp ring rpc rsa runtime scanner sha1 sha256
+		sha512 signal smtp sort sql strconv strings subtle suffixarray sync
+		syntax syscall syslog tabwriter tar template testing textproto time
+		tls token trace types unicode unsafe url user utf16 utf8 x509 xml
+		zip zlib
+		ctxhttp gensupport googleapi
+	`) {
+		reservedPackageNames[name] = true
+	}
+}
+
+// PackageName returns the name in the package clause of a's generated
+// code. This is normally Package, or the name combining the API's name
+// and version with -flatpkg, but may be set for an API in the -overrides
+// file. A name which would shadow a reserved package has
+// -pkg_suffix appended, so that, for instance, an API named "log" is
+// generated as package "logapi".
+func (a *API) PackageName() string {
+	if ao := overrides[a.ID]; ao != nil && ao.Package != "" {
+		return ao.Package
+	}
+	name := a.Package()
+	if *flatPkg {
+		name = a.flatName()
+	}
+	if reservedPackageNames[name] {
+		name += *pkgSuffix
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestPackageName(t *testing.T) {
+	defer func() { overrides = nil }()
+	overrides = map[string]*apiOverride{"logging:v2": {Package: "cloudlogging"}}
+	for _, tt := range []struct {
+		id, name, want string
+	}{
+		{"storage:v1", "storage", "storage"},
+		{"log:v1", "log", "logapi"},
+		{"googleapi:v1", "googleapi", "googleapiapi"},
+		{"logging:v2", "logging", "cloudlogging"},
+	} {
+		a := &API{ID: tt.id, Name: tt.name}
+		if got := a.PackageName(); got != tt.want {
+			t.Errorf("%s: got package %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFlatPackage(t *testing.T) {
+	defer func(b string) { *flatPkg, *apiPackageBase = false, b }(*apiPackageBase)
+	*flatPkg, *apiPackageBase = true, "google.golang.org/api"
+	for _, tt := range []struct {
+		name, version, want string
+	}{
+		{"drive", "v3", "drive3"},
+		{"storage", "v1beta2", "storage1beta2"},
+		{"compute", "alpha", "computealpha"},
+		{"admin", "directory_v1", "admindirectory1"},
+		{"admin", "email_migration_v2", "adminemailmigration2"},
+		{"adexchangebuyer", "v1.2", "adexchangebuyer1_2"},
+		{"clouduseraccounts", "vm_beta", "clouduseraccountsvmbeta"},
+	} {
+		a := &API{ID: tt.name + ":" + tt.version, Name: tt.name, Version: tt.version}
+		if got := a.PackageName(); got != tt.want {
+			t.Errorf("%s: got package %q, want %q", a.ID, got, tt.want)
+		}
+		if got, want := a.Target(), "google.golang.org/api/"+tt.want; got != want {
+			t.Errorf("%s: got target %q, want %q", a.ID, got, want)
+		}
+	}
+}
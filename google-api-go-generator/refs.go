@@ -0,0 +1,154 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// fetchExternalDoc returns the discovery document at the given URL.
+// It is overridden in tests.
+var fetchExternalDoc = slurpURL
+
+// An external reference names a schema defined in another discovery
+// document, in the form "<document URL>#/schemas/<name>". Within a
+// schema copied from such a document, plain references name other
+// schemas of that document.
+
+// inlineExternalRefs copies each schema named by an external reference
+// in a.m, together with the schemas it refers to, into a.m's schemas,
+// and rewrites the references to name the copies. A copied schema keeps
+// its name unless a schema of that name already exists, in which case
+// it is prefixed with the name of the API defining it.
+func (a *API) inlineExternalRefs() error {
+	r := &refResolver{
+		schemas: jobj(a.m, "schemas"),
+		docs:    make(map[string]map[string]interface{}),
+		local:   make(map[string]string),
+	}
+	if r.schemas == nil {
+		r.schemas = make(map[string]interface{})
+	}
+	if err := r.walk(a.m, ""); err != nil {
+		return err
+	}
+	if len(r.local) > 0 {
+		a.m["schemas"] = r.schemas
+	}
+	return nil
+}
+
+type refResolver struct {
+	schemas map[string]interface{}            // schemas of the API being generated
+	docs    map[string]map[string]interface{} // fetched documents, by URL
+	local   map[string]string                 // local schema name, by external reference
+}
+
+// walk rewrites the external references in v, which is part of the
+// document at docURL, or of the API being generated if docURL is empty.
+func (r *refResolver) walk(v interface{}, docURL string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if !strings.Contains(ref, "#") {
+				if docURL == "" {
+					return nil
+				}
+				ref = docURL + "#/schemas/" + ref
+			}
+			name, err := r.resolve(ref)
+			if err != nil {
+				return err
+			}
+			v["$ref"] = name
+		}
+		for _, e := range v {
+			if err := r.walk(e, docURL); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if err := r.walk(e, docURL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the local name of the schema named by the external
+// reference ref, copying it into r.schemas if it is not there already.
+func (r *refResolver) resolve(ref string) (string, error) {
+	if name, ok := r.local[ref]; ok {
+		return name, nil
+	}
+	i := strings.Index(ref, "#")
+	docURL, frag := ref[:i], ref[i+1:]
+	if !strings.HasPrefix(frag, "/schemas/") || strings.Count(frag, "/") != 2 {
+		return "", fmt.Errorf("unsupported reference %q: want the form <URL>#/schemas/<name>", ref)
+	}
+	id := strings.TrimPrefix(frag, "/schemas/")
+	doc, err := r.doc(docURL)
+	if err != nil {
+		return "", fmt.Errorf("resolving reference %q: %v", ref, err)
+	}
+	s, ok := jobj(doc, "schemas")[id].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("resolving reference %q: %s defines no schema %q", ref, docURL, id)
+	}
+	name := id
+	if _, ok := r.schemas[name]; ok {
+		name = initialCap(depunct(jstr(doc, "name"), false)) + id
+		if _, ok := r.schemas[name]; ok {
+			return "", fmt.Errorf("resolving reference %q: schema %q already exists", ref, name)
+		}
+	}
+	s = copyJSON(s).(map[string]interface{})
+	s["id"] = name
+	// Record the name before walking the copy, so that references back
+	// to this schema resolve to it.
+	r.local[ref] = name
+	r.schemas[name] = s
+	return name, r.walk(s, docURL)
+}
+
+// doc returns the discovery document at docURL, fetching it only once.
+func (r *refResolver) doc(docURL string) (map[string]interface{}, error) {
+	if doc, ok := r.docs[docURL]; ok {
+		return doc, nil
+	}
+	b, err := fetchExternalDoc(docURL)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", docURL, err)
+	}
+	r.docs[docURL] = doc
+	return doc, nil
+}
+
+// copyJSON returns a deep copy of the decoded JSON value v.
+func copyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copyJSON(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = copyJSON(e)
+		}
+		return l
+	}
+	return v
+}
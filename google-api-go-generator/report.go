@@ -0,0 +1,130 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+)
+
+// apiReport records the outcome of generating a single API.
+type apiReport struct {
+	ID       string   `json:"id"`
+	Success  bool     `json:"success"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Skipped lists discovery features which were not generated,
+	// such as unsupported maps or variants.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// generationReport is written to the file named by -report.
+type generationReport struct {
+	APIs []*apiReport `json:"apis"`
+}
+
+func (r *generationReport) add(a *API, err error) {
+	ar := &apiReport{
+		ID:       a.ID,
+		Success:  err == nil,
+		Warnings: a.warnings,
+		Skipped:  a.skipped,
+	}
+	if err != nil {
+		ar.Error = err.Error()
+	}
+	r.APIs = append(r.APIs, ar)
+}
+
+func (r *generationReport) write(file string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(b, '\n'), 0644)
+}
+
+// warnf logs a warning about a and records it for the generation report.
+func (a *API) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+	a.warnings = appendUnique(a.warnings, msg)
+}
+
+// skipf logs that a feature of a was not generated and records it for
+// the generation report and the warnings file. kind and subject are as
+// for lossf.
+func (a *API) skipf(kind, subject, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+	a.skipped = appendUnique(a.skipped, msg)
+	a.lossf(kind, subject, format, args...)
+}
+
+// A loss records a place where the code generated for an API omits or
+// only approximates part of its discovery document.
+type loss struct {
+	Kind    string // "array", "format", "identifier", "map" or "variant"
+	Subject string // discovery name of the schema, property or parameter
+	Detail  string
+}
+
+func (l loss) String() string {
+	subject := l.Subject
+	if subject == "" {
+		subject = "-"
+	}
+	return l.Kind + "\t" + subject + "\t" + l.Detail
+}
+
+// lossf records a loss for the warnings file written alongside a's
+// generated code.
+func (a *API) lossf(kind, subject, format string, args ...interface{}) {
+	l := loss{Kind: kind, Subject: subject, Detail: fmt.Sprintf(format, args...)}
+	for _, v := range a.losses {
+		if v == l {
+			return
+		}
+	}
+	a.losses = append(a.losses, l)
+}
+
+// warningsFileHeader starts each warnings file.
+const warningsFileHeader = `# Parts of the discovery document of %s which the generated code omits
+# or only approximates, one per line as: kind<TAB>subject<TAB>detail.
+`
+
+// writeWarnings writes the losses recorded while generating a to file,
+// or removes file if there were none.
+func (a *API) writeWarnings(file string) error {
+	if len(a.losses) == 0 {
+		return removeFile(file)
+	}
+	lines := make([]string, len(a.losses))
+	for i, l := range a.losses {
+		lines[i] = l.String()
+	}
+	sort.Strings(lines)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, warningsFileHeader, a.ID)
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return writeFile(file, buf.Bytes())
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
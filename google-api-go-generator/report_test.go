@@ -0,0 +1,115 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerationReport(t *testing.T) {
+	const doc = `{
+		"id": "maps:v1",
+		"name": "maps",
+		"version": "v1",
+		"rootUrl": "https://www.googleapis.com/",
+		"servicePath": "maps/v1/",
+		"schemas": {
+			"Layer": {
+				"id": "Layer",
+				"type": "object",
+				"properties": {
+					"styles": {
+						"type": "object",
+						"additionalProperties": {"type": "object", "properties": {"color": {"type": "string"}}}
+					}
+				}
+			}
+		}
+	}`
+	good := &API{ID: "maps:v1", Name: "maps", Version: "v1", forceJSON: []byte(doc)}
+	if _, err := good.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	bad := &API{ID: "bad:v1"}
+
+	var rep generationReport
+	rep.add(good, nil)
+	rep.add(bad, errors.New("boom"))
+	want := []*apiReport{
+		{
+			ID:      "maps:v1",
+			Success: true,
+			Skipped: []string{`Warning: found map to type "object" which is not implemented yet.`},
+		},
+		{ID: "bad:v1", Error: "boom"},
+	}
+	if !reflect.DeepEqual(rep.APIs, want) {
+		for _, r := range rep.APIs {
+			t.Logf("got %+v", r)
+		}
+		t.Errorf("report differs from %+v, %+v", want[0], want[1])
+	}
+}
+
+func TestWarningsFile(t *testing.T) {
+	const doc = `{
+		"id": "maps:v1",
+		"name": "maps",
+		"version": "v1",
+		"rootUrl": "https://www.googleapis.com/",
+		"servicePath": "maps/v1/",
+		"schemas": {
+			"Layer": {
+				"id": "Layer",
+				"type": "object",
+				"properties": {
+					"opacity": {"type": "number", "format": "decimal"},
+					"owner@": {"type": "string"}
+				}
+			},
+			"Point": {"id": "Point", "type": "array", "items": {"type": "number"}}
+		}
+	}`
+	a := &API{ID: "maps:v1", Name: "maps", Version: "v1", forceJSON: []byte(doc)}
+	if _, err := a.GenerateCode(); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "maps-warnings.txt")
+	if err := a.writeWarnings(file); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Parts of the discovery document of maps:v1 which the generated code omits
+# or only approximates, one per line as: kind<TAB>subject<TAB>detail.
+array	Point	TODO writeSchemaCode for arrays for Point
+format	Layer.opacity	unknown format "decimal" of number; using float64
+identifier	Layer.owner@	characters "@" dropped from Go name Owner
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A later run with nothing to report removes the file.
+	a.losses = nil
+	if err := a.writeWarnings(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("warnings file not removed: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	discovery "google.golang.org/api/discovery/v1"
+)
+
+// writeSnapshot downloads the discovery document of every API which
+// would be generated, as chosen by -api, -apilist and -versions, into
+// dir, as dir/<name>/<version>/<name>-api.json (or as
+// dir/<flat name>/<name>-api.json with -flatpkg), and writes an index
+// of them to dir/api-list.json. The layout is the one used by -gendir, so
+// code can later be regenerated from the snapshot alone with
+// -cache -gendir=dir.
+func writeSnapshot(dir string) error {
+	if *useCache {
+		return fmt.Errorf("-snapshot requires -cache=false")
+	}
+	list, err := listDirectory()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	var all AllAPIs
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	chosen := make(map[string]bool)
+	for _, a := range selectAPIs(&all) {
+		if !a.want() {
+			continue
+		}
+		log.Printf("Downloading API %s", a.ID)
+		u, err := a.DiscoveryURL()
+		if err != nil {
+			return err
+		}
+		doc, err := slurpURL(u)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(dir, filepath.FromSlash(a.relPath()), a.Package()+"-api.json")
+		if err := writeFile(file, doc); err != nil {
+			return err
+		}
+		chosen[a.ID] = true
+	}
+	var items []*discovery.DirectoryListItems
+	for _, item := range list.Items {
+		if chosen[item.Id] {
+			items = append(items, item)
+		}
+	}
+	list.Items = items
+	index, err := json.MarshalIndent(list, "", " ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "api-list.json"), index)
+}
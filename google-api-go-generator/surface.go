@@ -0,0 +1,358 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// An apiSurface describes the exported identifiers of a generated
+// package, as written by -surface. Two surfaces of a package are
+// compared with -surface_diff to find changes which would break
+// code using the older one.
+type apiSurface struct {
+	Package string         `json:"package"`
+	Types   []surfaceType  `json:"types"`
+	Funcs   []surfaceFunc  `json:"funcs"`
+	Values  []surfaceValue `json:"values"`
+}
+
+type surfaceType struct {
+	Name string `json:"name"`
+	// Kind is "struct", "interface", or for other types the underlying
+	// type, such as "string".
+	Kind    string         `json:"kind"`
+	Fields  []surfaceField `json:"fields,omitempty"`
+	Methods []surfaceFunc  `json:"methods,omitempty"`
+}
+
+type surfaceField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type surfaceFunc struct {
+	Name    string         `json:"name"`
+	Params  []surfaceField `json:"params"`
+	Results []string       `json:"results,omitempty"`
+}
+
+type surfaceValue struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "const" or "var"
+	Type string `json:"type,omitempty"`
+}
+
+// signature returns the parameter and result types of f, which are
+// what matters to callers; parameter names are not part of it.
+func (f surfaceFunc) signature() string {
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.Type
+	}
+	s := "(" + strings.Join(params, ", ") + ")"
+	switch len(f.Results) {
+	case 0:
+	case 1:
+		s += " " + f.Results[0]
+	default:
+		s += " (" + strings.Join(f.Results, ", ") + ")"
+	}
+	return s
+}
+
+// newSurface returns the surface of the Go source code src.
+func newSurface(src []byte) (*apiSurface, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	str := func(e ast.Expr) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, e)
+		return buf.String()
+	}
+	fields := func(l *ast.FieldList) []surfaceField {
+		var fs []surfaceField
+		if l == nil {
+			return fs
+		}
+		for _, f := range l.List {
+			typ := str(f.Type)
+			if len(f.Names) == 0 {
+				fs = append(fs, surfaceField{Type: typ})
+			}
+			for _, n := range f.Names {
+				fs = append(fs, surfaceField{Name: n.Name, Type: typ})
+			}
+		}
+		return fs
+	}
+	fn := func(name string, t *ast.FuncType) surfaceFunc {
+		sf := surfaceFunc{Name: name, Params: fields(t.Params)}
+		if sf.Params == nil {
+			sf.Params = []surfaceField{}
+		}
+		for _, r := range fields(t.Results) {
+			sf.Results = append(sf.Results, r.Type)
+		}
+		return sf
+	}
+
+	s := &apiSurface{Package: f.Name.Name, Types: []surfaceType{}, Funcs: []surfaceFunc{}, Values: []surfaceValue{}}
+	types := make(map[string]*surfaceType)
+	var methods []*ast.FuncDecl
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv != nil {
+				methods = append(methods, d)
+				continue
+			}
+			s.Funcs = append(s.Funcs, fn(d.Name.Name, d.Type))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if !spec.Name.IsExported() {
+						continue
+					}
+					st := surfaceType{Name: spec.Name.Name}
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						st.Kind = "struct"
+						for _, f := range fields(t.Fields) {
+							if f.Name == "" {
+								// An embedded field is named by its type.
+								f.Name = f.Type[strings.LastIndexAny(f.Type, "*.")+1:]
+							}
+							if ast.IsExported(f.Name) {
+								st.Fields = append(st.Fields, f)
+							}
+						}
+					case *ast.InterfaceType:
+						st.Kind = "interface"
+						for _, m := range t.Methods.List {
+							ft, ok := m.Type.(*ast.FuncType)
+							if !ok {
+								st.Fields = append(st.Fields, surfaceField{Type: str(m.Type)})
+								continue
+							}
+							for _, n := range m.Names {
+								st.Methods = append(st.Methods, fn(n.Name, ft))
+							}
+						}
+					default:
+						st.Kind = str(spec.Type)
+					}
+					s.Types = append(s.Types, st)
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for i, n := range spec.Names {
+						if !n.IsExported() {
+							continue
+						}
+						v := surfaceValue{Name: n.Name, Kind: kind}
+						if spec.Type != nil {
+							v.Type = str(spec.Type)
+						} else if i < len(spec.Values) {
+							if c, ok := spec.Values[i].(*ast.CompositeLit); ok && c.Type != nil {
+								v.Type = str(c.Type)
+							}
+						}
+						s.Values = append(s.Values, v)
+					}
+				}
+			}
+		}
+	}
+	for i := range s.Types {
+		types[s.Types[i].Name] = &s.Types[i]
+	}
+	for _, m := range methods {
+		recv := m.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		id, ok := recv.(*ast.Ident)
+		if !ok || types[id.Name] == nil {
+			continue
+		}
+		t := types[id.Name]
+		t.Methods = append(t.Methods, fn(m.Name.Name, m.Type))
+	}
+	s.sort()
+	return s, nil
+}
+
+func (s *apiSurface) sort() {
+	sort.Sort(typesByName(s.Types))
+	sort.Sort(funcsByName(s.Funcs))
+	sort.Sort(valuesByName(s.Values))
+	for _, t := range s.Types {
+		sort.Sort(funcsByName(t.Methods))
+	}
+}
+
+type typesByName []surfaceType
+
+func (s typesByName) Len() int           { return len(s) }
+func (s typesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s typesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type funcsByName []surfaceFunc
+
+func (s funcsByName) Len() int           { return len(s) }
+func (s funcsByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s funcsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type valuesByName []surfaceValue
+
+func (s valuesByName) Len() int           { return len(s) }
+func (s valuesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s valuesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// writeSurface writes the surface of the generated code to file.
+func writeSurface(file string, code []byte) error {
+	s, err := newSurface(code)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(file, append(b, '\n'))
+}
+
+// breakingChanges returns a description of each change from old to new
+// which could break code using old: removed or changed identifiers, and
+// methods added to interfaces, which existing implementations lack.
+func breakingChanges(old, new *apiSurface) []string {
+	var changes []string
+	add := func(format string, args ...interface{}) {
+		changes = append(changes, fmt.Sprintf(format, args...))
+	}
+	if old.Package != new.Package {
+		add("package renamed from %s to %s", old.Package, new.Package)
+	}
+
+	newTypes := make(map[string]surfaceType)
+	for _, t := range new.Types {
+		newTypes[t.Name] = t
+	}
+	for _, ot := range old.Types {
+		nt, ok := newTypes[ot.Name]
+		if !ok {
+			add("type %s removed", ot.Name)
+			continue
+		}
+		if ot.Kind != nt.Kind {
+			add("type %s changed from %s to %s", ot.Name, ot.Kind, nt.Kind)
+			continue
+		}
+		newFields := make(map[string]string)
+		for _, f := range nt.Fields {
+			newFields[f.Name] = f.Type
+		}
+		for _, f := range ot.Fields {
+			typ, ok := newFields[f.Name]
+			switch {
+			case !ok:
+				add("field %s.%s removed", ot.Name, f.Name)
+			case typ != f.Type:
+				add("field %s.%s changed type from %s to %s", ot.Name, f.Name, f.Type, typ)
+			}
+		}
+		changes = append(changes, funcChanges(ot.Name+".", ot.Methods, nt.Methods)...)
+		if ot.Kind == "interface" {
+			oldMethods := make(map[string]bool)
+			for _, m := range ot.Methods {
+				oldMethods[m.Name] = true
+			}
+			for _, m := range nt.Methods {
+				if !oldMethods[m.Name] {
+					add("method %s.%s added to interface", ot.Name, m.Name)
+				}
+			}
+		}
+	}
+	changes = append(changes, funcChanges("", old.Funcs, new.Funcs)...)
+
+	newValues := make(map[string]surfaceValue)
+	for _, v := range new.Values {
+		newValues[v.Name] = v
+	}
+	for _, ov := range old.Values {
+		nv, ok := newValues[ov.Name]
+		switch {
+		case !ok:
+			add("%s %s removed", ov.Kind, ov.Name)
+		case nv.Kind != ov.Kind:
+			add("%s %s changed to a %s", ov.Kind, ov.Name, nv.Kind)
+		case nv.Type != ov.Type:
+			add("%s %s changed type from %q to %q", ov.Kind, ov.Name, ov.Type, nv.Type)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// funcChanges returns the breaking changes from the functions or methods
+// old to new, naming each with prefix.
+func funcChanges(prefix string, old, new []surfaceFunc) []string {
+	var changes []string
+	sigs := make(map[string]string)
+	for _, f := range new {
+		sigs[f.Name] = f.signature()
+	}
+	for _, f := range old {
+		sig, ok := sigs[f.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("func %s%s removed", prefix, f.Name))
+		case sig != f.signature():
+			changes = append(changes, fmt.Sprintf("func %s%s changed from %s to %s", prefix, f.Name, f.signature(), sig))
+		}
+	}
+	return changes
+}
+
+// compareSurfaceFiles writes to w the breaking changes from the surface
+// in the file oldFile to that in newFile, and returns their number.
+func compareSurfaceFiles(w io.Writer, oldFile, newFile string) (int, error) {
+	var surfaces [2]apiSurface
+	for i, file := range []string{oldFile, newFile} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return 0, err
+		}
+		if err := json.Unmarshal(b, &surfaces[i]); err != nil {
+			return 0, fmt.Errorf("decoding %s: %v", file, err)
+		}
+	}
+	changes := breakingChanges(&surfaces[0], &surfaces[1])
+	for _, c := range changes {
+		fmt.Fprintln(w, c)
+	}
+	return len(changes), nil
+}
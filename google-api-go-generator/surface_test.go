@@ -0,0 +1,152 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const surfaceSrc = `package tasks
+
+type Service struct {
+	BasePath string
+	client   int
+	Tasks    *TasksService
+}
+
+func New(client int) (*Service, error) { return nil, nil }
+
+func (s *Service) setup() {}
+
+type TasksService struct{ s *Service }
+
+func (r *TasksService) Get(taskID string, opts ...int) *TasksGetCall { return nil }
+
+type TasksGetCall struct{}
+
+type Doer interface {
+	Do(opts ...int) (*Task, error)
+}
+
+type Status string
+
+const (
+	StatusDone = "done"
+	hidden     = "x"
+)
+
+var Kinds = map[string]int{}
+`
+
+func TestNewSurface(t *testing.T) {
+	got, err := newSurface([]byte(surfaceSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &apiSurface{
+		Package: "tasks",
+		Types: []surfaceType{
+			{Name: "Doer", Kind: "interface", Methods: []surfaceFunc{
+				{Name: "Do", Params: []surfaceField{{"opts", "...int"}}, Results: []string{"*Task", "error"}},
+			}},
+			{Name: "Service", Kind: "struct", Fields: []surfaceField{{"BasePath", "string"}, {"Tasks", "*TasksService"}}},
+			{Name: "Status", Kind: "string"},
+			{Name: "TasksGetCall", Kind: "struct"},
+			{Name: "TasksService", Kind: "struct", Methods: []surfaceFunc{
+				{Name: "Get", Params: []surfaceField{{"taskID", "string"}, {"opts", "...int"}}, Results: []string{"*TasksGetCall"}},
+			}},
+		},
+		Funcs: []surfaceFunc{
+			{Name: "New", Params: []surfaceField{{"client", "int"}}, Results: []string{"*Service", "error"}},
+		},
+		Values: []surfaceValue{
+			{Name: "Kinds", Kind: "var", Type: "map[string]int"},
+			{Name: "StatusDone", Kind: "const"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestBreakingChanges(t *testing.T) {
+	old, err := newSurface([]byte(surfaceSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := breakingChanges(old, old); len(got) != 0 {
+		t.Errorf("identical surfaces: got changes %q", got)
+	}
+	new, err := newSurface([]byte(`package tasks
+
+type Service struct {
+	BasePath  string
+	UserAgent string
+	Tasks     int
+}
+
+func New(c int) (*Service, error) { return nil, nil }
+
+func NewTwo() {}
+
+type TasksService struct{}
+
+func (r *TasksService) Get(taskID int64, opts ...int) *TasksGetCall { return nil }
+
+type Doer interface {
+	Do(opts ...int) (*Task, error)
+	Header() int
+}
+
+type Status int
+
+var StatusDone = "done"
+
+var Kinds = map[string]int{}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"const StatusDone changed to a var",
+		"field Service.Tasks changed type from *TasksService to int",
+		"func TasksService.Get changed from (string, ...int) *TasksGetCall to (int64, ...int) *TasksGetCall",
+		"method Doer.Header added to interface",
+		"type Status changed from string to int",
+		"type TasksGetCall removed",
+	}
+	if got := breakingChanges(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSurfaceOfGeneratedCode(t *testing.T) {
+	api, err := apiFromFile(filepath.Join("testdata", "blogger-3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := api.GenerateCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSurface(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, f := range s.Funcs {
+		if f.Name == "New" {
+			found = true
+			if got, want := f.signature(), "(*http.Client) (*Service, error)"; got != want {
+				t.Errorf("New: got signature %s, want %s", got, want)
+			}
+		}
+	}
+	if !found {
+		t.Error("surface has no func New")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// A watchChange is a change to the discovery directory seen by -watch.
+type watchChange struct {
+	API      string // API ID, such as "tasks:v1"
+	Change   string // "new", "revised" or "removed"
+	Revision string // revision of the discovery document, empty if removed
+}
+
+// runWatchHook runs the -watch_hook command for c. It is overridden in
+// tests.
+var runWatchHook = func(c watchChange) error {
+	if *watchHook == "" {
+		log.Printf("%s API %s (revision %q)", c.Change, c.API, c.Revision)
+		return nil
+	}
+	cmd := shellCommand(*watchHook)
+	cmd.Env = append(os.Environ(),
+		"WATCH_API="+c.API,
+		"WATCH_CHANGE="+c.Change,
+		"WATCH_REVISION="+c.Revision,
+	)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// shellCommand returns a command running line with the system shell:
+// cmd on Windows, and sh elsewhere.
+func shellCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
+
+// watchDirectory polls the discovery directory every interval, running
+// the -watch_hook command for each change. The first poll only records
+// the revisions seen, unless they were loaded from -watch_state. It
+// returns only if loading or saving -watch_state fails.
+func watchDirectory(interval time.Duration) error {
+	if *useCache {
+		return fmt.Errorf("-watch requires -cache=false")
+	}
+	seen, err := loadWatchState(*watchState)
+	if err != nil {
+		return err
+	}
+	for {
+		if seen, err = watchOnce(seen); err != nil {
+			log.Printf("watch: %v", err)
+		} else if err := saveWatchState(*watchState, seen); err != nil {
+			return err
+		}
+		time.Sleep(interval)
+	}
+}
+
+// watchOnce polls the discovery directory, runs the hook for each change
+// from the revisions in seen, and returns the revisions now seen. A nil
+// seen means no revisions have been recorded, so no hooks are run. If a
+// hook fails, the change it was run for is not recorded, so that it is
+// reported again by the next poll.
+func watchOnce(seen map[string]string) (map[string]string, error) {
+	cur, err := directoryRevisions()
+	if err != nil {
+		return seen, err
+	}
+	if seen == nil {
+		return cur, nil
+	}
+	next := make(map[string]string)
+	for id, rev := range seen {
+		next[id] = rev
+	}
+	for _, c := range diffRevisions(seen, cur) {
+		if err := runWatchHook(c); err != nil {
+			log.Printf("watch: hook for %s API %s: %v", c.Change, c.API, err)
+			continue
+		}
+		if c.Change == "removed" {
+			delete(next, c.API)
+		} else {
+			next[c.API] = c.Revision
+		}
+	}
+	return next, nil
+}
+
+// directoryRevisions returns the revision of every API in the discovery
+// directory, keyed by API ID.
+func directoryRevisions() (map[string]string, error) {
+	list, err := listDirectory()
+	if err != nil {
+		return nil, err
+	}
+	revs := make(map[string]string)
+	for _, item := range list.Items {
+		a := &API{ID: item.Id, Name: item.Name, Version: item.Version, DiscoveryLink: item.DiscoveryRestUrl}
+		u, err := a.DiscoveryURL()
+		if err != nil {
+			return nil, err
+		}
+		doc, err := slurpURL(u)
+		if err != nil {
+			return nil, err
+		}
+		var v struct {
+			Revision string `json:"revision"`
+		}
+		if err := json.Unmarshal(doc, &v); err != nil {
+			return nil, fmt.Errorf("decoding discovery document of %s: %v", a.ID, err)
+		}
+		revs[a.ID] = v.Revision
+	}
+	return revs, nil
+}
+
+// diffRevisions returns the changes from the revisions in old to those
+// in cur, sorted by API ID.
+func diffRevisions(old, cur map[string]string) []watchChange {
+	var changes []watchChange
+	for id, rev := range cur {
+		switch oldRev, ok := old[id]; {
+		case !ok:
+			changes = append(changes, watchChange{id, "new", rev})
+		case oldRev != rev:
+			changes = append(changes, watchChange{id, "revised", rev})
+		}
+	}
+	for id := range old {
+		if _, ok := cur[id]; !ok {
+			changes = append(changes, watchChange{id, "removed", ""})
+		}
+	}
+	sort.Sort(byAPI(changes))
+	return changes
+}
+
+type byAPI []watchChange
+
+func (c byAPI) Len() int           { return len(c) }
+func (c byAPI) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byAPI) Less(i, j int) bool { return c[i].API < c[j].API }
+
+// loadWatchState returns the revisions recorded in file, or nil if file
+// is empty or does not exist.
+func loadWatchState(file string) (map[string]string, error) {
+	if file == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var seen map[string]string
+	if err := json.Unmarshal(b, &seen); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", file, err)
+	}
+	return seen, nil
+}
+
+// saveWatchState records the revisions in seen in file, if it is not empty.
+func saveWatchState(file string, seen map[string]string) error {
+	if file == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(seen, "", " ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0644)
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatchOnce(t *testing.T) {
+	revs := map[string]string{"tasks:v1": "20160101", "drive:v2": "20160102"}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/discovery/v1/apis" {
+			w.Write([]byte(`{"items": [`))
+			first := true
+			for id := range revs {
+				if !first {
+					w.Write([]byte(","))
+				}
+				first = false
+				w.Write([]byte(`{"id": "` + id + `", "discoveryRestUrl": "` + ts.URL + `/` + id + `"}`))
+			}
+			w.Write([]byte(`]}`))
+			return
+		}
+		rev, ok := revs[r.URL.Path[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"revision": "` + rev + `"}`))
+	}))
+	defer ts.Close()
+
+	defer func(url string, cache bool, hook func(watchChange) error) {
+		*apisURL, *useCache, runWatchHook = url, cache, hook
+	}(*apisURL, *useCache, runWatchHook)
+	*apisURL, *useCache = ts.URL+"/discovery/v1/apis", false
+	var got []watchChange
+	var failing string
+	runWatchHook = func(c watchChange) error {
+		got = append(got, c)
+		if c.API == failing {
+			return errors.New("hook failed")
+		}
+		return nil
+	}
+
+	// The first poll records the revisions without reporting them.
+	seen, err := watchOnce(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, revs) || len(got) != 0 {
+		t.Fatalf("first poll: got revisions %v and changes %v, want %v and none", seen, got, revs)
+	}
+
+	revs = map[string]string{"tasks:v1": "20160201", "gmail:v1": "20160103"}
+	failing = "tasks:v1"
+	seen, err = watchOnce(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []watchChange{
+		{"drive:v2", "removed", ""},
+		{"gmail:v1", "new", "20160103"},
+		{"tasks:v1", "revised", "20160201"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %v, want %v", got, want)
+	}
+	// The failed hook is run again on the next poll.
+	if want := map[string]string{"tasks:v1": "20160101", "gmail:v1": "20160103"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("got revisions %v, want %v", seen, want)
+	}
+	got, failing = nil, ""
+	if seen, err = watchOnce(seen); err != nil {
+		t.Fatal(err)
+	}
+	if want := []watchChange{{"tasks:v1", "revised", "20160201"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(seen, revs) {
+		t.Errorf("got revisions %v, want %v", seen, revs)
+	}
+}
+
+func TestWatchState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "state.json")
+
+	seen, err := loadWatchState(file)
+	if err != nil || seen != nil {
+		t.Fatalf("missing state: got %v, %v; want nil, nil", seen, err)
+	}
+	want := map[string]string{"tasks:v1": "20160101"}
+	if err := saveWatchState(file, want); err != nil {
+		t.Fatal(err)
+	}
+	if seen, err = loadWatchState(file); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("got %v, want %v", seen, want)
+	}
+}
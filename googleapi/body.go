@@ -0,0 +1,53 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// A BodySource supplies the body of a request as many times as needed,
+// so that the request can be sent again after a failure without sending
+// a truncated body.
+type BodySource interface {
+	// Open returns a new reader of the whole body.
+	Open() (io.ReadCloser, error)
+}
+
+// BytesBody returns a BodySource which reads b.
+func BytesBody(b []byte) BodySource {
+	return bytesBody(b)
+}
+
+type bytesBody []byte
+
+func (b bytesBody) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Size returns the length of the body.
+func (b bytesBody) Size() int64 {
+	return int64(len(b))
+}
+
+// BodyFunc is a BodySource which calls itself to open the body, in the
+// manner of http.Request's GetBody.
+type BodyFunc func() (io.ReadCloser, error)
+
+// Open returns f().
+func (f BodyFunc) Open() (io.ReadCloser, error) {
+	return f()
+}
+
+// FileBody returns a BodySource which reads the named file, reopening
+// it each time the body is needed.
+func FileBody(name string) BodySource {
+	return BodyFunc(func() (io.ReadCloser, error) {
+		return os.Open(name)
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBodySources(t *testing.T) {
+	f, err := ioutil.TempFile("", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("contents")
+	f.Close()
+
+	for _, tt := range []struct {
+		desc string
+		src  BodySource
+	}{
+		{"bytes", BytesBody([]byte("contents"))},
+		{"file", FileBody(f.Name())},
+	} {
+		// Each Open must return the whole body, however much of an
+		// earlier reader was consumed.
+		for i := 0; i < 2; i++ {
+			rc, err := tt.src.Open()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.desc, err)
+			}
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil || string(b) != "contents" {
+				t.Errorf("%s: open %d: got %q, %v; want %q", tt.desc, i, b, err, "contents")
+			}
+		}
+	}
+	if _, err := FileBody("/nonexistent/file").Open(); err == nil {
+		t.Error("FileBody of missing file: got nil error, want one")
+	}
+}
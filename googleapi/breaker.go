@@ -0,0 +1,49 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// BreakerPolicy configures a circuit breaker and retry budget shared by
+// all calls made through a Service.
+//
+// The breaker opens after Failures consecutive calls fail with a 5xx
+// status or a network error. While it is open, calls fail immediately
+// with a *CircuitOpenError. Once Cooldown has passed, a single call is
+// let through as a probe: if it succeeds the breaker closes, and if it
+// fails the breaker stays open for another Cooldown.
+//
+// The retry budget limits the retries made by a Service with retries
+// enabled to a fraction of its calls, so that a struggling API does not
+// receive several times its usual load.
+type BreakerPolicy struct {
+	// Failures is the number of consecutive failed calls which open the
+	// breaker. If zero, the breaker never opens.
+	Failures int
+
+	// Cooldown is how long the breaker stays open before a probe call
+	// is allowed.
+	Cooldown time.Duration
+
+	// RetryRatio is the number of retries allowed per call, e.g. 0.1 to
+	// permit one retry for every ten calls. A small reserve allows
+	// occasional retries before any calls have been made. If zero, the
+	// number of retries is not limited.
+	RetryRatio float64
+}
+
+// CircuitOpenError is returned by calls made through a Service whose
+// circuit breaker is open. The call was not sent.
+type CircuitOpenError struct {
+	// Until is when the breaker will next allow a probe call.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("googleapi: circuit breaker open until %s", e.Until.Format(time.RFC3339))
+}
@@ -0,0 +1,23 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "fmt"
+
+// ChecksumError is returned when reading downloaded media whose contents
+// do not match the checksum sent by the server in its X-Goog-Hash header,
+// indicating that the media was corrupted in transit.
+type ChecksumError struct {
+	// Algorithm is the checksum algorithm, "crc32c" or "md5".
+	Algorithm string
+	// Want is the base64-encoded checksum sent by the server.
+	Want string
+	// Got is the base64-encoded checksum of the bytes received.
+	Got string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("googleapi: downloaded media is corrupt: %s checksum is %s, want %s", e.Algorithm, e.Got, e.Want)
+}
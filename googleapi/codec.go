@@ -0,0 +1,29 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "encoding/json"
+
+// Codec encodes request bodies and decodes response bodies for the
+// calls made through a Service. Implementations must be compatible with
+// encoding/json, including its treatment of struct tags and of types
+// implementing json.Marshaler and json.Unmarshaler, and must be safe
+// for concurrent use. Unmarshal must not retain data, which is reused
+// once it returns.
+//
+// A Codec may be used to plug in a faster JSON implementation for calls
+// returning large responses.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the default Codec, which uses encoding/json.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
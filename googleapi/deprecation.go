@@ -0,0 +1,118 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Deprecation holds the notice a server gave, in the header of a
+// response, that the endpoint called is deprecated or will be shut down.
+type Deprecation struct {
+	// Sunset is when the endpoint is expected to stop responding, from
+	// the Sunset header, or the zero time if not reported.
+	Sunset time.Time
+	// Warnings holds the text of each warning with code 299
+	// ("Miscellaneous persistent warning") in the Warning header, which
+	// is used to announce deprecations.
+	Warnings []string
+}
+
+// ParseDeprecation returns the deprecation notice in the response header
+// h, or false if h holds none.
+func ParseDeprecation(h http.Header) (*Deprecation, bool) {
+	d := &Deprecation{}
+	found := false
+	if v := strings.TrimSpace(h.Get("Sunset")); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			d.Sunset, found = t, true
+		}
+	}
+	for _, v := range h["Warning"] {
+		for _, w := range parseWarnings(v) {
+			if w.code == "299" {
+				d.Warnings, found = append(d.Warnings, w.text), true
+			}
+		}
+	}
+	return d, found
+}
+
+// Deprecation returns the deprecation notice given by the server in the
+// response, or false if it gave none.
+func (r ServerResponse) Deprecation() (*Deprecation, bool) {
+	return ParseDeprecation(r.Header)
+}
+
+func (d *Deprecation) String() string {
+	var parts []string
+	if !d.Sunset.IsZero() {
+		parts = append(parts, "sunset "+d.Sunset.UTC().Format(time.RFC1123))
+	}
+	for _, w := range d.Warnings {
+		parts = append(parts, fmt.Sprintf("warning %q", w))
+	}
+	return "deprecated (" + strings.Join(parts, "; ") + ")"
+}
+
+type warning struct {
+	code, text string
+}
+
+// parseWarnings parses the value of a Warning header, a comma-separated
+// list of warnings of the form: code agent "text" ["date"].
+func parseWarnings(v string) []warning {
+	var ws []warning
+	for v = strings.TrimSpace(v); v != ""; {
+		var w warning
+		i := strings.IndexByte(v, ' ')
+		if i < 0 {
+			break
+		}
+		w.code, v = v[:i], strings.TrimLeft(v[i:], " ")
+		// Skip the agent.
+		if i = strings.IndexByte(v, ' '); i < 0 {
+			break
+		}
+		v = strings.TrimLeft(v[i:], " ")
+		w.text, v = quoted(v)
+		ws = append(ws, w)
+		// Skip the optional date, up to the next warning.
+		if strings.HasPrefix(v, " ") {
+			v = strings.TrimLeft(v, " ")
+			if strings.HasPrefix(v, `"`) {
+				_, v = quoted(v)
+			}
+		}
+		v = strings.TrimLeft(v, " ,")
+	}
+	return ws
+}
+
+// quoted returns the contents of the quoted string at the start of s,
+// and the rest of s.
+func quoted(s string) (text, rest string) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s
+	}
+	var b []byte
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b = append(b, s[i])
+			}
+		case '"':
+			return string(b), s[i+1:]
+		default:
+			b = append(b, s[i])
+		}
+	}
+	return string(b), ""
+}
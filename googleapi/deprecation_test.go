@@ -0,0 +1,50 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDeprecation(t *testing.T) {
+	sunset := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		header http.Header
+		want   *Deprecation
+	}{
+		{http.Header{}, nil},
+		{http.Header{"Warning": {`199 - "Not a deprecation"`}}, nil},
+		{http.Header{"Sunset": {"not a date"}}, nil},
+		{
+			http.Header{"Sunset": {"Wed, 01 Mar 2017 00:00:00 GMT"}},
+			&Deprecation{Sunset: sunset},
+		},
+		{
+			http.Header{"Warning": {`299 - "This API is deprecated. Use v2."`}},
+			&Deprecation{Warnings: []string{"This API is deprecated. Use v2."}},
+		},
+		{
+			http.Header{
+				"Sunset":  {"Wed, 01 Mar 2017 00:00:00 GMT"},
+				"Warning": {`199 proxy "Stale", 299 www.googleapis.com "Field \"x\" is deprecated" "Wed, 01 Feb 2017 00:00:00 GMT", 299 - "Second"`},
+			},
+			&Deprecation{Sunset: sunset, Warnings: []string{`Field "x" is deprecated`, "Second"}},
+		},
+	} {
+		got, ok := ParseDeprecation(tt.header)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%v: got %+v, want none", tt.header, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %+v, %v; want %+v", tt.header, got, ok, tt.want)
+		}
+	}
+}
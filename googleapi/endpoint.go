@@ -0,0 +1,24 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "golang.org/x/net/context"
+
+type baseURLKey struct{}
+
+// WithBaseURL returns a copy of ctx which directs calls made with it to
+// baseURL rather than to the BasePath of their Service, for instance to
+// route them to a regional endpoint. A call's BaseURL method takes
+// precedence over the context.
+func WithBaseURL(ctx context.Context, baseURL string) context.Context {
+	return context.WithValue(ctx, baseURLKey{}, baseURL)
+}
+
+// BaseURLFromContext returns the base URL set in ctx by WithBaseURL, if
+// any.
+func BaseURLFromContext(ctx context.Context) (string, bool) {
+	u, ok := ctx.Value(baseURLKey{}).(string)
+	return u, ok && u != ""
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnumError is returned by calls made through a Service with enum
+// validation enabled when a parameter or request field holds a value
+// which the API does not accept. The call is not sent.
+type EnumError struct {
+	// Name names the parameter or field, e.g. "orderBy" or "Event.Status".
+	Name string
+	// Value is the rejected value.
+	Value string
+	// Allowed lists the values the API accepts.
+	Allowed []string
+}
+
+func (e *EnumError) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, v := range e.Allowed {
+		allowed[i] = fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("googleapi: invalid value %q for %s; allowed values are %s", e.Value, e.Name, strings.Join(allowed, ", "))
+}
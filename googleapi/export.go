@@ -0,0 +1,270 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// An Exporter writes the items of list responses, such as the pages
+// passed to ForEachPage, as rows of CSV or as newline-delimited JSON.
+//
+// The columns of each row are selected by field paths: the JSON names of
+// fields, separated by dots to reach into nested objects and maps, such
+// as "id" or "owner.displayName". With no field paths, CSV rows hold
+// the top-level fields of the first item written, and NDJSON rows hold
+// whole items.
+type Exporter struct {
+	fields [][]string // field paths, split at dots
+	names  []string   // field paths as given, or chosen by the first item
+	csv    *csv.Writer
+	json   *bufio.Writer
+	header bool // whether the CSV header has been written
+}
+
+// NewCSVExporter returns an Exporter writing to w as CSV, with a header
+// row naming the field paths. Call Flush when done.
+func NewCSVExporter(w io.Writer, fields ...string) *Exporter {
+	e := newExporter(fields)
+	e.csv = csv.NewWriter(w)
+	return e
+}
+
+// NewNDJSONExporter returns an Exporter writing to w as one JSON object
+// per line. With field paths, each object maps the paths to the values
+// found there. Call Flush when done.
+func NewNDJSONExporter(w io.Writer, fields ...string) *Exporter {
+	e := newExporter(fields)
+	e.json = bufio.NewWriter(w)
+	return e
+}
+
+func newExporter(fields []string) *Exporter {
+	e := &Exporter{names: fields}
+	for _, f := range fields {
+		e.fields = append(e.fields, strings.Split(f, "."))
+	}
+	return e
+}
+
+// Write writes a row for each item of v. v may be a list response, whose
+// items are those of its "items" field, or of its only slice field if it
+// has a "nextPageToken" field; a slice of items; or a single item.
+func (e *Exporter) Write(v interface{}) error {
+	items := listItems(reflect.ValueOf(v))
+	for i := 0; i < items.Len(); i++ {
+		if err := e.writeItem(items.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (e *Exporter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return e.json.Flush()
+}
+
+func (e *Exporter) writeItem(item reflect.Value) error {
+	if e.json != nil && len(e.fields) == 0 {
+		return e.writeJSON(item.Interface())
+	}
+	if e.csv != nil && !e.header && len(e.fields) == 0 {
+		e.names = fieldNames(item)
+		for _, n := range e.names {
+			e.fields = append(e.fields, []string{n})
+		}
+	}
+	if e.json != nil {
+		row := make(map[string]interface{}, len(e.fields))
+		for i, path := range e.fields {
+			if v, ok := lookupField(item, path); ok {
+				row[e.names[i]] = v.Interface()
+			} else {
+				row[e.names[i]] = nil
+			}
+		}
+		return e.writeJSON(row)
+	}
+	if !e.header {
+		if err := e.csv.Write(e.names); err != nil {
+			return err
+		}
+		e.header = true
+	}
+	row := make([]string, len(e.fields))
+	for i, path := range e.fields {
+		v, ok := lookupField(item, path)
+		if !ok {
+			continue
+		}
+		s, err := csvValue(v)
+		if err != nil {
+			return fmt.Errorf("googleapi: exporting field %s: %v", e.names[i], err)
+		}
+		row[i] = s
+	}
+	return e.csv.Write(row)
+}
+
+func (e *Exporter) writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := e.json.Write(b); err != nil {
+		return err
+	}
+	return e.json.WriteByte('\n')
+}
+
+// listItems returns the items of v, as a slice or array.
+func listItems(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return reflect.ValueOf([]interface{}{})
+	}
+	s := indirect(v)
+	switch s.Kind() {
+	case reflect.Slice, reflect.Array:
+		return s
+	case reflect.Struct:
+		t := s.Type()
+		var slices []int
+		paged := false
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := jsonName(f)
+			if !ok || f.PkgPath != "" {
+				continue
+			}
+			switch {
+			case name == "nextPageToken":
+				paged = true
+			case f.Type.Kind() != reflect.Slice:
+			case name == "items":
+				return s.Field(i)
+			default:
+				slices = append(slices, i)
+			}
+		}
+		if paged && len(slices) == 1 {
+			return s.Field(slices[0])
+		}
+	case reflect.Invalid:
+		return reflect.ValueOf([]interface{}{})
+	}
+	one := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+	one.Index(0).Set(v)
+	return one
+}
+
+// fieldNames returns the JSON names of the top-level fields of item, in
+// the order they are declared.
+func fieldNames(item reflect.Value) []string {
+	s := indirect(item)
+	if s.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, ok := jsonName(f); ok && f.PkgPath == "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// lookupField returns the value at path in v, reporting whether there is
+// one.
+func lookupField(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, name := range path {
+		v = indirect(v)
+		switch v.Kind() {
+		case reflect.Struct:
+			t := v.Type()
+			found := false
+			for i := 0; i < t.NumField(); i++ {
+				if n, ok := jsonName(t.Field(i)); ok && n == name && t.Field(i).PkgPath == "" {
+					v, found = v.Field(i), true
+					break
+				}
+			}
+			if !found {
+				return reflect.Value{}, false
+			}
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return reflect.Value{}, false
+			}
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	v = indirect(v)
+	return v, v.IsValid()
+}
+
+// indirect follows pointers and interfaces from v, returning the zero
+// Value if it reaches nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// jsonName returns the name of f in JSON, reporting false if f is not
+// encoded.
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" || f.Anonymous {
+		return "", false
+	}
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" {
+		return f.Name, true
+	}
+	return tag, true
+}
+
+// csvValue formats v for a CSV cell: scalars as themselves, nil maps and
+// slices as nothing, and other values as JSON.
+func csvValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return "", nil
+		}
+	}
+	b, err := json.Marshal(v.Interface())
+	return string(b), err
+}
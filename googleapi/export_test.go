@@ -0,0 +1,111 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"bytes"
+	"testing"
+)
+
+type exportOwner struct {
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type exportItem struct {
+	Id     string            `json:"id,omitempty"`
+	Size   uint64            `json:"size,omitempty,string"`
+	Owner  *exportOwner      `json:"owner,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+
+	ServerResponse `json:"-"`
+
+	ForceSendFields []string `json:"-"`
+}
+
+type exportList struct {
+	Items         []*exportItem `json:"items,omitempty"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+}
+
+type exportOtherList struct {
+	Kind          string        `json:"kind,omitempty"`
+	Objects       []*exportItem `json:"objects,omitempty"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+}
+
+var exportItems = []*exportItem{
+	{Id: "a", Size: 3, Owner: &exportOwner{DisplayName: "Ann"}, Labels: map[string]string{"env": "prod"}, Tags: []string{"x", "y"}},
+	{Id: "b,c"},
+}
+
+func TestExporterCSV(t *testing.T) {
+	for _, v := range []interface{}{
+		&exportList{Items: exportItems, NextPageToken: "next"},
+		&exportOtherList{Kind: "list", Objects: exportItems},
+		exportItems,
+	} {
+		var buf bytes.Buffer
+		e := NewCSVExporter(&buf, "id", "size", "owner.displayName", "labels.env", "tags", "missing")
+		if err := e.Write(v); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		want := `id,size,owner.displayName,labels.env,tags,missing
+a,3,Ann,prod,"[""x"",""y""]",
+"b,c",0,,,,
+`
+		if got := buf.String(); got != want {
+			t.Errorf("%T: got\n%s\nwant\n%s", v, got, want)
+		}
+	}
+}
+
+func TestExporterCSVAllFields(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewCSVExporter(&buf)
+	for _, item := range exportItems {
+		if err := e.Write(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.Flush()
+	want := `id,size,owner,labels,tags
+a,3,"{""displayName"":""Ann""}","{""env"":""prod""}","[""x"",""y""]"
+"b,c",0,,,
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExporterNDJSON(t *testing.T) {
+	page := &exportList{Items: exportItems}
+	for _, tt := range []struct {
+		fields []string
+		want   string
+	}{
+		{nil, `{"id":"a","size":"3","owner":{"displayName":"Ann"},"labels":{"env":"prod"},"tags":["x","y"]}
+{"id":"b,c"}
+`},
+		{[]string{"id", "owner.displayName"}, `{"id":"a","owner.displayName":"Ann"}
+{"id":"b,c","owner.displayName":null}
+`},
+	} {
+		var buf bytes.Buffer
+		e := NewNDJSONExporter(&buf, tt.fields...)
+		if err := e.Write(page); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("fields %q: got\n%s\nwant\n%s", tt.fields, got, tt.want)
+		}
+	}
+}
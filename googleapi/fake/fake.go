@@ -0,0 +1,281 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fake serves an in-memory fake of a Google API over HTTP, for
+// hermetic tests of programs using the API's generated package.
+//
+// The fake stores the JSON objects sent to it by path. Methods named
+// "insert" add an object to the collection named by their path, "get",
+// "update", "patch" and "delete" act on the object named by theirs, and
+// "list" returns the objects of a collection. Other methods, and media
+// uploads and downloads, are answered with 501 Not Implemented.
+//
+// The routes of an API are generated by google-api-go-generator with the
+// -fake flag, in a package named for the API's package with the suffix
+// "fake":
+//
+//   srv := urlshortenerfake.NewServer()
+//   defer srv.Close()
+//   svc, err := urlshortener.New(http.DefaultClient)
+//   ...
+//   svc.BasePath = srv.BasePath
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/googleapi"
+)
+
+// A Kind is what the fake does for a method.
+type Kind int
+
+const (
+	Other  Kind = iota // answered with 501 Not Implemented
+	List               // list the objects of a collection
+	Get                // return an object
+	Insert             // add an object to a collection
+	Update             // replace an object
+	Patch              // replace some fields of an object
+	Delete             // remove an object
+)
+
+// A Route describes a method of an API to the fake.
+type Route struct {
+	ID         string // discovery ID of the method, such as "urlshortener.url.get"
+	HTTPMethod string
+	// Path is the discovery path template of the method, relative to
+	// the service path, such as "blogs/{blogId}/posts".
+	Path string
+	Kind Kind
+	// ItemsField is the field of the responses of a List method which
+	// holds the objects. If empty, "items" is used.
+	ItemsField string
+}
+
+// A Handler serves a fake of an API. The zero value is not usable; use
+// NewHandler.
+type Handler struct {
+	servicePath string
+	routes      []Route
+
+	mu      sync.Mutex
+	objects map[string]map[string]interface{} // by path, relative to servicePath
+	lastID  int
+}
+
+// NewHandler returns a Handler serving routes below servicePath, the
+// service path of the API, such as "urlshortener/v1/".
+func NewHandler(servicePath string, routes []Route) *Handler {
+	return &Handler{
+		servicePath: "/" + strings.Trim(servicePath, "/") + "/",
+		routes:      routes,
+		objects:     make(map[string]map[string]interface{}),
+	}
+}
+
+// Put stores v, which must encode as a JSON object, at path, relative to
+// the service path, such as "blogs/123". It may be used to prepare the
+// fake for methods which only read.
+func (h *Handler) Put(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return fmt.Errorf("fake: %T does not encode as a JSON object", v)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.objects[strings.Trim(path, "/")] = obj
+	return nil
+}
+
+// ServeHTTP serves the method whose route matches r.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.Method
+	if o := r.Header.Get("X-HTTP-Method-Override"); o != "" && method == "POST" {
+		method = o
+	}
+	if !strings.HasPrefix(r.URL.Path, h.servicePath) {
+		writeError(w, http.StatusNotFound, googleapi.ReasonNotFound, "no API is served at "+r.URL.Path)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, h.servicePath), "/")
+	// Of the routes matching, the one with the most literal segments is
+	// taken, so that "posts/search" is not taken for "posts/{postId}".
+	var route *Route
+	best := -1
+	for i := range h.routes {
+		if h.routes[i].HTTPMethod != method {
+			continue
+		}
+		if n, ok := matchPath(h.routes[i].Path, path); ok && n > best {
+			route, best = &h.routes[i], n
+		}
+	}
+	if route == nil {
+		writeError(w, http.StatusNotFound, googleapi.ReasonNotFound, fmt.Sprintf("no method is served for %s %s", method, path))
+		return
+	}
+
+	var body map[string]interface{}
+	switch route.Kind {
+	case Insert, Update, Patch:
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body == nil {
+			writeError(w, http.StatusBadRequest, googleapi.ReasonBadRequest, "the request body is not a JSON object")
+			return
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	obj, found := h.objects[path]
+	switch route.Kind {
+	case List:
+		field := route.ItemsField
+		if field == "" {
+			field = "items"
+		}
+		writeJSON(w, map[string]interface{}{field: h.list(path)})
+	case Insert:
+		id, ok := body["id"]
+		if !ok || id == "" || id == nil {
+			h.lastID++
+			id = strconv.Itoa(h.lastID)
+			body["id"] = id
+		}
+		key := path + "/" + fmt.Sprint(id)
+		if _, ok := h.objects[key]; ok {
+			writeError(w, http.StatusConflict, googleapi.ReasonDuplicate, key+" already exists")
+			return
+		}
+		h.objects[key] = body
+		writeJSON(w, body)
+	case Get, Update, Patch, Delete:
+		if !found {
+			writeError(w, http.StatusNotFound, googleapi.ReasonNotFound, path+" not found")
+			return
+		}
+		switch route.Kind {
+		case Get:
+			writeJSON(w, obj)
+		case Update:
+			h.objects[path] = body
+			writeJSON(w, body)
+		case Patch:
+			for k, v := range body {
+				obj[k] = v
+			}
+			writeJSON(w, obj)
+		case Delete:
+			delete(h.objects, path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "notImplemented", route.ID+" is not implemented by the fake")
+	}
+}
+
+// list returns the objects of the collection at path, in order of their
+// paths.
+func (h *Handler) list(path string) []interface{} {
+	var keys []string
+	for k := range h.objects {
+		if strings.HasPrefix(k, path+"/") && !strings.Contains(k[len(path)+1:], "/") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	items := []interface{}{}
+	for _, k := range keys {
+		items = append(items, h.objects[k])
+	}
+	return items
+}
+
+// matchPath reports whether path matches the discovery path template
+// tmpl, and the number of segments of tmpl without variables. A variable
+// matches a non-empty part of a segment, or with "+", as in "{+name}",
+// the rest of the path.
+func matchPath(tmpl, path string) (int, bool) {
+	tsegs := strings.Split(strings.Trim(tmpl, "/"), "/")
+	psegs := strings.Split(path, "/")
+	literals := 0
+	for i, t := range tsegs {
+		if i >= len(psegs) {
+			return 0, false
+		}
+		open := strings.Index(t, "{")
+		end := strings.LastIndex(t, "}")
+		if open < 0 || end < open {
+			if t != psegs[i] {
+				return 0, false
+			}
+			literals++
+			continue
+		}
+		prefix, suffix := t[:open], t[end+1:]
+		p := psegs[i]
+		if strings.HasPrefix(t[open:], "{+") && i == len(tsegs)-1 {
+			p = strings.Join(psegs[i:], "/")
+			psegs = psegs[:i+1]
+		}
+		if len(p) <= len(prefix)+len(suffix) || !strings.HasPrefix(p, prefix) || !strings.HasSuffix(p, suffix) {
+			return 0, false
+		}
+	}
+	if len(tsegs) != len(psegs) {
+		return 0, false
+	}
+	return literals, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response in the form the Google APIs use,
+// which googleapi.CheckResponse decodes.
+func writeError(w http.ResponseWriter, code int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+			"errors": []interface{}{
+				map[string]interface{}{"reason": reason, "message": message},
+			},
+		},
+	})
+}
+
+// A Server is an HTTP server serving a Handler.
+type Server struct {
+	*httptest.Server
+	*Handler
+
+	// BasePath is the base path of the API as served, to which the
+	// BasePath field of a service using the fake should be set.
+	BasePath string
+}
+
+// NewServer starts and returns a Server serving a fake of the API whose
+// service path is servicePath, using routes. The caller should call
+// Close when done.
+func NewServer(servicePath string, routes []Route) *Server {
+	h := NewHandler(servicePath, routes)
+	s := httptest.NewServer(h)
+	return &Server{Server: s, Handler: h, BasePath: s.URL + h.servicePath}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestMatchPath(t *testing.T) {
+	for _, tt := range []struct {
+		tmpl, path string
+		literals   int
+		ok         bool
+	}{
+		{"blogs/{blogId}", "blogs/1", 1, true},
+		{"blogs/{blogId}", "blogs/1/posts", 0, false},
+		{"blogs/{blogId}", "blogs/", 0, false},
+		{"blogs/{blogId}/posts/search", "blogs/1/posts/search", 3, true},
+		{"v1/{+name}", "v1/projects/p/topics/t", 1, true},
+		{"{resource}:getIamPolicy", "r:getIamPolicy", 0, true},
+		{"{resource}:getIamPolicy", "r:setIamPolicy", 0, false},
+	} {
+		literals, ok := matchPath(tt.tmpl, tt.path)
+		if literals != tt.literals || ok != tt.ok {
+			t.Errorf("matchPath(%q, %q) = %d, %v; want %d, %v", tt.tmpl, tt.path, literals, ok, tt.literals, tt.ok)
+		}
+	}
+}
+
+var testRoutes = []Route{
+	{ID: "test.posts.delete", HTTPMethod: "DELETE", Path: "blogs/{blogId}/posts/{postId}", Kind: Delete},
+	{ID: "test.posts.get", HTTPMethod: "GET", Path: "blogs/{blogId}/posts/{postId}", Kind: Get},
+	{ID: "test.posts.insert", HTTPMethod: "POST", Path: "blogs/{blogId}/posts", Kind: Insert},
+	{ID: "test.posts.list", HTTPMethod: "GET", Path: "blogs/{blogId}/posts", Kind: List, ItemsField: "posts"},
+	{ID: "test.posts.patch", HTTPMethod: "PATCH", Path: "blogs/{blogId}/posts/{postId}", Kind: Patch},
+	{ID: "test.posts.search", HTTPMethod: "GET", Path: "blogs/{blogId}/posts/search", Kind: Other},
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer("test/v1/", testRoutes)
+	defer srv.Close()
+
+	do := func(method, path, body string) (map[string]interface{}, error) {
+		req, err := http.NewRequest(method, srv.BasePath+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if err := googleapi.CheckResponse(res); err != nil {
+			return nil, err
+		}
+		var v map[string]interface{}
+		if res.StatusCode != http.StatusNoContent {
+			if err := json.NewDecoder(res.Body).Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return v, nil
+	}
+	code := func(err error) int {
+		if e, ok := err.(*googleapi.Error); ok {
+			return e.Code
+		}
+		return 0
+	}
+
+	got, err := do("POST", "blogs/b/posts", `{"title": "first"}`)
+	if want := map[string]interface{}{"id": "1", "title": "first"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("insert: got %v, %v; want %v", got, err, want)
+	}
+	if err := srv.Put("blogs/b/posts/x", map[string]string{"id": "x", "title": "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := do("POST", "blogs/b/posts", `{"id": "x"}`); code(err) != http.StatusConflict {
+		t.Errorf("inserting a duplicate: got error %v, want 409", err)
+	}
+	got, err = do("PATCH", "blogs/b/posts/1", `{"title": "changed"}`)
+	if want := map[string]interface{}{"id": "1", "title": "changed"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("patch: got %v, %v; want %v", got, err, want)
+	}
+	got, err = do("GET", "blogs/b/posts", "")
+	if err != nil || len(got["posts"].([]interface{})) != 2 {
+		t.Errorf("list: got %v, %v; want 2 posts", got, err)
+	}
+	if _, err := do("GET", "blogs/b/posts/search", ""); code(err) != http.StatusNotImplemented {
+		t.Errorf("search: got error %v, want 501", err)
+	}
+	if _, err := do("DELETE", "blogs/b/posts/1", ""); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	if _, err := do("GET", "blogs/b/posts/1", ""); code(err) != http.StatusNotFound {
+		t.Errorf("get after delete: got error %v, want 404", err)
+	}
+	if _, err := do("PUT", "blogs/b/posts/x", `{}`); code(err) != http.StatusNotFound {
+		t.Errorf("unrouted method: got error %v, want 404", err)
+	}
+}
@@ -3,7 +3,10 @@
 // license that can be found in the LICENSE file.
 
 // Package googleapi contains the common code shared by all Google API
-// libraries.
+// libraries: the types and functions which users of generated packages
+// work with, such as Error, Field and CheckResponse. Unlike the
+// gensupport package, which holds the helpers called only by generated
+// code, it is kept backwards compatible.
 package googleapi // import "google.golang.org/api/googleapi"
 
 import (
@@ -15,8 +18,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
-	"google.golang.org/api/googleapi/internal/uritemplates"
+	"google.golang.org/api/internal/uritemplates"
 )
 
 // ContentTyper is an interface for Readers which know (or would like
@@ -82,9 +87,30 @@ type ErrorItem struct {
 	Message string `json:"message"`
 }
 
+// maxErrorSnippet is the length to which a non-JSON error body, such as
+// an HTML page returned by a proxy, is shortened in the message of an Error.
+const maxErrorSnippet = 256
+
 func (e *Error) Error() string {
 	if len(e.Errors) == 0 && e.Message == "" {
-		return fmt.Sprintf("googleapi: got HTTP response code %d with body: %v", e.Code, e.Body)
+		body := strings.TrimSpace(e.Body)
+		if body == "" || strings.HasPrefix(body, "{") {
+			return fmt.Sprintf("googleapi: got HTTP response code %d with body: %v", e.Code, e.Body)
+		}
+		// Collapse the layout of text and HTML bodies, and keep only
+		// the start, which is usually enough to identify the problem.
+		body = strings.Join(strings.Fields(body), " ")
+		if len(body) > maxErrorSnippet {
+			n := maxErrorSnippet
+			for n > 0 && !utf8.RuneStart(body[n]) {
+				n--
+			}
+			body = body[:n] + "..."
+		}
+		if ct := e.Header.Get("Content-Type"); ct != "" {
+			return fmt.Sprintf("googleapi: got HTTP response code %d with %s body: %s", e.Code, ct, body)
+		}
+		return fmt.Sprintf("googleapi: got HTTP response code %d with body: %s", e.Code, body)
 	}
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "googleapi: Error %d: ", e.Code)
@@ -105,6 +131,28 @@ func (e *Error) Error() string {
 	return buf.String()
 }
 
+// DryRunError is returned by calls made through a Service in dry-run mode.
+// It holds the fully built request, which was not sent. Callers which do
+// not go on to send the request must close its Body, if any, or the
+// goroutine writing the body of a media upload blocks forever.
+type DryRunError struct {
+	Request *http.Request
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("googleapi: dry run: %s %s not sent", e.Request.Method, e.Request.URL)
+}
+
+// IsDryRun reports whether err is a *DryRunError and, if so, returns the
+// request that would have been sent.
+func IsDryRun(err error) (*http.Request, bool) {
+	e, ok := err.(*DryRunError)
+	if !ok {
+		return nil, false
+	}
+	return e.Request, true
+}
+
 type errorReply struct {
 	Error *Error `json:"error"`
 }
@@ -115,7 +163,7 @@ func CheckResponse(res *http.Response) error {
 	if res.StatusCode >= 200 && res.StatusCode <= 299 {
 		return nil
 	}
-	slurp, err := ioutil.ReadAll(res.Body)
+	slurp, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
 	if err == nil {
 		jerr := new(errorReply)
 		err = json.Unmarshal(slurp, jerr)
@@ -124,6 +172,7 @@ func CheckResponse(res *http.Response) error {
 				jerr.Error.Code = res.StatusCode
 			}
 			jerr.Error.Body = string(slurp)
+			jerr.Error.Header = res.Header
 			return jerr.Error
 		}
 	}
@@ -166,8 +215,22 @@ type MarshalStyle bool
 var WithDataWrapper = MarshalStyle(true)
 var WithoutDataWrapper = MarshalStyle(false)
 
+// jsonBufferPool holds buffers for JSONReader to encode into, so that
+// each call allocates only its result.
+var jsonBufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// maxPooledBuffer is the capacity beyond which a buffer is not returned
+// to jsonBufferPool.
+const maxPooledBuffer = 1 << 20
+
 func (wrap MarshalStyle) JSONReader(v interface{}) (io.Reader, error) {
-	buf := new(bytes.Buffer)
+	buf := jsonBufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			jsonBufferPool.Put(buf)
+		}
+	}()
 	if wrap {
 		buf.Write([]byte(`{"data": `))
 	}
@@ -178,7 +241,7 @@ func (wrap MarshalStyle) JSONReader(v interface{}) (io.Reader, error) {
 	if wrap {
 		buf.Write([]byte(`}`))
 	}
-	return buf, nil
+	return bytes.NewReader(append([]byte(nil), buf.Bytes()...)), nil
 }
 
 // endingWithErrorReader from r until it returns an error.  If the
@@ -250,12 +313,30 @@ func ChunkSize(size int) MediaOption {
 	return chunkSizeOption(size)
 }
 
+type bandwidthLimitOption int64
+
+func (bl bandwidthLimitOption) setOptions(o *MediaOptions) {
+	o.BandwidthLimit = int64(bl)
+}
+
+// WithBandwidthLimit returns a MediaOption which limits the rate at which
+// media is uploaded to bytesPerSec bytes per second, on average.
+// All the chunks of an upload share the limit. If bytesPerSec is zero,
+// the rate is not limited.
+// Generated download calls offer the same limit through their
+// BandwidthLimit methods.
+func WithBandwidthLimit(bytesPerSec int64) MediaOption {
+	return bandwidthLimitOption(bytesPerSec)
+}
+
 // MediaOptions stores options for customizing media upload.  It is not used by developers directly.
 type MediaOptions struct {
 	ContentType           string
 	ForceEmptyContentType bool
 
 	ChunkSize int
+
+	BandwidthLimit int64 // bytes per second, or zero for no limit
 }
 
 // ProcessMediaOptions stores options from opts in a MediaOptions.
@@ -268,6 +349,11 @@ func ProcessMediaOptions(opts []MediaOption) *MediaOptions {
 	return mo
 }
 
+// ResolveRelative resolves relstr against basestr, leaving the braces of
+// any template variables unescaped.
+//
+// Deprecated: Generated code now calls gensupport.ResolveRelative. This function is
+// kept for packages generated by older versions of the generator.
 func ResolveRelative(basestr, relstr string) string {
 	u, _ := url.Parse(basestr)
 	rel, _ := url.Parse(relstr)
@@ -297,6 +383,9 @@ func init() {
 
 // SetOpaque sets u.Opaque from u.Path such that HTTP requests to it
 // don't alter any hex-escaped characters in u.Path.
+//
+// Deprecated: Generated code now calls gensupport.SetOpaque. This function is
+// kept for packages generated by older versions of the generator.
 func SetOpaque(u *url.URL) {
 	u.Opaque = "//" + u.Host + u.Path
 	if !has4860Fix {
@@ -308,6 +397,9 @@ func SetOpaque(u *url.URL) {
 // the map supplied.
 //
 // This calls SetOpaque to avoid encoding of the parameters in the URL path.
+//
+// Deprecated: Generated code now calls gensupport.Expand. This function is
+// kept for packages generated by older versions of the generator.
 func Expand(u *url.URL, expansions map[string]string) {
 	expanded, err := uritemplates.Expand(u.Path, expansions)
 	if err == nil {
@@ -319,6 +411,9 @@ func Expand(u *url.URL, expansions map[string]string) {
 // CloseBody is used to close res.Body.
 // Prior to calling Close, it also tries to Read a small amount to see an EOF.
 // Not seeing an EOF can prevent HTTP Transports from reusing connections.
+//
+// Deprecated: Generated code now calls gensupport.CloseBody. This function is
+// kept for packages generated by older versions of the generator.
 func CloseBody(res *http.Response) {
 	if res == nil || res.Body == nil {
 		return
@@ -342,6 +437,9 @@ func CloseBody(res *http.Response) {
 // VariantType returns the type name of the given variant.
 // If the map doesn't contain the named key or the value is not a []interface{}, "" is returned.
 // This is used to support "variant" APIs that can return one of a number of different types.
+//
+// Deprecated: Generated code now calls gensupport.VariantType. This function is
+// kept for packages generated by older versions of the generator.
 func VariantType(t map[string]interface{}) string {
 	s, _ := t["type"].(string)
 	return s
@@ -350,6 +448,9 @@ func VariantType(t map[string]interface{}) string {
 // ConvertVariant uses the JSON encoder/decoder to fill in the struct 'dst' with the fields found in variant 'v'.
 // This is used to support "variant" APIs that can return one of a number of different types.
 // It reports whether the conversion was successful.
+//
+// Deprecated: Generated code now calls gensupport.ConvertVariant. This function is
+// kept for packages generated by older versions of the generator.
 func ConvertVariant(v map[string]interface{}, dst interface{}) bool {
 	var buf bytes.Buffer
 	err := json.NewEncoder(&buf).Encode(v)
@@ -385,6 +486,9 @@ func ConvertVariant(v map[string]interface{}, dst interface{}) bool {
 type Field string
 
 // CombineFields combines fields into a single string.
+//
+// Deprecated: Generated code now calls gensupport.CombineFields. This function is
+// kept for packages generated by older versions of the generator.
 func CombineFields(s []Field) string {
 	r := make([]string, len(s))
 	for i, v := range s {
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -257,6 +258,31 @@ var checkResponseTests = []CheckResponseTest{
 		},
 		`googleapi: got HTTP response code 400 with body: {"error":"invalid_token","error_description":"Invalid Value"}`,
 	},
+	{
+		&http.Response{
+			StatusCode: http.StatusBadGateway,
+			Header:     http.Header{"Content-Type": {"text/html; charset=UTF-8"}},
+		},
+		"<html>\n  <head><title>502 Bad Gateway</title></head>\n  <body>" + strings.Repeat("x", 300) + "</body>\n</html>\n",
+		&Error{
+			Code:   http.StatusBadGateway,
+			Body:   "<html>\n  <head><title>502 Bad Gateway</title></head>\n  <body>" + strings.Repeat("x", 300) + "</body>\n</html>\n",
+			Header: http.Header{"Content-Type": {"text/html; charset=UTF-8"}},
+		},
+		"googleapi: got HTTP response code 502 with text/html; charset=UTF-8 body: " +
+			"<html> <head><title>502 Bad Gateway</title></head> <body>" + strings.Repeat("x", 199) + "...",
+	},
+	{
+		&http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+		},
+		"Service Unavailable\n",
+		&Error{
+			Code: http.StatusServiceUnavailable,
+			Body: "Service Unavailable\n",
+		},
+		"googleapi: got HTTP response code 503 with body: Service Unavailable",
+	},
 	{
 		&http.Response{
 			StatusCode: http.StatusBadRequest,
@@ -378,3 +404,56 @@ func TestRoundChunkSize(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONReader(t *testing.T) {
+	// Encoding buffers are pooled; each reader must keep its own contents.
+	r1, err := WithDataWrapper.JSONReader(map[string]string{"a": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := WithoutDataWrapper.JSONReader(map[string]string{"b": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		r    io.Reader
+		want string
+	}{
+		{r1, `{"data": {"a":"1"}` + "\n}"},
+		{r2, `{"b":"2"}` + "\n"},
+	} {
+		got, _ := ioutil.ReadAll(tt.r)
+		if string(got) != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func BenchmarkJSONReader(b *testing.B) {
+	v := map[string]interface{}{
+		"name":        "object.txt",
+		"contentType": "text/plain",
+		"metadata":    map[string]string{"a": "1", "b": "2"},
+		"acl":         []string{"user-a", "user-b", "allUsers"},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := WithoutDataWrapper.JSONReader(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheckResponse(b *testing.B) {
+	body := `{"error":{"code":404,"message":"Not Found","errors":[{"reason":"notFound","message":"Not Found"}]}}`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		res := &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+		if CheckResponse(res) == nil {
+			b.Fatal("got nil error")
+		}
+	}
+}
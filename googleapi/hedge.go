@@ -0,0 +1,21 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "time"
+
+// HedgePolicy configures hedged requests for the GET calls made through a
+// Service. When a call has not received a response within the hedging
+// delay, a second, identical request is sent; whichever responds first
+// is used and the other is cancelled. This trades a little extra load
+// for lower tail latency on read paths.
+//
+// The hedging delay is the 95th percentile of the latencies of recent
+// calls, so that roughly one call in twenty is hedged.
+type HedgePolicy struct {
+	// Delay is the hedging delay used until enough calls have completed
+	// to estimate the 95th percentile, and the least delay used after.
+	Delay time.Duration
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "encoding/json"
+
+// KindOf returns the "kind" field of the JSON object data, such as
+// "drive#file", which names the type of a resource. It returns "" if
+// data has no kind.
+func KindOf(data []byte) (string, error) {
+	var v struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	return v.Kind, nil
+}
+
+// IsKind reports whether the JSON object data has the given kind.
+func IsKind(data []byte, kind string) bool {
+	k, err := KindOf(data)
+	return err == nil && k == kind
+}
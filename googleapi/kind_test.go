@@ -0,0 +1,35 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "testing"
+
+func TestKindOf(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		want string
+	}{
+		{`{"kind": "drive#file", "id": "a"}`, "drive#file"},
+		{`{"id": "a"}`, ""},
+	} {
+		got, err := KindOf([]byte(tt.data))
+		if err != nil {
+			t.Errorf("KindOf(%s): %v", tt.data, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("KindOf(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+	if _, err := KindOf([]byte(`[1]`)); err == nil {
+		t.Error("KindOf of an array: got nil error")
+	}
+	if !IsKind([]byte(`{"kind": "drive#file"}`), "drive#file") {
+		t.Error("IsKind: got false, want true")
+	}
+	if IsKind([]byte(`{"kind": "drive#change"}`), "drive#file") {
+		t.Error("IsKind: got true, want false")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// A LatencyClass is the latency expected of the calls of a method, which
+// generated packages check each call against, to track the health of
+// the APIs a program depends on against its service level objectives.
+type LatencyClass struct {
+	Name   string        // e.g. "interactive"
+	Budget time.Duration // time within which calls are expected to respond
+}
+
+// Common latency classes. Programs may declare others.
+var (
+	Interactive = LatencyClass{Name: "interactive", Budget: 500 * time.Millisecond}
+	Batch       = LatencyClass{Name: "batch", Budget: 10 * time.Second}
+)
+
+// SetLatencyClass declares class as the latency expected of the calls of
+// the method with the given ID, e.g. "storage.objects.get", as recorded
+// by LookupMethod. A class with a zero Budget removes the declaration.
+func SetLatencyClass(methodID string, class LatencyClass) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if class.Budget <= 0 {
+		delete(latencyClasses, methodID)
+		return
+	}
+	latencyClasses[methodID] = class
+}
+
+// LookupLatencyClass returns the latency class declared for the method
+// with the given ID by SetLatencyClass, if any.
+func LookupLatencyClass(methodID string) (LatencyClass, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	c, ok := latencyClasses[methodID]
+	return c, ok
+}
+
+// An SLOViolation reports a call which took longer than the budget of
+// the latency class of its method.
+type SLOViolation struct {
+	MethodID   string
+	Class      LatencyClass
+	Latency    time.Duration // time taken to receive the response, or fail
+	StatusCode int           // status of the response; 0 if there was none
+	Err        error         // error returned by the call, if any
+}
+
+func (v *SLOViolation) String() string {
+	return fmt.Sprintf("%s took %v, over the %v budget of its %s latency class", v.MethodID, v.Latency, v.Class.Budget, v.Class.Name)
+}
+
+var sloHandler func(*SLOViolation)
+
+// HandleSLOViolations sets f as the function called with the SLO
+// violations of calls made through Services which have no handler of
+// their own, so that they may be tracked in one place. A nil f, the
+// default, drops them.
+func HandleSLOViolations(f func(*SLOViolation)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	sloHandler = f
+}
+
+// SLOHandler returns the function set by HandleSLOViolations.
+func SLOHandler() func(*SLOViolation) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return sloHandler
+}
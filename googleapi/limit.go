@@ -0,0 +1,53 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+// A RateLimitError wraps an *Error reporting that calls were made faster
+// than a rate limit allows, for the project (ReasonRateLimitExceeded) or
+// for the user (ReasonUserRateLimitExceeded). The call may succeed if
+// retried after a pause.
+type RateLimitError struct {
+	Err    *Error
+	Reason string
+}
+
+func (e *RateLimitError) Error() string { return e.Err.Error() }
+
+// Retryable reports that the call may succeed if retried after a pause.
+func (e *RateLimitError) Retryable() bool { return true }
+
+// A QuotaError wraps an *Error reporting that a quota has been exhausted,
+// for the period (ReasonDailyLimitExceeded) or for the resources the
+// call would use (ReasonQuotaExceeded). Retrying the call will not help
+// until the quota is reset or raised.
+type QuotaError struct {
+	Err    *Error
+	Reason string
+}
+
+func (e *QuotaError) Error() string { return e.Err.Error() }
+
+// Retryable reports that retrying the call will not help.
+func (e *QuotaError) Retryable() bool { return false }
+
+// ClassifyLimit returns a *RateLimitError or *QuotaError wrapping err if
+// err is an *Error with a reason reporting that a limit was exceeded, or
+// else err itself. Both types have a Retryable method, which the retry
+// policy of generated packages follows.
+func ClassifyLimit(err error) error {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	for _, item := range e.Errors {
+		switch item.Reason {
+		case ReasonRateLimitExceeded, ReasonUserRateLimitExceeded:
+			return &RateLimitError{Err: e, Reason: item.Reason}
+		case ReasonQuotaExceeded, ReasonDailyLimitExceeded:
+			return &QuotaError{Err: e, Reason: item.Reason}
+		}
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyLimit(t *testing.T) {
+	type retryable interface {
+		Retryable() bool
+	}
+	for _, tt := range []struct {
+		reason        string
+		wantRetryable bool
+		wantType      string
+	}{
+		{ReasonRateLimitExceeded, true, "*googleapi.RateLimitError"},
+		{ReasonUserRateLimitExceeded, true, "*googleapi.RateLimitError"},
+		{ReasonQuotaExceeded, false, "*googleapi.QuotaError"},
+		{ReasonDailyLimitExceeded, false, "*googleapi.QuotaError"},
+	} {
+		e := &Error{Code: 403, Errors: []ErrorItem{{Reason: tt.reason}}}
+		got := ClassifyLimit(e)
+		r, ok := got.(retryable)
+		if !ok {
+			t.Errorf("%s: got %T, want %s", tt.reason, got, tt.wantType)
+			continue
+		}
+		if r.Retryable() != tt.wantRetryable {
+			t.Errorf("%s: Retryable() = %v, want %v", tt.reason, r.Retryable(), tt.wantRetryable)
+		}
+		if got.Error() != e.Error() {
+			t.Errorf("%s: message %q, want %q", tt.reason, got.Error(), e.Error())
+		}
+	}
+	for _, err := range []error{
+		nil,
+		errors.New("other"),
+		&Error{Code: 404, Errors: []ErrorItem{{Reason: ReasonNotFound}}},
+	} {
+		if got := ClassifyLimit(err); got != err {
+			t.Errorf("ClassifyLimit(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
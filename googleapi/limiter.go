@@ -0,0 +1,146 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A Limiter limits the rate of calls made on behalf of one party, such
+// as one customer of a service which shares a project's quota among its
+// customers. It allows Limit calls per second on average, and bursts of
+// up to Burst calls. A Limiter with a Limit of zero allows all calls.
+// A Limiter is safe for concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  float64 // calls per second
+	burst  float64
+	tokens float64   // calls which may be made now
+	last   time.Time // when tokens was last brought up to date
+}
+
+// SetLimit changes the rate and burst size which l allows. A burst of
+// less than one is taken as one.
+func (l *Limiter) SetLimit(perSecond float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.refill(timeNow())
+	}
+	l.limit, l.burst = perSecond, float64(burst)
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// refill brings l.tokens up to date at now, starting with a full burst
+// the first time. l.mu must be held.
+func (l *Limiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.limit
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	} else {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// Wait pauses until l allows another call, or ctx is done. ctx may be
+// nil.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.limit <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := timeNow()
+	l.refill(now)
+	l.tokens--
+	var pause time.Duration
+	if l.tokens < 0 {
+		pause = time.Duration(-l.tokens / l.limit * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if pause == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t := time.NewTimer(pause)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the call which was not made.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+var limiters = struct {
+	sync.Mutex
+	m     map[string]*Limiter
+	limit float64
+	burst int
+}{m: make(map[string]*Limiter)}
+
+// SetDefaultLimit sets the rate and burst size of the Limiters which
+// LimiterFor creates from now on. Until it is called, they allow all
+// calls.
+func SetDefaultLimit(perSecond float64, burst int) {
+	limiters.Lock()
+	defer limiters.Unlock()
+	limiters.limit, limiters.burst = perSecond, burst
+}
+
+// LimiterFor returns the Limiter for key, such as a customer ID, creating
+// it with the default limit set by SetDefaultLimit if there is none. Its
+// limit may be changed with SetLimit, for instance to give one customer
+// a larger share of the quota.
+func LimiterFor(key string) *Limiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+	l := limiters.m[key]
+	if l == nil {
+		l = new(Limiter)
+		l.SetLimit(limiters.limit, limiters.burst)
+		limiters.m[key] = l
+	}
+	return l
+}
+
+// LimitedBy returns a CallOption which makes a call wait until the
+// Limiter LimiterFor(key) allows it before sending its request. Packages
+// generated before the option was added ignore it.
+func LimitedBy(key string) CallOption { return limiterKey(key) }
+
+type limiterKey string
+
+// Get returns no URL parameter: the option is acted on by
+// LimiterFromOptions.
+func (k limiterKey) Get() (string, string) { return "", "" }
+
+// LimiterFromOptions returns the Limiter chosen by the last LimitedBy
+// option among opts, if any.
+func LimiterFromOptions(opts ...CallOption) (*Limiter, bool) {
+	for i := len(opts) - 1; i >= 0; i-- {
+		if k, ok := opts[i].(limiterKey); ok {
+			return LimiterFor(string(k)), true
+		}
+	}
+	return nil, false
+}
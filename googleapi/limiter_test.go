@@ -0,0 +1,63 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestLimiter(t *testing.T) {
+	defer func(old func() time.Time) { timeNow = old }(timeNow)
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+
+	l := new(Limiter)
+	if err := l.Wait(nil); err != nil {
+		t.Fatalf("unlimited Limiter: %v", err)
+	}
+	l.SetLimit(0.001, 2)
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(nil); err != nil {
+			t.Fatalf("call %d of burst: %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("call beyond burst: got %v, want %v", err, context.Canceled)
+	}
+	// The call given back is allowed once another has been refilled.
+	now = now.Add(1000 * time.Second)
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("call after refill: %v", err)
+	}
+}
+
+func TestLimiterFor(t *testing.T) {
+	SetDefaultLimit(5, 1)
+	defer SetDefaultLimit(0, 0)
+
+	a := LimiterFor("customer-a")
+	if a != LimiterFor("customer-a") {
+		t.Error("LimiterFor returned different Limiters for the same key")
+	}
+	if a == LimiterFor("customer-b") {
+		t.Error("LimiterFor returned the same Limiter for different keys")
+	}
+	if a.limit != 5 || a.burst != 1 {
+		t.Errorf("new Limiter has limit %v, burst %v; want 5, 1", a.limit, a.burst)
+	}
+
+	opts := []CallOption{QuotaUser("u"), LimitedBy("customer-b"), LimitedBy("customer-a")}
+	if l, ok := LimiterFromOptions(opts...); !ok || l != a {
+		t.Errorf("LimiterFromOptions chose %p, %v; want the last, %p", l, ok, a)
+	}
+	if _, ok := LimiterFromOptions(QuotaUser("u")); ok {
+		t.Error("LimiterFromOptions found a Limiter among options without one")
+	}
+}
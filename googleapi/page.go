@@ -0,0 +1,46 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+// PageResponse is implemented by the responses of list methods which
+// return their results in pages.
+type PageResponse interface {
+	// GetNextPageToken returns the token of the page following this
+	// one, or "" if this is the last page.
+	GetNextPageToken() string
+}
+
+// PageIterator is implemented by the calls of list methods which return
+// their results in pages, so that code to page through results can be
+// written once for all APIs.
+type PageIterator interface {
+	// SetPageToken sets the token of the page which the call fetches.
+	// The empty token fetches the first page.
+	SetPageToken(token string)
+
+	// DoPage executes the call, returning the page of results.
+	DoPage(opts ...CallOption) (PageResponse, error)
+}
+
+// ForEachPage calls f for each page of results fetched by it, starting
+// with the page whose token was last set, until the last page has been
+// seen or an error is returned by it or f. The page token of it is left
+// set to that of the last page fetched.
+func ForEachPage(it PageIterator, f func(PageResponse) error, opts ...CallOption) error {
+	for {
+		page, err := it.DoPage(opts...)
+		if err != nil {
+			return err
+		}
+		if err := f(page); err != nil {
+			return err
+		}
+		token := page.GetNextPageToken()
+		if token == "" {
+			return nil
+		}
+		it.SetPageToken(token)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testPage struct {
+	items []string
+	next  string
+}
+
+func (p *testPage) GetNextPageToken() string { return p.next }
+
+// testPages serves the pages of a fixed list, keyed by page token.
+type testPages struct {
+	pages map[string]*testPage
+	token string
+}
+
+func (it *testPages) SetPageToken(token string) { it.token = token }
+
+func (it *testPages) DoPage(opts ...CallOption) (PageResponse, error) {
+	p, ok := it.pages[it.token]
+	if !ok {
+		return nil, errors.New("bad page token")
+	}
+	return p, nil
+}
+
+func TestForEachPage(t *testing.T) {
+	it := &testPages{pages: map[string]*testPage{
+		"":   {items: []string{"a", "b"}, next: "t1"},
+		"t1": {items: []string{"c"}, next: "t2"},
+		"t2": {items: []string{"d"}},
+	}}
+	var got []string
+	err := ForEachPage(it, func(p PageResponse) error {
+		got = append(got, p.(*testPage).items...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got items %q, want %q", got, want)
+	}
+
+	stop := errors.New("stop")
+	it.SetPageToken("")
+	pages := 0
+	err = ForEachPage(it, func(p PageResponse) error {
+		pages++
+		return stop
+	})
+	if err != stop || pages != 1 {
+		t.Errorf("got error %v after %d pages, want %v after 1", err, pages, stop)
+	}
+
+	it.SetPageToken("bogus")
+	if err := ForEachPage(it, func(PageResponse) error { return nil }); err == nil {
+		t.Error("got nil error for a bad page token")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A Task is one of the calls run by Parallel. It is usually a closure
+// executing a generated call with ctx, whatever its API and response
+// type:
+//
+//   func(ctx context.Context) (interface{}, error) {
+//           return svc.Objects.Get(bucket, name).Context(ctx).Do()
+//   }
+type Task func(ctx context.Context) (interface{}, error)
+
+// A Result is the outcome of a Task run by Parallel.
+type Result struct {
+	// Value is the value returned by the last run of the task. It may
+	// be converted back to the call's response type.
+	Value interface{}
+
+	// Err is the error returned by the last run of the task, if any.
+	Err error
+
+	// Attempts is the number of times the task was run.
+	Attempts int
+}
+
+// DefaultParallelLimit is the number of tasks which Parallel runs at
+// once.
+const DefaultParallelLimit = 8
+
+// ParallelPolicy configures how tasks are run by its Run method.
+type ParallelPolicy struct {
+	// Limit is the number of tasks run at once. If zero,
+	// DefaultParallelLimit is used; if negative, all tasks are run at
+	// once.
+	Limit int
+
+	// Retries is the number of times a task which fails with a
+	// retryable error, such as a *RateLimitError or an *Error with a
+	// 5xx status code, is run again.
+	Retries int
+
+	// Backoff is the pause before a task is run again, doubled for each
+	// further attempt. If zero, tasks are run again at once.
+	Backoff time.Duration
+
+	// FailFast sets whether the first task to fail, after any retries,
+	// cancels the context of the tasks still running and keeps those not
+	// yet started from starting.
+	FailFast bool
+}
+
+// Parallel runs tasks concurrently, DefaultParallelLimit at a time and
+// without retries, and returns their results in the order of tasks
+// together with the error of the first task which failed, if any.
+// Use a ParallelPolicy to run them otherwise.
+func Parallel(ctx context.Context, tasks ...Task) ([]Result, error) {
+	var p ParallelPolicy
+	return p.Run(ctx, tasks...)
+}
+
+// Run runs tasks concurrently as p configures, and returns their results
+// in the order of tasks together with the error of the first task which
+// failed, if any. Tasks kept from starting by FailFast or by ctx being
+// done have a Result with no attempts and the error which stopped them.
+// ctx may be nil.
+func (p *ParallelPolicy) Run(ctx context.Context, tasks ...Task) ([]Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := p.Limit
+	if limit == 0 {
+		limit = DefaultParallelLimit
+	}
+	if limit < 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+
+	results := make([]Result, len(tasks))
+	var (
+		mu     sync.Mutex
+		failed error // the error which stopped the remaining tasks
+	)
+	// stopped returns the error keeping tasks from starting, if any.
+	stopped := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed != nil {
+			return failed
+		}
+		return ctx.Err()
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r := &results[i]
+				if err := stopped(); err != nil {
+					r.Err = err
+					continue
+				}
+				p.runTask(ctx, tasks[i], r)
+				if r.Err != nil && p.FailFast {
+					mu.Lock()
+					if failed == nil {
+						failed = r.Err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+	return results, nil
+}
+
+// runTask runs t into r, running it again as p allows.
+func (p *ParallelPolicy) runTask(ctx context.Context, t Task, r *Result) {
+	pause := p.Backoff
+	for {
+		r.Value, r.Err = t(ctx)
+		r.Attempts++
+		if r.Err == nil || r.Attempts > p.Retries || !retryable(r.Err) {
+			return
+		}
+		if pause > 0 {
+			timer := time.NewTimer(pause)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			pause *= 2
+		}
+	}
+}
+
+// retryable reports whether a call which failed with err may succeed if
+// made again.
+func retryable(err error) bool {
+	if r, ok := ClassifyLimit(err).(interface {
+		Retryable() bool
+	}); ok {
+		return r.Retryable()
+	}
+	e, ok := err.(*Error)
+	return ok && (e.Code == 429 || e.Code >= 500)
+}
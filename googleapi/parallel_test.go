@@ -0,0 +1,96 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestParallel(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		running, most int
+		flakyAttempts int
+	)
+	task := func(v interface{}) Task {
+		return func(ctx context.Context) (interface{}, error) {
+			mu.Lock()
+			running++
+			if running > most {
+				most = running
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			return v, nil
+		}
+	}
+	flaky := func(ctx context.Context) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		flakyAttempts++
+		if flakyAttempts < 3 {
+			return nil, &Error{Code: 503}
+		}
+		return "flaky", nil
+	}
+	bad := &Error{Code: 404}
+	notFound := func(ctx context.Context) (interface{}, error) { return nil, bad }
+
+	tasks := []Task{task(1), task("two"), flaky, notFound}
+	for i := 0; i < 10; i++ {
+		tasks = append(tasks, task(i))
+	}
+	p := &ParallelPolicy{Limit: 2, Retries: 2}
+	results, err := p.Run(context.Background(), tasks...)
+	if err != bad {
+		t.Errorf("got error %v, want %v", err, bad)
+	}
+	if most > 2 {
+		t.Errorf("%d tasks ran at once, want at most 2", most)
+	}
+	if len(results) != len(tasks) {
+		t.Fatalf("got %d results, want %d", len(results), len(tasks))
+	}
+	if results[0].Value != 1 || results[1].Value != "two" {
+		t.Errorf("got values %v and %v, want 1 and two", results[0].Value, results[1].Value)
+	}
+	if r := results[2]; r.Value != "flaky" || r.Err != nil || r.Attempts != 3 {
+		t.Errorf("flaky task: got %+v, want value flaky after 3 attempts", r)
+	}
+	if r := results[3]; r.Err != bad || r.Attempts != 1 {
+		t.Errorf("failing task: got %+v, want %v after 1 attempt", r, bad)
+	}
+}
+
+func TestParallelFailFast(t *testing.T) {
+	boom := errors.New("boom")
+	ran := make([]bool, 5)
+	tasks := []Task{func(ctx context.Context) (interface{}, error) { return nil, boom }}
+	for i := 1; i < len(ran); i++ {
+		i := i
+		tasks = append(tasks, func(ctx context.Context) (interface{}, error) {
+			ran[i] = true
+			return nil, nil
+		})
+	}
+	p := &ParallelPolicy{Limit: 1, FailFast: true}
+	results, err := p.Run(nil, tasks...)
+	if err != boom {
+		t.Fatalf("got error %v, want %v", err, boom)
+	}
+	for i, r := range results[1:] {
+		if ran[i+1] || r.Err != boom || r.Attempts != 0 {
+			t.Errorf("task %d: ran %v, result %+v; want not run, with error %v", i+1, ran[i+1], r, boom)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// A PingResult is returned by the Ping method of a generated Service,
+// which sends one cheap request to check, at startup, that the service
+// is configured so that calls can succeed.
+type PingResult struct {
+	// Method is the ID of the method called, or "OPTIONS" if the API
+	// has no method cheap enough and an OPTIONS request was sent to the
+	// base URL instead.
+	Method string
+
+	// URL is the URL requested.
+	URL string
+
+	// StatusCode is the status code of the response, or zero if none
+	// was received.
+	StatusCode int
+
+	// Latency is the time taken to receive the response, or the error.
+	Latency time.Duration
+
+	// Err is the error sending the request, or the error response, if
+	// any.
+	Err error
+}
+
+// Reachable reports whether the server responded.
+func (r *PingResult) Reachable() bool { return r.StatusCode != 0 }
+
+// OK reports whether the configuration appears sound: the server
+// responded and neither rejected the credentials nor failed to find
+// the URL. An OPTIONS request or a method needing parameters may be
+// answered with other errors while the configuration is sound.
+func (r *PingResult) OK() bool {
+	switch {
+	case !r.Reachable(), r.StatusCode == 401, r.StatusCode == 403, r.StatusCode == 404:
+		return false
+	}
+	return true
+}
+
+// Diagnosis describes what r shows about the configuration, in a form
+// suitable for logs.
+func (r *PingResult) Diagnosis() string {
+	switch {
+	case !r.Reachable():
+		return fmt.Sprintf("network: cannot reach %s: %v", r.URL, r.Err)
+	case r.StatusCode == 401:
+		return "credentials: missing or invalid; check the client's token source"
+	case r.StatusCode == 403:
+		return "credentials: lack permission, or the API is not enabled for the project"
+	case r.StatusCode == 404:
+		return fmt.Sprintf("base URL: %s not found; check BasePath", r.URL)
+	}
+	return fmt.Sprintf("ok: %s answered with status %d in %v", r.Method, r.StatusCode, r.Latency)
+}
+
+func (r *PingResult) String() string { return r.Diagnosis() }
@@ -0,0 +1,339 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A QueueStore durably holds the requests of a Queue, in order.
+// Implementations must be safe for concurrent use.
+type QueueStore interface {
+	// Push adds req to the back of the queue.
+	Push(req []byte) error
+	// Peek returns the request at the front of the queue, or false if
+	// the queue is empty.
+	Peek() (req []byte, ok bool, err error)
+	// Pop removes the request at the front of the queue.
+	Pop() error
+	// Len returns the number of requests in the queue.
+	Len() (int, error)
+}
+
+// MemoryQueueStore is a QueueStore which keeps requests in memory, and
+// so loses them when the process exits. The zero value is an empty
+// queue ready to use.
+type MemoryQueueStore struct {
+	mu   sync.Mutex
+	reqs [][]byte
+}
+
+func (s *MemoryQueueStore) Push(req []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, append([]byte(nil), req...))
+	return nil
+}
+
+func (s *MemoryQueueStore) Peek() ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reqs) == 0 {
+		return nil, false, nil
+	}
+	return s.reqs[0], true, nil
+}
+
+func (s *MemoryQueueStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reqs) > 0 {
+		s.reqs = s.reqs[1:]
+	}
+	return nil
+}
+
+func (s *MemoryQueueStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.reqs), nil
+}
+
+// FileQueueStore is a QueueStore which keeps each request in a file of
+// its own in a directory, named by its position in the queue, so that
+// requests survive the process.
+type FileQueueStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// queueFileSuffix ends the names of the files of a FileQueueStore.
+const queueFileSuffix = ".request"
+
+// NewFileQueueStore returns a FileQueueStore keeping its requests in dir,
+// which is created if it does not exist. Requests already in dir, left
+// by an earlier process, are at the front of the queue.
+func NewFileQueueStore(dir string) (*FileQueueStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileQueueStore{dir: dir}, nil
+}
+
+// seqs returns the positions of the requests in s, in order.
+// s.mu must be held.
+func (s *FileQueueStore) seqs() ([]int64, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, "*"+queueFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var seqs []int64
+	for _, name := range names {
+		n, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), queueFileSuffix), 10, 64)
+		if err == nil {
+			seqs = append(seqs, n)
+		}
+	}
+	sort.Sort(int64s(seqs))
+	return seqs, nil
+}
+
+func (s *FileQueueStore) file(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, queueFileSuffix))
+}
+
+func (s *FileQueueStore) Push(req []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seqs, err := s.seqs()
+	if err != nil {
+		return err
+	}
+	next := int64(1)
+	if len(seqs) > 0 {
+		next = seqs[len(seqs)-1] + 1
+	}
+	// The request is written under another name and renamed, so that a
+	// crash does not leave a partial request in the queue.
+	f, err := ioutil.TempFile(s.dir, "push-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(req)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.file(next))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s *FileQueueStore) Peek() ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seqs, err := s.seqs()
+	if err != nil || len(seqs) == 0 {
+		return nil, false, err
+	}
+	req, err := ioutil.ReadFile(s.file(seqs[0]))
+	if err != nil {
+		return nil, false, err
+	}
+	return req, true, nil
+}
+
+func (s *FileQueueStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seqs, err := s.seqs()
+	if err != nil || len(seqs) == 0 {
+		return err
+	}
+	return os.Remove(s.file(seqs[0]))
+}
+
+func (s *FileQueueStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seqs, err := s.seqs()
+	return len(seqs), err
+}
+
+type int64s []int64
+
+func (s int64s) Len() int           { return len(s) }
+func (s int64s) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// ErrQueued is returned by Queue.Do when a request could not be sent
+// now, and was queued to be sent by a later Flush.
+var ErrQueued = errors.New("googleapi: request queued to be sent later")
+
+// A Queue sends mutating requests, serialized by the MarshalRequest
+// methods of generated calls, in the order they were made, holding them
+// in a QueueStore while the server cannot be reached, as happens to
+// programs which work offline.
+//
+// A request is held back when sending it fails without a response from
+// the server, or with a 429 or 5xx response, and is sent again by the
+// next Flush. A request which the server rejects otherwise is dropped,
+// after being passed to OnConflict or OnFailure.
+type Queue struct {
+	// Store holds the requests not yet sent. It must be non-nil.
+	Store QueueStore
+
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// OnConflict, if non-nil, is called when the server rejects a
+	// request with 409 Conflict or 412 Precondition Failed, as when the
+	// resource was changed by someone else while the request was held.
+	// It returns a request to send in its place, such as one rebuilt
+	// from the current state of the resource, or nil to drop it.
+	OnConflict func(req []byte, err *Error) []byte
+
+	// OnFailure, if non-nil, is called with the other error responses
+	// for which a request is dropped, and with a conflict if OnConflict
+	// is nil.
+	OnFailure func(req []byte, err *Error)
+
+	mu sync.Mutex // serializes sending, to keep requests in order
+}
+
+// maxConflicts is the number of replacements for one request which a
+// Queue sends before dropping it.
+const maxConflicts = 3
+
+// Do sends req, a serialized request, if the queue is empty, and
+// otherwise, or if it cannot be sent now, adds it to the back of the
+// queue and returns ErrQueued. The caller must close the body of a
+// returned response.
+func (q *Queue) Do(req []byte) (*http.Response, error) {
+	if err := checkSerialized(req); err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n, err := q.Store.Len()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		res, err := Execute(req, q.client())
+		if err == nil || !transient(err) {
+			return res, err
+		}
+	}
+	if err := q.Store.Push(req); err != nil {
+		return nil, err
+	}
+	return nil, ErrQueued
+}
+
+// Enqueue adds req, a serialized request, to the back of the queue
+// without trying to send it.
+func (q *Queue) Enqueue(req []byte) error {
+	if err := checkSerialized(req); err != nil {
+		return err
+	}
+	return q.Store.Push(req)
+}
+
+// Flush sends the queued requests in order until the queue is empty or
+// a request cannot be sent now, which is left at the front of the queue.
+// It returns the number of requests which were sent or dropped, and the
+// error which stopped it, if any.
+func (q *Queue) Flush() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	done := 0
+	for {
+		req, ok, err := q.Store.Peek()
+		if err != nil || !ok {
+			return done, err
+		}
+		if err := q.send(req); err != nil {
+			return done, err
+		}
+		if err := q.Store.Pop(); err != nil {
+			return done, err
+		}
+		done++
+	}
+}
+
+// send sends req, passing an error response to OnConflict or OnFailure.
+// It returns an error only if req should be held back.
+func (q *Queue) send(req []byte) error {
+	for i := 0; ; i++ {
+		res, err := Execute(req, q.client())
+		if err == nil {
+			res.Body.Close()
+			return nil
+		}
+		if transient(err) {
+			return err
+		}
+		e := err.(*Error)
+		conflict := e.Code == http.StatusConflict || e.Code == http.StatusPreconditionFailed
+		if conflict && q.OnConflict != nil {
+			if req = q.OnConflict(req, e); req == nil || i == maxConflicts {
+				return nil
+			}
+			if err := checkSerialized(req); err != nil {
+				return nil
+			}
+			continue
+		}
+		if q.OnFailure != nil {
+			q.OnFailure(req, e)
+		}
+		return nil
+	}
+}
+
+func (q *Queue) client() *http.Client {
+	if q.Client != nil {
+		return q.Client
+	}
+	return http.DefaultClient
+}
+
+// transient reports whether a request which failed with err may succeed
+// if sent again later.
+func transient(err error) bool {
+	e, ok := err.(*Error)
+	return !ok || e.Code == 429 || e.Code >= 500
+}
+
+// checkSerialized returns an error if req is not a serialized request,
+// so that one which could never be sent does not block a queue.
+func checkSerialized(req []byte) error {
+	var sr SerializedRequest
+	if err := json.Unmarshal(req, &sr); err != nil {
+		return fmt.Errorf("googleapi: not a serialized request: %v", err)
+	}
+	if sr.Method == "" || sr.URL == "" {
+		return errors.New("googleapi: serialized request is missing its method or URL")
+	}
+	return nil
+}
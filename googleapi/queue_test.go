@@ -0,0 +1,150 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func serialize(t *testing.T, method, url string) []byte {
+	b, err := json.Marshal(SerializedRequest{Method: method, URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestQueue(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		offline bool
+		got     []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case offline:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/conflict":
+			w.WriteHeader(http.StatusConflict)
+		case r.URL.Path == "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			got = append(got, r.Method+" "+r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	setOffline := func(v bool) {
+		mu.Lock()
+		offline = v
+		mu.Unlock()
+	}
+
+	var conflicts, failures int
+	q := &Queue{
+		Store: new(MemoryQueueStore),
+		OnConflict: func(req []byte, err *Error) []byte {
+			conflicts++
+			return serialize(t, "PUT", srv.URL+"/resolved")
+		},
+		OnFailure: func(req []byte, err *Error) { failures++ },
+	}
+
+	res, err := q.Do(serialize(t, "POST", srv.URL+"/a"))
+	if err != nil {
+		t.Fatalf("Do while online: %v", err)
+	}
+	res.Body.Close()
+
+	setOffline(true)
+	for _, path := range []string{"/b", "/conflict", "/bad"} {
+		if _, err := q.Do(serialize(t, "POST", srv.URL+path)); err != ErrQueued {
+			t.Fatalf("Do %s while offline: got %v, want ErrQueued", path, err)
+		}
+	}
+	if err := q.Enqueue(serialize(t, "DELETE", srv.URL+"/c")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := q.Flush(); n != 0 || err == nil {
+		t.Fatalf("Flush while offline: got (%d, %v), want (0, error)", n, err)
+	}
+
+	setOffline(false)
+	if n, err := q.Flush(); n != 4 || err != nil {
+		t.Fatalf("Flush: got (%d, %v), want (4, nil)", n, err)
+	}
+	if n, _ := q.Store.Len(); n != 0 {
+		t.Errorf("%d requests left queued, want none", n)
+	}
+	want := []string{"POST /a", "POST /b", "PUT /resolved", "DELETE /c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+	if conflicts != 1 || failures != 1 {
+		t.Errorf("got %d conflicts and %d failures, want 1 and 1", conflicts, failures)
+	}
+
+	if err := q.Enqueue([]byte("not a request")); err == nil {
+		t.Error("Enqueue of a malformed request succeeded")
+	}
+}
+
+func TestFileQueueStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []string{"one", "two", "three"} {
+		if err := s.Push([]byte(req)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Pop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store over the same directory sees the requests left.
+	s, err = NewFileQueueStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Push([]byte("four")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Len(); n != 3 || err != nil {
+		t.Fatalf("Len() = %d, %v, want 3, nil", n, err)
+	}
+	var got []string
+	for {
+		req, ok, err := s.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, string(req))
+		if err := s.Pop(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"two", "three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
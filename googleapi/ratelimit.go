@@ -0,0 +1,103 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit holds the quota information a server reported in the
+// header of a response. Fields the server did not report are -1 or zero.
+//
+// Both the common X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset headers and their unprefixed RateLimit-* forms are
+// understood, as is Retry-After.
+type RateLimit struct {
+	// Limit is the number of requests permitted in the current window,
+	// or -1 if not reported.
+	Limit int64
+	// Remaining is the number of requests remaining in the current
+	// window, or -1 if not reported.
+	Remaining int64
+	// Reset is when the current window ends, or the zero time if not
+	// reported.
+	Reset time.Time
+	// RetryAfter is how long the server asked the client to wait before
+	// sending another request, or zero if not reported.
+	RetryAfter time.Duration
+}
+
+// resetEpoch distinguishes a reset header holding a Unix time from one
+// holding a number of seconds: no window is anywhere near this long.
+const resetEpoch = 1e9
+
+// ParseRateLimit returns the quota information in the response header h,
+// or false if h reports none.
+func ParseRateLimit(h http.Header) (*RateLimit, bool) {
+	rl := &RateLimit{Limit: -1, Remaining: -1}
+	found := false
+	if n, ok := rateLimitInt(h, "Limit"); ok {
+		rl.Limit, found = n, true
+	}
+	if n, ok := rateLimitInt(h, "Remaining"); ok {
+		rl.Remaining, found = n, true
+	}
+	if n, ok := rateLimitInt(h, "Reset"); ok {
+		if n >= resetEpoch {
+			rl.Reset = time.Unix(n, 0)
+		} else {
+			rl.Reset = responseTime(h).Add(time.Duration(n) * time.Second)
+		}
+		found = true
+	}
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+			rl.RetryAfter, found = time.Duration(secs)*time.Second, true
+		} else if t, err := http.ParseTime(v); err == nil {
+			if d := t.Sub(responseTime(h)); d > 0 {
+				rl.RetryAfter = d
+			}
+			found = true
+		}
+	}
+	return rl, found
+}
+
+// RateLimit returns the quota information reported by the server in the
+// response, or false if it reported none.
+func (r ServerResponse) RateLimit() (*RateLimit, bool) {
+	return ParseRateLimit(r.Header)
+}
+
+// rateLimitInt returns the value of the X-RateLimit-name or RateLimit-name
+// header in h.
+func rateLimitInt(h http.Header, name string) (int64, bool) {
+	for _, key := range []string{"X-RateLimit-" + name, "RateLimit-" + name} {
+		v := strings.TrimSpace(h.Get(key))
+		// Some servers append a policy, e.g. "100, 100;w=60".
+		if i := strings.IndexAny(v, ",;"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// timeNow is overridden in tests.
+var timeNow = time.Now
+
+// responseTime returns the time given in the Date header of h, or the
+// current time if there is none.
+func responseTime(h http.Header) time.Time {
+	if t, err := http.ParseTime(h.Get("Date")); err == nil {
+		return t
+	}
+	return timeNow()
+}
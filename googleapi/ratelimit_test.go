@@ -0,0 +1,64 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	clock := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return clock }
+
+	for _, tt := range []struct {
+		desc   string
+		header http.Header
+		want   *RateLimit // nil if none reported
+	}{
+		{"none", http.Header{"Content-Type": {"application/json"}}, nil},
+		{
+			"x-ratelimit with epoch reset",
+			http.Header{
+				"X-Ratelimit-Limit":     {"100"},
+				"X-Ratelimit-Remaining": {"7"},
+				"X-Ratelimit-Reset":     {"1462104060"},
+			},
+			&RateLimit{Limit: 100, Remaining: 7, Reset: time.Unix(1462104060, 0)},
+		},
+		{
+			"ratelimit with policy and delta reset",
+			http.Header{
+				"Ratelimit-Limit":     {"100, 100;w=60"},
+				"Ratelimit-Remaining": {"0"},
+				"Ratelimit-Reset":     {"30"},
+			},
+			&RateLimit{Limit: 100, Remaining: 0, Reset: clock.Add(30 * time.Second)},
+		},
+		{
+			"delta reset relative to Date",
+			http.Header{
+				"Date":              {"Sun, 01 May 2016 11:00:00 GMT"},
+				"X-Ratelimit-Reset": {"60"},
+			},
+			&RateLimit{Limit: -1, Remaining: -1, Reset: clock.Add(-59 * time.Minute)},
+		},
+		{"retry-after seconds", http.Header{"Retry-After": {"120"}}, &RateLimit{Limit: -1, Remaining: -1, RetryAfter: 2 * time.Minute}},
+		{"retry-after date", http.Header{"Retry-After": {"Sun, 01 May 2016 12:00:10 GMT"}}, &RateLimit{Limit: -1, Remaining: -1, RetryAfter: 10 * time.Second}},
+		{"malformed", http.Header{"X-Ratelimit-Remaining": {"lots"}, "Retry-After": {"soon"}}, nil},
+	} {
+		got, ok := ServerResponse{Header: tt.header}.RateLimit()
+		if ok != (tt.want != nil) {
+			t.Errorf("%s: got ok %v, want %v", tt.desc, ok, tt.want != nil)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.desc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+// Reasons reported in the Errors of an *Error by Google APIs. These are
+// the reasons common to all APIs; an API may report others, which are
+// given in its documentation.
+const (
+	ReasonBackendError            = "backendError"
+	ReasonBadRequest              = "badRequest"
+	ReasonConditionNotMet         = "conditionNotMet"
+	ReasonConflict                = "conflict"
+	ReasonDailyLimitExceeded      = "dailyLimitExceeded"
+	ReasonDuplicate               = "duplicate"
+	ReasonForbidden               = "forbidden"
+	ReasonInsufficientPermissions = "insufficientPermissions"
+	ReasonInternalError           = "internalError"
+	ReasonInvalid                 = "invalid"
+	ReasonInvalidParameter        = "invalidParameter"
+	ReasonKeyInvalid              = "keyInvalid"
+	ReasonNotFound                = "notFound"
+	ReasonNotModified             = "notModified"
+	ReasonQuotaExceeded           = "quotaExceeded"
+	ReasonRateLimitExceeded       = "rateLimitExceeded"
+	ReasonRequired                = "required"
+	ReasonUserRateLimitExceeded   = "userRateLimitExceeded"
+)
+
+// HasReason reports whether err is an *Error one of whose Errors has the
+// given reason, such as ReasonRateLimitExceeded.
+func HasReason(err error, reason string) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	for _, item := range e.Errors {
+		if item.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHasReason(t *testing.T) {
+	err := &Error{Code: 403, Errors: []ErrorItem{
+		{Reason: ReasonForbidden},
+		{Reason: ReasonRateLimitExceeded},
+	}}
+	for _, tt := range []struct {
+		err    error
+		reason string
+		want   bool
+	}{
+		{err, ReasonRateLimitExceeded, true},
+		{err, ReasonForbidden, true},
+		{err, ReasonNotFound, false},
+		{&Error{Code: 404}, ReasonNotFound, false},
+		{errors.New(ReasonNotFound), ReasonNotFound, false},
+		{nil, ReasonNotFound, false},
+	} {
+		if got := HasReason(tt.err, tt.reason); got != tt.want {
+			t.Errorf("HasReason(%v, %q) = %v, want %v", tt.err, tt.reason, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"sort"
+	"sync"
+)
+
+// APIInfo describes a generated API package linked into a binary.
+type APIInfo struct {
+	ID                string   // API ID, e.g. "storage:v1"
+	Name              string   // API name, e.g. "storage"
+	Version           string   // API version, e.g. "v1"
+	ClientVersion     string   // Version of this library the package was generated for
+	DiscoveryRevision string   // Revision of the discovery document the package was generated from
+	Labels            []string // Labels of the API, e.g. "limited_availability" or "deprecated"
+	Methods           []MethodInfo
+}
+
+// MethodInfo describes a method of a generated API package.
+type MethodInfo struct {
+	ID         string // Method ID, e.g. "storage.objects.get"
+	HTTPMethod string // e.g. "GET"
+
+	// Idempotent reports whether calling the method more than once has
+	// the same effect as calling it once, so that a failed call may be
+	// safely resent. It is judged from the HTTP method: GET, HEAD, PUT
+	// and DELETE methods are idempotent, except those uploading media.
+	Idempotent bool
+
+	// Labels holds the labels of the method, e.g. "deprecated".
+	Labels []string
+
+	// Scopes holds the OAuth2 scopes of which any one authorizes calls
+	// of the method.
+	Scopes []string
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]APIInfo)
+	methods    = make(map[string]MethodInfo)
+
+	latencyClasses = make(map[string]LatencyClass) // by method ID
+)
+
+// RegisterAPI records info for RegisteredAPIs and LookupMethod.
+// Generated packages call it from an init function.
+func RegisterAPI(info APIInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[info.ID] = info
+	for _, m := range info.Methods {
+		methods[m.ID] = m
+	}
+}
+
+// LookupMethod returns the description of the method with the given ID,
+// if it belongs to a registered API. Packages generated before methods
+// were registered do not describe their methods.
+func LookupMethod(id string) (MethodInfo, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	m, ok := methods[id]
+	return m, ok
+}
+
+// RegisteredAPIs returns the APIs registered with RegisterAPI, sorted by ID.
+// It can be used to report exactly which API surfaces a binary was built
+// against.
+func RegisteredAPIs() []APIInfo {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	infos := make([]APIInfo, 0, len(registry))
+	for _, info := range registry {
+		infos = append(infos, info)
+	}
+	sort.Sort(byID(infos))
+	return infos
+}
+
+type byID []APIInfo
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
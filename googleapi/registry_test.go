@@ -0,0 +1,45 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegisteredAPIs(t *testing.T) {
+	defer func(old map[string]APIInfo) { registry = old }(registry)
+	registry = make(map[string]APIInfo)
+
+	storage := APIInfo{ID: "storage:v1", Name: "storage", Version: "v1", ClientVersion: Version, DiscoveryRevision: "20160304"}
+	drive := APIInfo{ID: "drive:v3", Name: "drive", Version: "v3", ClientVersion: Version, DiscoveryRevision: "20160303"}
+	RegisterAPI(storage)
+	RegisterAPI(drive)
+	RegisterAPI(storage)
+
+	got := RegisteredAPIs()
+	want := []APIInfo{drive, storage}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLookupMethod(t *testing.T) {
+	defer func(old map[string]MethodInfo) { methods = old }(methods)
+	methods = make(map[string]MethodInfo)
+
+	get := MethodInfo{ID: "storage.objects.get", HTTPMethod: "GET", Idempotent: true, Labels: []string{"deprecated"}}
+	insert := MethodInfo{ID: "storage.objects.insert", HTTPMethod: "POST"}
+	RegisterAPI(APIInfo{ID: "storage:v1", Methods: []MethodInfo{get, insert}})
+
+	for _, want := range []MethodInfo{get, insert} {
+		if got, ok := LookupMethod(want.ID); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("LookupMethod(%q) = %+v, %v; want %+v, true", want.ID, got, ok, want)
+		}
+	}
+	if _, ok := LookupMethod("storage.objects.delete"); ok {
+		t.Error("found unregistered method")
+	}
+}
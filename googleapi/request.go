@@ -0,0 +1,52 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// SerializedRequest is the serialized form of a request built by a
+// generated call's MarshalRequest method.
+type SerializedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Execute sends a request previously serialized by a generated call's
+// MarshalRequest method, using client.
+// If the returned error is nil, the Response is guaranteed to have a 2xx
+// status code. Callers must close the Response.Body as usual, and may
+// decode it into the response type of the original call.
+func Execute(serialized []byte, client *http.Client) (*http.Response, error) {
+	var sr SerializedRequest
+	if err := json.Unmarshal(serialized, &sr); err != nil {
+		return nil, err
+	}
+	if sr.Method == "" || sr.URL == "" {
+		return nil, errors.New("googleapi: serialized request is missing its method or URL")
+	}
+	req, err := http.NewRequest(sr.Method, sr.URL, bytes.NewReader(sr.Body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range sr.Header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckResponse(res); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
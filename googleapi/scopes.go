@@ -0,0 +1,135 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// tokenInfoURL is the endpoint which reports the scopes of the token
+// sent to it. Tests replace it.
+var tokenInfoURL = "https://www.googleapis.com/oauth2/v3/tokeninfo"
+
+// A ScopeError is returned by CheckScopes and CheckMethodScopes when the
+// credentials lack scopes which calls will need.
+type ScopeError struct {
+	// Granted holds the scopes the credentials carry.
+	Granted []string
+
+	// Missing holds the scopes required but not granted. For
+	// CheckMethodScopes, it holds the scopes of which any one would
+	// authorize the methods in Methods.
+	Missing []string
+
+	// Methods holds the IDs of the methods which no granted scope
+	// authorizes, if the error is from CheckMethodScopes.
+	Methods []string
+}
+
+func (e *ScopeError) Error() string {
+	if len(e.Methods) > 0 {
+		return fmt.Sprintf("googleapi: credentials cannot call %s: they need one of the scopes %s, and carry only %s",
+			strings.Join(e.Methods, ", "), strings.Join(e.Missing, " "), strings.Join(e.Granted, " "))
+	}
+	return fmt.Sprintf("googleapi: credentials lack the scopes %s: they carry only %s",
+		strings.Join(e.Missing, " "), strings.Join(e.Granted, " "))
+}
+
+// GrantedScopes returns the OAuth2 scopes of the access token which
+// client, an authorized client such as one made by oauth2.NewClient,
+// sends, as reported by Google's tokeninfo endpoint.
+func GrantedScopes(client *http.Client) ([]string, error) {
+	res, err := client.Get(tokenInfoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := CheckResponse(res); err != nil {
+		return nil, err
+	}
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("googleapi: cannot decode token info: %v", err)
+	}
+	scopes := strings.Fields(info.Scope)
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// CheckScopes checks that the token which client sends carries all of
+// the required scopes, returning a *ScopeError naming those it lacks.
+// It is meant to be called at startup, so that a program with
+// insufficient credentials fails at once with a clear message rather
+// than on its first call needing a missing scope.
+func CheckScopes(client *http.Client, required ...string) error {
+	granted, err := GrantedScopes(client)
+	if err != nil {
+		return err
+	}
+	has := scopeSet(granted)
+	var missing []string
+	for _, s := range required {
+		if !has[s] {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		return &ScopeError{Granted: granted, Missing: missing}
+	}
+	return nil
+}
+
+// CheckMethodScopes checks that the token which client sends carries,
+// for each of the methods with the given IDs, one of the scopes which
+// authorize it, returning a *ScopeError naming those it cannot call.
+// The methods must belong to registered APIs; methods of packages
+// generated before their scopes were registered are taken to need none.
+func CheckMethodScopes(client *http.Client, methodIDs ...string) error {
+	granted, err := GrantedScopes(client)
+	if err != nil {
+		return err
+	}
+	has := scopeSet(granted)
+	e := &ScopeError{Granted: granted}
+	missing := make(map[string]bool)
+	for _, id := range methodIDs {
+		m, ok := LookupMethod(id)
+		if !ok {
+			return fmt.Errorf("googleapi: method %q is not registered", id)
+		}
+		authorized := len(m.Scopes) == 0
+		for _, s := range m.Scopes {
+			authorized = authorized || has[s]
+		}
+		if authorized {
+			continue
+		}
+		e.Methods = append(e.Methods, id)
+		for _, s := range m.Scopes {
+			if !missing[s] {
+				missing[s] = true
+				e.Missing = append(e.Missing, s)
+			}
+		}
+	}
+	if len(e.Methods) > 0 {
+		return e
+	}
+	return nil
+}
+
+func scopeSet(scopes []string) map[string]bool {
+	set := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		set[s] = true
+	}
+	return set
+}
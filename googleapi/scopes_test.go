@@ -0,0 +1,67 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCheckScopes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error_description": "Invalid Value"}`)
+			return
+		}
+		fmt.Fprint(w, `{"aud": "client", "scope": "https://www.googleapis.com/auth/b https://www.googleapis.com/auth/a", "expires_in": "3599"}`)
+	}))
+	defer srv.Close()
+	defer func(u string) { tokenInfoURL = u }(tokenInfoURL)
+	tokenInfoURL = srv.URL
+
+	const a, b, c = "https://www.googleapis.com/auth/a", "https://www.googleapis.com/auth/b", "https://www.googleapis.com/auth/c"
+	client := &http.Client{Transport: bearerTransport("tok")}
+	if err := CheckScopes(client, a, b); err != nil {
+		t.Errorf("CheckScopes(a, b): %v", err)
+	}
+	err := CheckScopes(client, a, c)
+	want := &ScopeError{Granted: []string{a, b}, Missing: []string{c}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("CheckScopes(a, c): got %v, want %v", err, want)
+	}
+	if err := CheckScopes(&http.Client{}, a); err == nil {
+		t.Error("CheckScopes without a token succeeded")
+	}
+
+	RegisterAPI(APIInfo{ID: "scopes:v1", Methods: []MethodInfo{
+		{ID: "scopes.read", Scopes: []string{c, a}},
+		{ID: "scopes.write", Scopes: []string{c}},
+		{ID: "scopes.old"},
+	}})
+	if err := CheckMethodScopes(client, "scopes.read", "scopes.old"); err != nil {
+		t.Errorf("CheckMethodScopes(read, old): %v", err)
+	}
+	err = CheckMethodScopes(client, "scopes.read", "scopes.write")
+	want = &ScopeError{Granted: []string{a, b}, Missing: []string{c}, Methods: []string{"scopes.write"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("CheckMethodScopes(read, write): got %v, want %v", err, want)
+	}
+}
+
+type bearerTransport string
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := *req
+	r.Header = make(http.Header)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+string(t))
+	return http.DefaultTransport.RoundTrip(&r)
+}
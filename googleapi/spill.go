@@ -0,0 +1,71 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// SpilledBody holds the contents of a response body read by Spill.
+// Small bodies are kept in memory; larger ones in a temporary file.
+// Callers must call Close when done to remove any temporary file.
+type SpilledBody struct {
+	// Size is the length of the body in bytes.
+	Size int64
+
+	r io.ReaderAt // *bytes.Reader or *os.File
+	f *os.File    // non-nil if the body was spilled to disk
+}
+
+// Spill reads r to EOF. If it yields at most threshold bytes they are held
+// in memory; otherwise the whole body is written to a temporary file in
+// dir (or the default directory for temporary files, if dir is empty),
+// so that multi-gigabyte downloads do not exhaust memory.
+func Spill(r io.Reader, threshold int64, dir string) (*SpilledBody, error) {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, threshold+1)
+	if err == io.EOF {
+		return &SpilledBody{Size: n, r: bytes.NewReader(buf.Bytes())}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(dir, "googleapi-spill-")
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(f, io.MultiReader(&buf, r))
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &SpilledBody{Size: size, r: f, f: f}, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (b *SpilledBody) ReadAt(p []byte, off int64) (int, error) {
+	return b.r.ReadAt(p, off)
+}
+
+// Spilled reports whether the body was written to a temporary file.
+func (b *SpilledBody) Spilled() bool {
+	return b.f != nil
+}
+
+// Close releases the body, removing its temporary file, if any.
+func (b *SpilledBody) Close() error {
+	if b.f == nil {
+		return nil
+	}
+	err := b.f.Close()
+	if rerr := os.Remove(b.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpill(t *testing.T) {
+	const body = "0123456789"
+	for _, tt := range []struct {
+		threshold   int64
+		wantSpilled bool
+	}{
+		{100, false},
+		{10, false},
+		{9, true},
+		{0, true},
+	} {
+		b, err := Spill(strings.NewReader(body), tt.threshold, "")
+		if err != nil {
+			t.Fatalf("threshold %d: %v", tt.threshold, err)
+		}
+		if got := b.Spilled(); got != tt.wantSpilled {
+			t.Errorf("threshold %d: Spilled() = %v, want %v", tt.threshold, got, tt.wantSpilled)
+		}
+		if b.Size != int64(len(body)) {
+			t.Errorf("threshold %d: Size = %d, want %d", tt.threshold, b.Size, len(body))
+		}
+		got := make([]byte, 4)
+		if _, err := b.ReadAt(got, 6); err != nil && err != io.EOF {
+			t.Errorf("threshold %d: ReadAt: %v", tt.threshold, err)
+		}
+		if string(got) != "6789" {
+			t.Errorf("threshold %d: ReadAt got %q, want %q", tt.threshold, got, "6789")
+		}
+		var name string
+		if b.f != nil {
+			name = b.f.Name()
+		}
+		if err := b.Close(); err != nil {
+			t.Errorf("threshold %d: Close: %v", tt.threshold, err)
+		}
+		if name != "" {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("threshold %d: temporary file %s not removed", tt.threshold, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package googleapi
+
+import "golang.org/x/net/context"
+
+// A Tracer creates spans for the calls made by a generated API package.
+// Implementations adapt it to the tracing system of their choice.
+// A Tracer is installed with a Service's SetTracer method.
+type Tracer interface {
+	// StartSpan starts a span for a call. name is the discovery method
+	// ID of the call, e.g. "storage.objects.get". ctx is the context
+	// supplied to the call, or context.Background() if there was none.
+	StartSpan(ctx context.Context, name string) Span
+}
+
+// A Span records a single call started by a Tracer.
+type Span interface {
+	// Finish ends the span. It is called exactly once, when the call's
+	// response body has been closed or the call has failed.
+	Finish(info SpanInfo)
+}
+
+// SpanInfo describes the outcome of a traced call.
+type SpanInfo struct {
+	StatusCode    int   // HTTP status of the final response; 0 if there was none
+	Err           error // error returned by the call, if any
+	Retries       int   // number of requests retried after a failure
+	BytesSent     int64 // request body bytes sent
+	BytesReceived int64 // response body bytes read by the caller
+}
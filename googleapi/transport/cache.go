@@ -0,0 +1,327 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores serialized HTTP responses for Cache.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the response stored under key, if any.
+	Get(key string) (resp []byte, ok bool)
+	// Set stores resp under key, replacing any existing value.
+	Set(key string, resp []byte)
+	// Delete removes any response stored under key.
+	Delete(key string)
+}
+
+// DefaultMemoryCacheBytes is the size limit of a MemoryCache whose
+// MaxBytes is zero.
+const DefaultMemoryCacheBytes = 32 << 20
+
+// MemoryCache is a CacheStore which keeps responses in memory. When the
+// responses exceed its size limit, the least recently used are evicted.
+// The zero value is an empty cache ready to use.
+type MemoryCache struct {
+	// MaxBytes limits the total size of the stored responses. If zero,
+	// DefaultMemoryCacheBytes is used.
+	MaxBytes int64
+
+	mu    sync.Mutex
+	m     map[string]*list.Element // of *memoryCacheEntry
+	lru   list.List                // most recently used at the front
+	bytes int64
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp []byte
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).resp, true
+}
+
+func (c *MemoryCache) Set(key string, resp []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]*list.Element)
+	}
+	c.remove(key)
+	max := c.MaxBytes
+	if max == 0 {
+		max = DefaultMemoryCacheBytes
+	}
+	if int64(len(resp)) > max {
+		return
+	}
+	c.m[key] = c.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	c.bytes += int64(len(resp))
+	for c.bytes > max {
+		c.remove(c.lru.Back().Value.(*memoryCacheEntry).key)
+	}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+// remove removes the entry for key, if any. c.mu must be held.
+func (c *MemoryCache) remove(key string) {
+	e, ok := c.m[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(e)
+	delete(c.m, key)
+	c.bytes -= int64(len(e.Value.(*memoryCacheEntry).resp))
+}
+
+// Cache is an HTTP Transport which caches responses to GET requests.
+//
+// A cached response is returned without contacting the server while it is
+// fresh according to its Cache-Control max-age or Expires header. Once
+// stale, a response carrying an ETag or Last-Modified header is
+// revalidated with a conditional request; if the server replies with
+// 304 Not Modified, the cached response is returned. Responses served
+// from the cache carry an "X-From-Cache: 1" header.
+//
+// Requests which set their own If-None-Match header, or which carry
+// "Cache-Control: no-store", bypass the cache. A successful non-GET
+// request removes any cached response for the same URL.
+//
+// Cache keeps one response per URL. A cached response is used only for
+// a request with the same Authorization header, and the same values of
+// the headers named by the response's Vary header, as the request which
+// received it; otherwise it is replaced. So a Cache placed beneath an
+// authenticating transport may be shared between users, and a response
+// cached under an expired token does not linger once the token is
+// refreshed. Responses with "Vary: *" are not cached.
+type Cache struct {
+	// Store holds the cached responses. It must be non-nil.
+	Store CacheStore
+
+	// Transport is the underlying HTTP transport.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// now is overridden in tests.
+var now = time.Now
+
+func (t *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+		if rt == nil {
+			return nil, errors.New("googleapi/transport: no Transport specified or available")
+		}
+	}
+	key := req.URL.String()
+
+	if req.Method != "GET" {
+		res, err := rt.RoundTrip(req)
+		if err == nil && res.StatusCode >= 200 && res.StatusCode <= 299 {
+			t.Store.Delete(key)
+		}
+		return res, err
+	}
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("Range") != "" ||
+		hasDirective(req.Header, "no-store") {
+		return rt.RoundTrip(req)
+	}
+
+	var cached *http.Response
+	if b, ok := t.Store.Get(key); ok {
+		cached = readCached(b, req)
+	}
+	if cached != nil {
+		if isFresh(cached.Header) && !hasDirective(req.Header, "no-cache") {
+			cached.Header.Set("X-From-Cache", "1")
+			return cached, nil
+		}
+		etag, lastMod := cached.Header.Get("Etag"), cached.Header.Get("Last-Modified")
+		if etag != "" || lastMod != "" {
+			r := new(http.Request)
+			*r = *req
+			r.Header = cloneHeader(req.Header)
+			if etag != "" {
+				r.Header.Set("If-None-Match", etag)
+			}
+			if lastMod != "" {
+				r.Header.Set("If-Modified-Since", lastMod)
+			}
+			req = r
+		}
+	}
+
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		for _, h := range []string{"Date", "Cache-Control", "Expires", "Etag", "Last-Modified"} {
+			if v := res.Header.Get(h); v != "" {
+				cached.Header.Set(h, v)
+			}
+		}
+		t.store(key, req, cached)
+		cached.Header.Set("X-From-Cache", "1")
+		return cached, nil
+	}
+	if res.StatusCode == http.StatusOK && isCacheable(res.Header) {
+		if err := t.store(key, req, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// store saves res, the response to req, under key. It reads res.Body and
+// replaces it with an equivalent in-memory reader.
+func (t *Cache) store(key string, req *http.Request, res *http.Response) error {
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	dump, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		return err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	// The stored response is preceded by a line identifying the requests
+	// it may be used for.
+	t.Store.Set(key, append([]byte(cacheVariant(req, res.Header)+"\n"), dump...))
+	return nil
+}
+
+// readCached returns the response stored in b if it may be used for req,
+// or nil.
+func readCached(b []byte, req *http.Request) *http.Response {
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return nil
+	}
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b[i+1:])), req)
+	if err != nil || cacheVariant(req, res.Header) != string(b[:i]) {
+		return nil
+	}
+	return res
+}
+
+// cacheVariant returns a hash of the Authorization header of req and of
+// the headers named by the Vary header of h, the header of a response to
+// req.
+func cacheVariant(req *http.Request, h http.Header) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%q", req.Header.Get("Authorization"))
+	for _, v := range h["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			fmt.Fprintf(hash, " %q=%q", name, req.Header[name])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isCacheable reports whether a response with header h may be stored.
+func isCacheable(h http.Header) bool {
+	if hasDirective(h, "no-store") {
+		return false
+	}
+	for _, v := range h["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			if strings.TrimSpace(name) == "*" {
+				return false
+			}
+		}
+	}
+	if h.Get("Etag") != "" || h.Get("Last-Modified") != "" {
+		return true
+	}
+	return isFresh(h)
+}
+
+// isFresh reports whether a response with header h may be used without
+// revalidation.
+func isFresh(h http.Header) bool {
+	if hasDirective(h, "no-cache") {
+		return false
+	}
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return false
+	}
+	age := now().Sub(date)
+	if maxAge, ok := directiveValue(h, "max-age"); ok {
+		secs, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return false
+		}
+		return age < time.Duration(secs)*time.Second
+	}
+	if exp, err := http.ParseTime(h.Get("Expires")); err == nil {
+		return now().Before(exp)
+	}
+	return false
+}
+
+func hasDirective(h http.Header, name string) bool {
+	_, ok := directiveValue(h, name)
+	return ok
+}
+
+// directiveValue returns the value of the named Cache-Control directive.
+func directiveValue(h http.Header, name string) (string, bool) {
+	for _, cc := range h["Cache-Control"] {
+		for _, d := range strings.Split(cc, ",") {
+			d = strings.TrimSpace(d)
+			k, v := d, ""
+			if i := strings.Index(d, "="); i >= 0 {
+				k, v = d[:i], strings.Trim(d[i+1:], `"`)
+			}
+			if strings.EqualFold(k, name) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
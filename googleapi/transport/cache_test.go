@@ -0,0 +1,153 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package transport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var hits, revalidated int
+	cacheControl := "max-age=60"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("Etag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &Cache{Store: new(MemoryCache)}}
+	get := func(wantFromCache bool) {
+		res, err := client.Get(ts.URL + "/x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		if string(body) != "hello" {
+			t.Errorf("body = %q; want %q", body, "hello")
+		}
+		if got := res.Header.Get("X-From-Cache") == "1"; got != wantFromCache {
+			t.Errorf("from cache = %v; want %v", got, wantFromCache)
+		}
+	}
+
+	get(false)
+	get(true)
+	if hits != 1 {
+		t.Errorf("fresh response: server hits = %d; want 1", hits)
+	}
+
+	// Once stale, the response is revalidated using its ETag.
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	get(true)
+	if hits != 2 || revalidated != 1 {
+		t.Errorf("stale response: hits = %d, revalidated = %d; want 2, 1", hits, revalidated)
+	}
+
+	// A successful mutation invalidates the cached response.
+	req, _ := http.NewRequest("DELETE", ts.URL+"/x", nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	get(false)
+}
+
+func TestCacheNoStore(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Etag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &Cache{Store: new(MemoryCache)}}
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if hits != 2 {
+		t.Errorf("server hits = %d; want 2", hits)
+	}
+}
+
+func TestCacheVariants(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("Accept-Language")))
+	}))
+	defer ts.Close()
+
+	store := new(MemoryCache)
+	client := &http.Client{Transport: &Cache{Store: store}}
+	get := func(auth, lang, want string, wantHits int) {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("Accept-Language", lang)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != want || hits != wantHits {
+			t.Errorf("%s, %s: got %q after %d hits, want %q after %d", auth, lang, body, hits, want, wantHits)
+		}
+	}
+	get("Bearer old", "en", "Bearer old en", 1)
+	get("Bearer old", "en", "Bearer old en", 1)
+	// A refreshed token replaces the entry of the old one.
+	get("Bearer new", "en", "Bearer new en", 2)
+	if n := len(store.m); n != 1 {
+		t.Errorf("store holds %d responses, want 1", n)
+	}
+	get("Bearer old", "en", "Bearer old en", 3)
+	// A header named by Vary selects the response too.
+	get("Bearer old", "fr", "Bearer old fr", 4)
+	get("Bearer old", "fr", "Bearer old fr", 4)
+}
+
+func TestMemoryCacheLimit(t *testing.T) {
+	c := &MemoryCache{MaxBytes: 10}
+	c.Set("a", []byte("aaaa"))
+	c.Set("b", []byte("bbbb"))
+	c.Get("a")
+	// Storing c evicts b, the least recently used.
+	c.Set("c", []byte("cccc"))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+	// A response larger than the limit is not stored.
+	c.Set("d", make([]byte, 11))
+	if _, ok := c.Get("d"); ok {
+		t.Error("stored a response larger than MaxBytes")
+	}
+	if c.bytes != 8 {
+		t.Errorf("size = %d, want 8", c.bytes)
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// DefaultHARBodySize is the number of bytes of each body recorded by a
+// HAR whose MaxBodySize is zero.
+const DefaultHARBodySize = 64 << 10
+
+// HAR is an HTTP Transport which records the requests it sends and the
+// responses it receives, so that failing interactions with an API can be
+// reported in the standard HTTP Archive format, HAR 1.2.
+//
+// Credentials are not recorded: the Authorization, Proxy-Authorization
+// and Cookie headers of requests, the Set-Cookie headers of responses,
+// and the "key" and "access_token" query parameters are left out.
+// Bodies are recorded as they are read, up to MaxBodySize bytes each.
+type HAR struct {
+	// Transport is the underlying HTTP transport.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MaxBodySize is the number of bytes of each body recorded. If zero,
+	// DefaultHARBodySize is used; if negative, bodies are not recorded.
+	MaxBodySize int
+
+	mu      sync.Mutex // guards entries and the bodies they record
+	entries []*harEntry
+}
+
+// secretHeaders are the headers left out of a HAR capture.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretParams are the query parameters left out of a HAR capture.
+var secretParams = map[string]bool{
+	"key":          true,
+	"access_token": true,
+}
+
+func (t *HAR) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+		if rt == nil {
+			return nil, errors.New("googleapi/transport: no Transport specified or available")
+		}
+	}
+	start := time.Now()
+	e := &harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			HTTPVersion: harProto(req.Proto),
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req.URL),
+			Cookies:     []harPair{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Cache: struct{}{},
+	}
+	if req.Body != nil {
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type")}
+		newReq := *req
+		newReq.Body = t.capture(req.Body, &e.Request.PostData.Text, nil)
+		req = &newReq
+	}
+	t.mu.Lock()
+	t.entries = append(t.entries, e)
+	t.mu.Unlock()
+
+	resp, err := rt.RoundTrip(req)
+	wait := time.Since(start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e.Time = durationMillis(wait)
+	e.Timings = harTimings{Send: 0, Wait: e.Time, Receive: 0}
+	if err != nil {
+		e.Error = err.Error()
+		e.Response = harResponse{Headers: []harPair{}, Cookies: []harPair{}, HeadersSize: -1, BodySize: -1}
+		return resp, err
+	}
+	e.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     harHeaders(resp.Header),
+		Cookies:     []harPair{},
+		Content:     harContent{MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    resp.ContentLength,
+	}
+	if resp.Body != nil {
+		resp.Body = t.capture(resp.Body, &e.Response.Content.Text, func(n int64) {
+			e.Response.Content.Size = n
+			e.Timings.Receive = durationMillis(time.Since(start)) - e.Timings.Wait
+			e.Time = e.Timings.Wait + e.Timings.Receive
+		})
+	}
+	return resp, nil
+}
+
+// WriteTo writes the requests and responses recorded so far to w as a
+// HAR 1.2 log.
+func (t *HAR) WriteTo(w io.Writer) (int64, error) {
+	t.mu.Lock()
+	b, err := json.MarshalIndent(harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "google-api-go-client", Version: googleapi.Version},
+		Entries: append([]*harEntry{}, t.entries...),
+	}}, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(b, '\n'))
+	return int64(n), err
+}
+
+// Reset discards the requests and responses recorded so far.
+func (t *HAR) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = nil
+}
+
+// capture returns a body reading from body which records what is read
+// in text, up to t.MaxBodySize bytes, and calls read, if non-nil, with
+// the number of bytes read so far.
+func (t *HAR) capture(body io.ReadCloser, text *string, read func(n int64)) io.ReadCloser {
+	max := t.MaxBodySize
+	if max == 0 {
+		max = DefaultHARBodySize
+	}
+	return &harBody{body: body, har: t, text: text, max: max, read: read}
+}
+
+type harBody struct {
+	body io.ReadCloser
+	har  *HAR
+	text *string
+	max  int
+	n    int64
+	read func(n int64)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.har.mu.Lock()
+	if room := b.max - len(*b.text); room > 0 {
+		if room > n {
+			room = n
+		}
+		*b.text += string(p[:room])
+	}
+	b.n += int64(n)
+	if b.read != nil {
+		b.read(b.n)
+	}
+	b.har.mu.Unlock()
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	return b.body.Close()
+}
+
+// redactURL returns u without its secret query parameters.
+func redactURL(u *url.URL) string {
+	ru := *u
+	q := u.Query()
+	for p := range secretParams {
+		q.Del(p)
+	}
+	ru.RawQuery = q.Encode()
+	return ru.String()
+}
+
+func harHeaders(h http.Header) []harPair {
+	pairs := []harPair{}
+	for name, values := range h {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, v := range values {
+			pairs = append(pairs, harPair{name, v})
+		}
+	}
+	sort.Stable(pairsByName(pairs))
+	return pairs
+}
+
+func harQuery(u *url.URL) []harPair {
+	pairs := []harPair{}
+	for name, values := range u.Query() {
+		if secretParams[name] {
+			continue
+		}
+		for _, v := range values {
+			pairs = append(pairs, harPair{name, v})
+		}
+	}
+	sort.Stable(pairsByName(pairs))
+	return pairs
+}
+
+// harProto returns proto, or for requests which leave it to the
+// transport, as clients' requests do, HTTP/1.1.
+func harProto(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// The types below follow the HAR 1.2 specification,
+// http://www.softwareishard.com/blog/har-12-spec/.
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	// Error is the error returned instead of a response, if any. HAR
+	// allows custom fields whose names begin with an underscore.
+	Error string `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	Cookies     []harPair    `json:"cookies"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+	PostData    *harPostData `json:"postData,omitempty"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Headers     []harPair  `json:"headers"`
+	Cookies     []harPair  `json:"cookies"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int64      `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type pairsByName []harPair
+
+func (s pairsByName) Len() int           { return len(s) }
+func (s pairsByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s pairsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
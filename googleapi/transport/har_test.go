@@ -0,0 +1,81 @@
+// Copyright 2016 Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHAR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "not found"}`))
+	}))
+	defer ts.Close()
+
+	har := &HAR{}
+	client := &http.Client{Transport: har}
+	req, err := http.NewRequest("POST", ts.URL+"/x?key=secret&alt=json", strings.NewReader(`{"name": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := har.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("HAR contains a credential:\n%s", buf.String())
+	}
+	var got harFile
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Log.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got.Log.Entries))
+	}
+	e := got.Log.Entries[0]
+	if want := ts.URL + "/x?alt=json"; e.Request.URL != want {
+		t.Errorf("request URL: got %q, want %q", e.Request.URL, want)
+	}
+	if e.Request.PostData == nil || e.Request.PostData.Text != `{"name": "x"}` {
+		t.Errorf("request body: got %+v, want the JSON sent", e.Request.PostData)
+	}
+	if e.Response.Status != http.StatusNotFound || e.Response.Content.Text != `{"error": "not found"}` {
+		t.Errorf("response: got status %d, body %q", e.Response.Status, e.Response.Content.Text)
+	}
+
+	har.Reset()
+	har.MaxBodySize = 4
+	res, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	buf.Reset()
+	har.WriteTo(&buf)
+	got = harFile{}
+	json.Unmarshal(buf.Bytes(), &got)
+	if len(got.Log.Entries) != 1 || got.Log.Entries[0].Response.Content.Text != `{"er` {
+		t.Errorf("after Reset with MaxBodySize 4: got %+v", got.Log.Entries)
+	}
+}
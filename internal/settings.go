@@ -2,7 +2,9 @@
 package internal
 
 import (
+	"crypto/tls"
 	"net/http"
+	"time"
 
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
@@ -18,4 +20,9 @@ type DialSettings struct {
 	HTTPClient   *http.Client
 	GRPCDialOpts []grpc.DialOption
 	GRPCConn     *grpc.ClientConn
+
+	// Settings for the HTTP transport constructed when HTTPClient is nil.
+	MaxIdleConnsPerHost   int
+	ResponseHeaderTimeout time.Duration
+	TLSConfig             *tls.Config
 }
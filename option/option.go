@@ -2,7 +2,9 @@
 package option
 
 import (
+	"crypto/tls"
 	"net/http"
+	"time"
 
 	"golang.org/x/oauth2"
 	"google.golang.org/api/internal"
@@ -101,3 +103,43 @@ type withGRPCDialOption struct{ opt grpc.DialOption }
 func (w withGRPCDialOption) Apply(o *internal.DialSettings) {
 	o.GRPCDialOpts = append(o.GRPCDialOpts, w.opt)
 }
+
+// WithMaxIdleConnsPerHost returns a ClientOption that sets the maximum number
+// of idle connections kept open to each host by the HTTP transport constructed
+// for a service. Raising it lets high-QPS clients reuse connections instead of
+// exhausting ephemeral ports. It has no effect with WithHTTPClient.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return withMaxIdleConnsPerHost(n)
+}
+
+type withMaxIdleConnsPerHost int
+
+func (w withMaxIdleConnsPerHost) Apply(o *internal.DialSettings) {
+	o.MaxIdleConnsPerHost = int(w)
+}
+
+// WithResponseHeaderTimeout returns a ClientOption that limits how long the
+// HTTP transport constructed for a service waits for a server's response
+// headers after writing a request. It has no effect with WithHTTPClient.
+func WithResponseHeaderTimeout(d time.Duration) ClientOption {
+	return withResponseHeaderTimeout(d)
+}
+
+type withResponseHeaderTimeout time.Duration
+
+func (w withResponseHeaderTimeout) Apply(o *internal.DialSettings) {
+	o.ResponseHeaderTimeout = time.Duration(w)
+}
+
+// WithTLSConfig returns a ClientOption that specifies the TLS configuration
+// of the HTTP transport constructed for a service. HTTP/2 remains enabled.
+// It has no effect with WithHTTPClient.
+func WithTLSConfig(c *tls.Config) ClientOption {
+	return withTLSConfig{c}
+}
+
+type withTLSConfig struct{ c *tls.Config }
+
+func (w withTLSConfig) Apply(o *internal.DialSettings) {
+	o.TLSConfig = w.c
+}
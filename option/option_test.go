@@ -1,8 +1,10 @@
 package option
 
 import (
+	"crypto/tls"
 	"reflect"
 	"testing"
+	"time"
 
 	"google.golang.org/api/internal"
 	"google.golang.org/grpc"
@@ -25,12 +27,16 @@ func TestCopyScopes(t *testing.T) {
 
 func TestApply(t *testing.T) {
 	conn := &grpc.ClientConn{}
+	tlsConfig := &tls.Config{}
 	opts := []ClientOption{
 		WithEndpoint("https://example.com:443"),
 		WithScopes("a"), // the next WithScopes should overwrite this one
 		WithScopes("https://example.com/auth/helloworld", "https://example.com/auth/otherthing"),
 		WithGRPCConn(conn),
 		WithUserAgent("ua"),
+		WithMaxIdleConnsPerHost(100),
+		WithResponseHeaderTimeout(time.Minute),
+		WithTLSConfig(tlsConfig),
 	}
 	var got internal.DialSettings
 	for _, opt := range opts {
@@ -41,6 +47,10 @@ func TestApply(t *testing.T) {
 		UserAgent: "ua",
 		Endpoint:  "https://example.com:443",
 		GRPCConn:  conn,
+
+		MaxIdleConnsPerHost:   100,
+		ResponseHeaderTimeout: time.Minute,
+		TLSConfig:             tlsConfig,
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("\ngot  %#v\nwant %#v", got, want)
@@ -20,15 +20,18 @@ package transport
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
 
+	"google.golang.org/api/auth"
 	"google.golang.org/api/internal"
 	"google.golang.org/api/option"
 )
@@ -48,16 +51,45 @@ func NewHTTPClient(ctx context.Context, opts ...option.ClientOption) (*http.Clie
 	if o.HTTPClient != nil {
 		return o.HTTPClient, o.Endpoint, nil
 	}
+	if o.MaxIdleConnsPerHost != 0 || o.ResponseHeaderTimeout != 0 || o.TLSConfig != nil {
+		t, err := newTransport(&o)
+		if err != nil {
+			return nil, "", err
+		}
+		// oauth2.NewClient uses the client in ctx as its base.
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: t})
+	}
 	if o.TokenSource == nil {
 		var err error
-		o.TokenSource, err = google.DefaultTokenSource(ctx, o.Scopes...)
+		o.TokenSource, err = auth.DefaultTokenSource(ctx, o.Scopes...)
 		if err != nil {
-			return nil, "", fmt.Errorf("google.DefaultTokenSource: %v", err)
+			return nil, "", fmt.Errorf("auth.DefaultTokenSource: %v", err)
 		}
 	}
 	return oauth2.NewClient(ctx, o.TokenSource), o.Endpoint, nil
 }
 
+// newTransport returns an HTTP transport configured like
+// http.DefaultTransport, with the connection settings from o applied.
+func newTransport(o *internal.DialSettings) (*http.Transport, error) {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout:   10 * time.Second,
+		MaxIdleConnsPerHost:   o.MaxIdleConnsPerHost,
+		ResponseHeaderTimeout: o.ResponseHeaderTimeout,
+		TLSClientConfig:       o.TLSConfig,
+	}
+	// Setting TLSClientConfig stops net/http from enabling HTTP/2 itself.
+	if err := http2.ConfigureTransport(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
 // Set at init time by dial_appengine.go. If nil, we're not on App Engine.
 var appengineDialerHook func(context.Context) grpc.DialOption
 
@@ -76,9 +108,9 @@ func DialGRPC(ctx context.Context, opts ...option.ClientOption) (*grpc.ClientCon
 	}
 	if o.TokenSource == nil {
 		var err error
-		o.TokenSource, err = google.DefaultTokenSource(ctx, o.Scopes...)
+		o.TokenSource, err = auth.DefaultTokenSource(ctx, o.Scopes...)
 		if err != nil {
-			return nil, fmt.Errorf("google.DefaultTokenSource: %v", err)
+			return nil, fmt.Errorf("auth.DefaultTokenSource: %v", err)
 		}
 	}
 	grpcOpts := []grpc.DialOption{
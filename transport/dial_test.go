@@ -15,6 +15,7 @@
 package transport
 
 import (
+	"crypto/tls"
 	"errors"
 	"net"
 	"testing"
@@ -22,6 +23,7 @@ import (
 
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/internal"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 )
@@ -64,3 +66,27 @@ func TestGRPCHook(t *testing.T) {
 		t.Error("expected a call to expected dialer, didn't get one")
 	}
 }
+
+func TestNewTransport(t *testing.T) {
+	o := &internal.DialSettings{
+		MaxIdleConnsPerHost:   64,
+		ResponseHeaderTimeout: 5 * time.Second,
+		TLSConfig:             &tls.Config{ServerName: "example.google.com"},
+	}
+	tr, err := newTransport(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.MaxIdleConnsPerHost != 64 {
+		t.Errorf("MaxIdleConnsPerHost: got %d, want 64", tr.MaxIdleConnsPerHost)
+	}
+	if tr.ResponseHeaderTimeout != 5*time.Second {
+		t.Errorf("ResponseHeaderTimeout: got %v, want 5s", tr.ResponseHeaderTimeout)
+	}
+	if tr.TLSClientConfig.ServerName != "example.google.com" {
+		t.Errorf("TLSClientConfig.ServerName: got %q, want example.google.com", tr.TLSClientConfig.ServerName)
+	}
+	if tr.TLSNextProto["h2"] == nil {
+		t.Error("HTTP/2 not configured")
+	}
+}